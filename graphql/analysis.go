@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"context"
+	"sort"
+)
+
+// FieldAccess is a field on a named type that a query accesses.
+type FieldAccess struct {
+	Type  string
+	Field string
+}
+
+// AnalyzeQuery returns the set of fields the given selectionSet accesses on typ
+// and all types reachable from it, including the generated connection and edge
+// types of paginated fields. The result is sorted by type and then by field.
+//
+// AnalyzeQuery is a static analysis and should be called after PrepareQuery has
+// validated the selectionSet against typ, e.g. to authorize a query as a whole
+// before it is executed.
+func AnalyzeQuery(typ Type, selectionSet *SelectionSet) ([]FieldAccess, error) {
+	seen := make(map[FieldAccess]bool)
+	if err := analyzeQuery(typ, selectionSet, seen); err != nil {
+		return nil, err
+	}
+
+	accesses := make([]FieldAccess, 0, len(seen))
+	for access := range seen {
+		accesses = append(accesses, access)
+	}
+	sort.Slice(accesses, func(i, j int) bool {
+		if accesses[i].Type != accesses[j].Type {
+			return accesses[i].Type < accesses[j].Type
+		}
+		return accesses[i].Field < accesses[j].Field
+	})
+	return accesses, nil
+}
+
+func analyzeQuery(typ Type, selectionSet *SelectionSet, seen map[FieldAccess]bool) error {
+	switch typ := typ.(type) {
+	case *Scalar, *Enum:
+		return nil
+	case *Union:
+		if selectionSet == nil {
+			return NewClientError("object field must have selections")
+		}
+		for _, fragment := range selectionSet.Fragments {
+			graphqlTyp, ok := typ.Types[fragment.On]
+			if !ok {
+				continue
+			}
+			if err := analyzeQuery(graphqlTyp, fragment.SelectionSet, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Interface:
+		if selectionSet == nil {
+			return NewClientError("object field must have selections")
+		}
+		for typString, graphqlTyp := range typ.Types {
+			if err := analyzeQuery(graphqlTyp, selectionSetOn(selectionSet, typ.Name, typString), seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Object:
+		if selectionSet == nil {
+			return NewClientError("object field must have selections")
+		}
+		for _, selection := range selectionSet.Selections {
+			if selection.Name == "__typename" {
+				continue
+			}
+
+			field, ok := typ.Fields[selection.Name]
+			if !ok {
+				return NewClientError(`unknown field "%s"`, selection.Name)
+			}
+			seen[FieldAccess{Type: typ.Name, Field: selection.Name}] = true
+
+			if err := analyzeQuery(field.Type, selection.SelectionSet, seen); err != nil {
+				return err
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if err := analyzeQuery(typ, fragment.SelectionSet, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *List:
+		return analyzeQuery(typ.Type, selectionSet, seen)
+	case *NonNull:
+		return analyzeQuery(typ.Type, selectionSet, seen)
+	default:
+		panic("unknown type kind")
+	}
+}
+
+// QueryCost estimates the cost of executing the given selectionSet on typ in
+// ctx. Every field costs its Field.Cost, or 1 by default, plus the cost of its
+// selections, which is multiplied by the field's CostMultiplier, e.g. the page
+// size of a connection, which may depend on the MaxPageSize of ctx. Of the
+// possible types of a union or interface, the most expensive one counts.
+//
+// Like AnalyzeQuery, QueryCost should be called after PrepareQuery has parsed
+// the args of the selectionSet.
+func QueryCost(ctx context.Context, typ Type, selectionSet *SelectionSet) int {
+	switch typ := typ.(type) {
+	case *Union:
+		var max int
+		for _, fragment := range selectionSet.Fragments {
+			if graphqlTyp, ok := typ.Types[fragment.On]; ok {
+				if cost := QueryCost(ctx, graphqlTyp, fragment.SelectionSet); cost > max {
+					max = cost
+				}
+			}
+		}
+		return max
+	case *Interface:
+		var max int
+		for typString, graphqlTyp := range typ.Types {
+			if cost := QueryCost(ctx, graphqlTyp, selectionSetOn(selectionSet, typ.Name, typString)); cost > max {
+				max = cost
+			}
+		}
+		return max
+	case *Object:
+		var total int
+		for _, selection := range Flatten(selectionSet) {
+			field, ok := typ.Fields[selection.Name]
+			if !ok {
+				continue
+			}
+
+			cost := field.Cost
+			if cost == 0 {
+				cost = 1
+			}
+			if selection.SelectionSet != nil {
+				multiplier := 1
+				if field.CostMultiplier != nil {
+					multiplier = field.CostMultiplier(ctx, selection.Args)
+				}
+				cost += multiplier * QueryCost(ctx, field.Type, selection.SelectionSet)
+			}
+			total += cost
+		}
+		return total
+	case *List:
+		return QueryCost(ctx, typ.Type, selectionSet)
+	case *NonNull:
+		return QueryCost(ctx, typ.Type, selectionSet)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,58 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/samsarahq/thunder/batch"
+)
+
+// A Batcher combines the loads of many keys during an execution into a single
+// call of its batch function, avoiding a lookup per resolved object.
+//
+// Resolvers that take a context are executed concurrently, so loads made by
+// sibling resolvers, e.g. for every element of a list, are queued and flushed
+// together. For example, the companies of a list of users can be loaded with a
+// single lookup:
+//   companies := graphql.NewBatcher(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+//     return fetchCompanies(ctx, keys)
+//   })
+//
+//   user.FieldFunc("company", func(ctx context.Context, u *User) (*Company, error) {
+//     company, err := companies.Load(ctx, u.CompanyId)
+//     if err != nil {
+//       return nil, err
+//     }
+//     return company.(*Company), nil
+//   })
+//
+// Loads are only combined if the context has batching enabled with
+// batch.WithBatching, as it does in HTTPHandler and the websocket server.
+type Batcher struct {
+	batchFunc *batch.Func
+}
+
+// NewBatcher returns a Batcher calling fn with the queued keys. fn must return
+// a value for every key, in the order of the keys.
+func NewBatcher(fn func(ctx context.Context, keys []interface{}) ([]interface{}, error)) *Batcher {
+	return &Batcher{
+		batchFunc: &batch.Func{Many: fn},
+	}
+}
+
+// Load queues key to be loaded with the next batch, and returns its value once
+// the batch is done.
+func (b *Batcher) Load(ctx context.Context, key interface{}) (interface{}, error) {
+	if batch.HasBatching(ctx) {
+		return b.batchFunc.Invoke(ctx, key)
+	}
+
+	values, err := b.batchFunc.Many(ctx, []interface{}{key})
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, errors.New("batch function returned incorrect number of results")
+	}
+	return values[0], nil
+}
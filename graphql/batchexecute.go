@@ -0,0 +1,60 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/samsarahq/thunder/batch"
+	"github.com/samsarahq/thunder/reactive"
+)
+
+// ExecuteBatch executes several operations sent in one request, and returns
+// their results in the same order. Queries are executed on schema's Query and
+// mutations on its Mutation root.
+//
+// The operations are executed one after another and share the batching of ctx,
+// so dataloaders and caches are reused between them. They are otherwise
+// isolated: an operation that fails sets the Err of its own result, and the
+// errors of its failed fields and its reactive dependencies are collected in
+// its result. The returned error is only set if ctx is canceled.
+func (e *Executor) ExecuteBatch(ctx context.Context, schema *Schema, queries []*Query) ([]*ExecutionResult, error) {
+	if !batch.HasBatching(ctx) {
+		ctx = batch.WithBatching(ctx)
+	}
+
+	results := make([]*ExecutionResult, 0, len(queries))
+	for _, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results = append(results, e.executeOperation(ctx, schema, query))
+	}
+	return results, nil
+}
+
+// executeOperation executes a single operation of a batch.
+func (e *Executor) executeOperation(ctx context.Context, schema *Schema, query *Query) *ExecutionResult {
+	var typ Type
+	switch query.Kind {
+	case "mutation":
+		typ = schema.Mutation
+	case "subscription":
+		return &ExecutionResult{Err: NewClientError("subscriptions can't be executed in a batch")}
+	default:
+		typ = schema.Query
+	}
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		return &ExecutionResult{Err: err}
+	}
+
+	if reactive.HasRerunner(ctx) {
+		ctx = reactive.WithDependencySet(ctx)
+	}
+	x := e.newExecution()
+	data, err := x.run(ctx, typ, nil, query)
+	result := x.result(data)
+	result.Err = err
+	if reactive.HasRerunner(ctx) {
+		result.Dependencies = reactive.Dependencies(ctx)
+	}
+	return result
+}
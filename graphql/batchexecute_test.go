@@ -0,0 +1,82 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+	"github.com/samsarahq/thunder/reactive"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("value", func(ctx context.Context, args struct{ Name string }) string {
+		reactive.AddDependency(ctx, reactive.NewResource(), args.Name)
+		return args.Name
+	})
+	query.FieldFunc("fail", func() (string, error) {
+		return "", errors.New("failed")
+	})
+	mutation := schema.Mutation()
+	mutation.FieldFunc("echo", func(args struct{ Value int64 }) int64 {
+		return args.Value
+	})
+	builtSchema := schema.MustBuild()
+
+	queries := []*graphql.Query{
+		graphql.MustParse(`{ value(name: "a") }`, nil),
+		graphql.MustParse(`{ fail }`, nil),
+		graphql.MustParse(`mutation { echo(value: 3) }`, nil),
+		graphql.MustParse(`{ a: value(name: "b") c: value(name: "c") }`, nil),
+		graphql.MustParse(`{ missing }`, nil),
+	}
+
+	done := make(chan []*graphql.ExecutionResult, 1)
+	runner := reactive.NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
+		e := graphql.Executor{}
+		results, err := e.ExecuteBatch(ctx, builtSchema, queries)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- results
+		return nil, nil
+	}, time.Millisecond)
+	defer runner.Stop()
+
+	var results []*graphql.ExecutionResult
+	select {
+	case results = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for results")
+	}
+
+	if !assert.Len(t, results, 5) {
+		return
+	}
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, map[string]interface{}{"value": "a"}, internal.AsJSON(results[0].Data))
+	assert.Equal(t, []reactive.Dependency{"a"}, results[0].Dependencies)
+
+	if results[1].Err == nil || results[1].Err.Error() != "fail: failed" {
+		t.Errorf("expected fail error, got %v", results[1].Err)
+	}
+
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, map[string]interface{}{"echo": float64(3)}, internal.AsJSON(results[2].Data))
+
+	assert.NoError(t, results[3].Err)
+	assert.Equal(t, map[string]interface{}{"a": "b", "c": "c"}, internal.AsJSON(results[3].Data))
+	assert.ElementsMatch(t, []reactive.Dependency{"b", "c"}, results[3].Dependencies)
+
+	if results[4].Err == nil || results[4].Err.Error() != `unknown field "missing"` {
+		t.Errorf("expected unknown field error, got %v", results[4].Err)
+	}
+}
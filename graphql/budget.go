@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// budget is the number of units an execution may still spend, see
+// Executor.Budget.
+type budget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+type budgetKey struct{}
+
+// withBudget returns a context in which resolvers can spend units.
+func withBudget(ctx context.Context, units int) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budget{remaining: units})
+}
+
+// ChargeBudget spends units of the budget of the execution resolving in ctx,
+// e.g. for the calls a resolver makes to an external API. It returns a client
+// error once the budget is overspent, which the resolver should typically
+// return. ChargeBudget does nothing if the execution has no Budget.
+func ChargeBudget(ctx context.Context, units int) error {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remaining -= units
+	if b.remaining < 0 {
+		return NewClientError("query exceeded its budget")
+	}
+	return nil
+}
+
+// checkBudget returns a client error if the budget of the execution resolving
+// in ctx is spent.
+func checkBudget(ctx context.Context) error {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining <= 0 {
+		return NewClientError("query exceeded its budget")
+	}
+	return nil
+}
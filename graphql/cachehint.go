@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheScope tells who may cache a response, see SetCacheHint.
+type CacheScope int
+
+const (
+	// CacheScopePublic responses may be cached by shared caches, e.g. CDNs.
+	CacheScopePublic CacheScope = iota
+	// CacheScopePrivate responses may only be cached by the client, e.g.
+	// because they depend on the user.
+	CacheScopePrivate
+)
+
+// A CacheHint tells how long and by whom the response to a query may be
+// cached.
+type CacheHint struct {
+	MaxAge time.Duration
+	Scope  CacheScope
+}
+
+// CacheControl returns the hint as the value of a Cache-Control header.
+func (h *CacheHint) CacheControl() string {
+	scope := "public"
+	if h.Scope == CacheScopePrivate {
+		scope = "private"
+	}
+	return fmt.Sprintf("max-age=%d, %s", int64(h.MaxAge/time.Second), scope)
+}
+
+type cacheHintKey struct{}
+
+// cacheHints aggregates the hints set during an execution.
+type cacheHints struct {
+	mu   sync.Mutex
+	hint *CacheHint
+
+	// rootFields tells for every root field executed so far if a hint was
+	// set while resolving it.
+	rootFields map[string]bool
+}
+
+// rootFieldCacheHints are the hints of an execution, as seen by the fields
+// resolving a root field.
+type rootFieldCacheHints struct {
+	hints *cacheHints
+	field string
+}
+
+// withRootField returns the context in which the root field with the given
+// alias resolves. The aggregated hint only applies once every root field set
+// a hint.
+func (h *cacheHints) withRootField(ctx context.Context, alias string) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rootFields == nil {
+		h.rootFields = make(map[string]bool)
+	}
+	if _, ok := h.rootFields[alias]; !ok {
+		h.rootFields[alias] = false
+	}
+	return context.WithValue(ctx, cacheHintKey{}, &rootFieldCacheHints{hints: h, field: alias})
+}
+
+// SetCacheHint sets a cache hint for the response of the query resolving in
+// ctx, e.g. for a CDN to cache it. The hints of all fields are aggregated into
+// the shortest max age and the most private scope, which is returned in the
+// ExecutionResult of Executor.ExecuteResult. The HTTP handler sets the
+// Cache-Control header of query responses from it.
+//
+// A hint set by a field covers the root field it is nested in. If any root
+// field of the query is not covered by a hint, e.g. a field returning user
+// data next to a public field, the response gets no hint at all, as if the
+// uncovered field had a max age of 0.
+func SetCacheHint(ctx context.Context, maxAge time.Duration, scope CacheScope) {
+	root, ok := ctx.Value(cacheHintKey{}).(*rootFieldCacheHints)
+	if !ok {
+		return
+	}
+	hints := root.hints
+
+	hints.mu.Lock()
+	defer hints.mu.Unlock()
+	hints.rootFields[root.field] = true
+	if hints.hint == nil {
+		hints.hint = &CacheHint{MaxAge: maxAge, Scope: scope}
+		return
+	}
+	if maxAge < hints.hint.MaxAge {
+		hints.hint.MaxAge = maxAge
+	}
+	if scope > hints.hint.Scope {
+		hints.hint.Scope = scope
+	}
+}
+
+// get returns the aggregated hint, or nil if a root field isn't covered by a
+// hint.
+func (h *cacheHints) get() *CacheHint {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hint == nil {
+		return nil
+	}
+	for _, hinted := range h.rootFields {
+		if !hinted {
+			return nil
+		}
+	}
+	hint := *h.hint
+	return &hint
+}
@@ -1,12 +1,21 @@
 package graphql_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
 	"github.com/samsarahq/thunder/reactive"
 	"github.com/stretchr/testify/assert"
 )
@@ -178,7 +187,65 @@ func TestConnection(t *testing.T) {
 					"hasPrevPage": true,
 					"startCursor": "NA==",
 					"endCursor":   "NQ==",
-					"pages":       []interface{}{"", "Mg==", "NA=="},
+					"pages":       []interface{}{"Mg==", "NA==", ""},
+				},
+			},
+		},
+	}, val)
+
+	// Test for last and before with a cursor. The pages should line up with the returned edges.
+	q = graphql.MustParse(`
+		{
+			inner {
+				innerConnection(last: 2, before: "NA==", additional: "jk") {
+					edges {
+						node {
+							id
+						}
+						cursor
+					}
+					pageInfo {
+						hasNextPage
+						hasPrevPage
+						startCursor
+						endCursor
+						pages
+					}
+				}
+			}
+	    }`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e = graphql.Executor{}
+	val, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(2),
+							"id":    int64(2),
+						},
+						"cursor": "Mg==",
+					},
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(3),
+							"id":    int64(3),
+						},
+						"cursor": "Mw==",
+					},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+					"hasPrevPage": true,
+					"startCursor": "Mg==",
+					"endCursor":   "Mw==",
+					"pages":       []interface{}{"Mg==", "NA==", ""},
 				},
 			},
 		},
@@ -394,15 +461,124 @@ func TestConnection(t *testing.T) {
 			}
 	    }`, nil)
 
+	err = graphql.PrepareQuery(builtSchema.Query, q.SelectionSet)
+	if err == nil || err.Error() != `error parsing args for "innerConnection": last: should be a non-negative integer` {
+		t.Errorf("bad error: %v", err)
+	}
+
+	// Relay allows combining first and last.
+	q = graphql.MustParse(`
+		{
+			inner {
+				innerConnection(first: 2, last: 2, additional: "jk") {
+					totalCount
+				}
+			}
+	    }`, nil)
+
+	err = graphql.PrepareQuery(builtSchema.Query, q.SelectionSet)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConnectionAliases(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	query.FieldFunc("items", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}, {Id: 3}}
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		all: items { count: totalCount }
+		firstTwo: items(first: 2) {
+			count: totalCount
+			items: edges { at: cursor item: node { itemId: id } }
+			page: pageInfo { more: hasNextPage last: endCursor }
+		}
+	}`, nil)
 	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	e = graphql.Executor{}
-	val, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
-	if err == nil || err.Error() != "last should be a non-negative integer" {
-		t.Errorf("bad error: %v", err)
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"all": map[string]interface{}{
+			"count": int64(3),
+		},
+		"firstTwo": map[string]interface{}{
+			"count": int64(3),
+			"items": []interface{}{
+				map[string]interface{}{
+					"at":   "MQ==",
+					"item": map[string]interface{}{"__key": int64(1), "itemId": int64(1)},
+				},
+				map[string]interface{}{
+					"at":   "Mg==",
+					"item": map[string]interface{}{"__key": int64(2), "itemId": int64(2)},
+				},
+			},
+			"page": map[string]interface{}{
+				"more": true,
+				"last": "Mg==",
+			},
+		},
+	}, val)
+}
+
+func TestConnectionPointerNodes(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	query.FieldFunc("items", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}}
+	}, schemabuilder.Paginated)
+	query.FieldFunc("itemPtrs", func() []*Item {
+		return []*Item{{Id: 1}, {Id: 2}}
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	fields := builtSchema.Query.(*graphql.Object).Fields
+	assert.Equal(t, "ItemConnection!", fields["items"].Type.String())
+	assert.Equal(t, "ItemConnection!", fields["itemPtrs"].Type.String())
+
+	edgeType := func(name string) string {
+		connection := fields[name].Type.(*graphql.NonNull).Type.(*graphql.Object)
+		return connection.Fields["edges"].Type.String() + " " +
+			connection.Fields["edges"].Type.(*graphql.NonNull).Type.(*graphql.List).Type.(*graphql.NonNull).Type.(*graphql.Object).Fields["node"].Type.String()
+	}
+	assert.Equal(t, "[ItemEdge!]! item!", edgeType("items"))
+	assert.Equal(t, "[ItemEdge!]! item!", edgeType("itemPtrs"))
+
+	q := graphql.MustParse(`{
+		items(first: 1) { edges { cursor node { id } } }
+		itemPtrs(first: 1) { edges { cursor node { id } } }
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
 	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
 
+	connection := map[string]interface{}{
+		"edges": []interface{}{
+			map[string]interface{}{
+				"cursor": "MQ==",
+				"node":   map[string]interface{}{"__key": int64(1), "id": int64(1)},
+			},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"items":    connection,
+		"itemPtrs": connection,
+	}, val)
 }
 
 func TestPaginateBuildFailure(t *testing.T) {
@@ -666,3 +842,2019 @@ func TestEmbeddedFail(t *testing.T) {
 		t.Errorf("bad error: %v", err)
 	}
 }
+
+func TestEdgeHighlighter(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+	type SearchArgs struct {
+		Query string
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("search", func(args SearchArgs) []Item {
+		return []Item{{Id: 11}, {Id: 2}, {Id: 31}}
+	}, schemabuilder.Paginated.WithEdgeHighlighter(func(ctx context.Context, node interface{}, query string) schemabuilder.Highlight {
+		id := fmt.Sprint(node.(Item).Id)
+		if strings.Contains(id, query) {
+			return schemabuilder.Highlight{Snippets: []string{id}}
+		}
+		return schemabuilder.Highlight{}
+	}))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				search(first: 2, query: "1") {
+					edges {
+						node {
+							id
+						}
+						highlight {
+							snippets
+						}
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"search": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(11),
+							"id":    int64(11),
+						},
+						"highlight": map[string]interface{}{
+							"snippets": []interface{}{"11"},
+						},
+					},
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(2),
+							"id":    int64(2),
+						},
+						"highlight": map[string]interface{}{
+							"snippets": []interface{}{},
+						},
+					},
+				},
+			},
+		},
+	}, val)
+
+	// The query arg is found by its GraphQL name, not its Go name.
+	type TaggedSearchArgs struct {
+		Text  string `graphql:"query"`
+		Query string `graphql:"scope"`
+	}
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	item = schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("search", func(args TaggedSearchArgs) []Item {
+		return []Item{{Id: 11}, {Id: 2}}
+	}, schemabuilder.Paginated.WithEdgeHighlighter(func(ctx context.Context, node interface{}, query string) schemabuilder.Highlight {
+		return schemabuilder.Highlight{Snippets: []string{query}}
+	}))
+	builtSchema = schema.MustBuild()
+	q = graphql.MustParse(`{ inner { search(first: 1, query: "1", scope: "all") { edges { highlight { snippets } } } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	val, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"search": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{
+						"highlight": map[string]interface{}{
+							"snippets": []interface{}{"1"},
+						},
+					},
+				},
+			},
+		},
+	}, val)
+
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	item = schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("search", func(args Args) []Item {
+		return nil
+	}, schemabuilder.Paginated.WithEdgeHighlighter(func(ctx context.Context, node interface{}, query string) schemabuilder.Highlight {
+		return schemabuilder.Highlight{}
+	}))
+	_, err = schema.Build()
+	if err == nil || err.Error() != "bad method inner on type schemabuilder.query: paginated fields with an edge highlighter must take a string query arg" {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestConnectionNodes(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []*Item {
+		return []*Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}, {Id: 5}}
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection(first: 2, after: "MQ==") {
+					nodes {
+						id
+					}
+					edges {
+						node {
+							id
+						}
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"nodes": []interface{}{
+					map[string]interface{}{
+						"__key": int64(2),
+						"id":    int64(2),
+					},
+					map[string]interface{}{
+						"__key": int64(3),
+						"id":    int64(3),
+					},
+				},
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(2),
+							"id":    int64(2),
+						},
+					},
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(3),
+							"id":    int64(3),
+						},
+					},
+				},
+			},
+		},
+	}, val)
+}
+
+func TestAnalyzeConnectionQuery(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []*Item {
+		return nil
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection(first: 2) {
+					edges {
+						node {
+							id
+						}
+						cursor
+					}
+					pageInfo {
+						hasNextPage
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	accesses, err := graphql.AnalyzeQuery(builtSchema.Query, q.SelectionSet)
+	assert.Nil(t, err)
+	assert.Equal(t, []graphql.FieldAccess{
+		{Type: "ItemConnection", Field: "edges"},
+		{Type: "ItemConnection", Field: "pageInfo"},
+		{Type: "ItemEdge", Field: "cursor"},
+		{Type: "ItemEdge", Field: "node"},
+		{Type: "PageInfo", Field: "hasNextPage"},
+		{Type: "Query", Field: "inner"},
+		{Type: "inner", Field: "innerConnection"},
+		{Type: "item", Field: "id"},
+	}, accesses)
+}
+
+func TestQueryCost(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []*Item {
+		return []*Item{{Id: 1}, {Id: 2}}
+	}, schemabuilder.Paginated)
+	inner.FieldFunc("expensive", func() string {
+		return "expensive"
+	}, schemabuilder.Cost(5))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection(first: 10) {
+					edges {
+						node {
+							id
+						}
+					}
+				}
+				expensive
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	// The connection costs 1, plus 10 times its edges, nodes and ids.
+	assert.Equal(t, 37, graphql.QueryCost(context.Background(), builtSchema.Query, q.SelectionSet))
+
+	e := graphql.Executor{MaxCost: 37}
+	_, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+
+	overBudget := graphql.Executor{MaxCost: 36}
+	_, err = overBudget.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err == nil || err.Error() != "query cost 37 exceeds max cost 36" {
+		t.Errorf("expected cost error, got %v", err)
+	}
+
+	// Without a first or last, the max page size of the context counts, and
+	// unbounded connections cost the configured page size.
+	unbounded := graphql.MustParse(`
+		{
+			inner {
+				innerConnection {
+					edges {
+						node {
+							id
+						}
+					}
+				}
+			}
+		}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, unbounded.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 1+1+100*3, graphql.QueryCost(context.Background(), builtSchema.Query, unbounded.SelectionSet))
+	ctx := graphql.WithMaxPageSize(context.Background(), 4)
+	assert.Equal(t, 1+1+4*3, graphql.QueryCost(ctx, builtSchema.Query, unbounded.SelectionSet))
+
+	schema = schemabuilder.NewSchema(schemabuilder.WithUnboundedPageCost(20))
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	item = schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []*Item {
+		return nil
+	}, schemabuilder.Paginated)
+	builtSchema = schema.MustBuild()
+	if err := graphql.PrepareQuery(builtSchema.Query, unbounded.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 1+1+20*3, graphql.QueryCost(context.Background(), builtSchema.Query, unbounded.SelectionSet))
+}
+
+func TestPaginationMaxLimit(t *testing.T) {
+	type Inner struct {
+	}
+
+	makeSchema := func(opts []schemabuilder.SchemaOption, fieldOpt schemabuilder.FieldFuncOption) *graphql.Schema {
+		schema := schemabuilder.NewSchema(opts...)
+		query := schema.Query()
+		query.FieldFunc("inner", func() Inner {
+			return Inner{}
+		})
+		inner := schema.Object("inner", Inner{})
+		item := schema.Object("item", Item{})
+		item.Key("id")
+		inner.FieldFunc("innerConnection", func() []Item {
+			return []Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}, {Id: 5}}
+		}, fieldOpt)
+		return schema.MustBuild()
+	}
+
+	executeWithContext := func(ctx context.Context, builtSchema *graphql.Schema, args string) (interface{}, error) {
+		q := graphql.MustParse(fmt.Sprintf(`
+			{
+				inner {
+					innerConnection%s {
+						edges {
+							cursor
+						}
+						pageInfo {
+							hasNextPage
+						}
+					}
+				}
+			}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(ctx, builtSchema.Query, nil, q)
+	}
+	execute := func(builtSchema *graphql.Schema, args string) (interface{}, error) {
+		return executeWithContext(context.Background(), builtSchema, args)
+	}
+
+	builtSchema := makeSchema(nil, schemabuilder.Paginated.WithMaxLimit(2))
+
+	_, err := execute(builtSchema, "(first: 3)")
+	if err == nil || err.Error() != "first should not be greater than 2" {
+		t.Errorf("bad error: %v", err)
+	}
+	_, err = execute(builtSchema, "(last: 3)")
+	if err == nil || err.Error() != "last should not be greater than 2" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	val, err := execute(builtSchema, "(last: 2)")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "NA=="},
+					map[string]interface{}{"cursor": "NQ=="},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": false,
+				},
+			},
+		},
+	}, val)
+
+	// Without first or last, the limit should be applied instead of returning the entire set.
+	val, err = execute(builtSchema, "")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "MQ=="},
+					map[string]interface{}{"cursor": "Mg=="},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+				},
+			},
+		},
+	}, val)
+
+	// The schema-wide limit applies unless the field overrides it.
+	builtSchema = makeSchema([]schemabuilder.SchemaOption{schemabuilder.WithMaxPaginationLimit(3)}, schemabuilder.Paginated)
+	_, err = execute(builtSchema, "(first: 4)")
+	if err == nil || err.Error() != "first should not be greater than 3" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	builtSchema = makeSchema([]schemabuilder.SchemaOption{schemabuilder.WithMaxPaginationLimit(3)}, schemabuilder.Paginated.WithMaxLimit(4))
+	_, err = execute(builtSchema, "(first: 4)")
+	assert.Nil(t, err)
+
+	// A max page size in the context lowers the limits of the schema and the field, but doesn't
+	// raise them.
+	userCtx := graphql.WithMaxPageSize(context.Background(), 1)
+	adminCtx := graphql.WithMaxPageSize(context.Background(), 5)
+
+	_, err = executeWithContext(userCtx, builtSchema, "(first: 2)")
+	if err == nil || err.Error() != "first should not be greater than 1" {
+		t.Errorf("bad error: %v", err)
+	}
+	_, err = executeWithContext(adminCtx, builtSchema, "(first: 4)")
+	assert.Nil(t, err)
+	_, err = executeWithContext(adminCtx, builtSchema, "(first: 5)")
+	if err == nil || err.Error() != "first should not be greater than 4" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	// Without limits on the schema or the field, the context's max page size applies.
+	unlimitedSchema := makeSchema(nil, schemabuilder.Paginated)
+	_, err = executeWithContext(adminCtx, unlimitedSchema, "(first: 6)")
+	if err == nil || err.Error() != "first should not be greater than 5" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	val, err = executeWithContext(userCtx, builtSchema, "")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "MQ=="},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+				},
+			},
+		},
+	}, val)
+}
+
+func TestPaginationRequiredNodeID(t *testing.T) {
+	type Inner struct {
+	}
+	type Widget struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("items", func() []Item {
+		return nil
+	}, schemabuilder.Paginated.WithRequiredNodeID())
+	_, err := schema.Build()
+	assert.Nil(t, err)
+
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	widget := schema.Object("widget", Widget{})
+	widget.Key("name")
+	inner.FieldFunc("widgets", func() []Widget {
+		return nil
+	}, schemabuilder.Paginated.WithRequiredNodeID())
+	_, err = schema.Build()
+	if err == nil || err.Error() != "Query.inner.widgets: widget must expose an id field for client-side normalization" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	// Node types can have connections of themselves, whatever the order their fields are built in.
+	type Folder struct {
+		Key int64
+	}
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("root", func() Folder {
+		return Folder{}
+	})
+	folder := schema.Object("Folder", Folder{})
+	folder.Key("key")
+	folder.FieldFunc("children", func(f Folder) []Folder {
+		return nil
+	}, schemabuilder.Paginated.WithRequiredNodeID())
+	folder.FieldFunc("id", func(f Folder) string {
+		return fmt.Sprint(f.Key)
+	})
+	_, err = schema.Build()
+	assert.Nil(t, err)
+}
+
+func TestTotalCountEstimate(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func(args EmbeddedArgs) ([]Item, schemabuilder.PaginationInfo, error) {
+		return []Item{{Id: 1}},
+			schemabuilder.PaginationInfo{
+				HasNextPage:         true,
+				EstimatedTotalCount: func() int64 { return int64(1200000) },
+			}, nil
+	}, schemabuilder.Paginated.WithTotalCountEstimate())
+	inner.FieldFunc("innerConnectionWithoutEstimate", func(args EmbeddedArgs) ([]Item, schemabuilder.PaginationInfo, error) {
+		return []Item{{Id: 1}}, schemabuilder.PaginationInfo{}, nil
+	}, schemabuilder.Paginated.WithTotalCountEstimate())
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection(first: 1, additional: "jk") {
+					totalCountEstimate
+				}
+				innerConnectionWithoutEstimate(first: 1, additional: "jk") {
+					totalCountEstimate
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"totalCountEstimate": int64(1200000),
+			},
+			"innerConnectionWithoutEstimate": map[string]interface{}{
+				"totalCountEstimate": (*int64)(nil),
+			},
+		},
+	}, val)
+}
+
+func TestPaginationDefaultLimit(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}, {Id: 5}}
+	}, schemabuilder.Paginated.WithDefaultLimit(2))
+	inner.FieldFunc("innerConnectionLargeDefault", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}, {Id: 5}}
+	}, schemabuilder.Paginated.WithDefaultLimit(5))
+	inner.FieldFunc("innerConnectionCappedDefault", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}, {Id: 5}}
+	}, schemabuilder.Paginated.WithDefaultLimit(5).WithMaxLimit(3))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection {
+					edges {
+						cursor
+					}
+					pageInfo {
+						hasNextPage
+					}
+				}
+				innerConnectionLargeDefault {
+					pageInfo {
+						hasNextPage
+					}
+				}
+				innerConnectionCappedDefault {
+					pageInfo {
+						endCursor
+					}
+				}
+				explicit: innerConnection(first: 3) {
+					pageInfo {
+						hasNextPage
+						endCursor
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "MQ=="},
+					map[string]interface{}{"cursor": "Mg=="},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+				},
+			},
+			"innerConnectionLargeDefault": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": false,
+				},
+			},
+			"innerConnectionCappedDefault": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"endCursor": "Mw==",
+				},
+			},
+			"explicit": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+					"endCursor":   "Mw==",
+				},
+			},
+		},
+	}, val)
+
+	// Defaults are clamped to the field's max page size above, and to the request's max page size.
+	q = graphql.MustParse(`
+		{
+			inner {
+				innerConnectionLargeDefault {
+					pageInfo {
+						endCursor
+					}
+				}
+			}
+		}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	val, err = e.Execute(graphql.WithMaxPageSize(context.Background(), 4), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnectionLargeDefault": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"endCursor": "NA==",
+				},
+			},
+		},
+	}, val)
+}
+
+type Person struct {
+	Id   int64
+	Name string
+	Age  int64
+}
+
+func TestPaginationSortFields(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	people := []*Person{
+		{Id: 1, Name: "carol", Age: 30},
+		{Id: 2, Name: "alice", Age: 50},
+		{Id: 4, Name: "dave", Age: 40},
+		{Id: 3, Name: "bob", Age: 40},
+	}
+
+	inner := schema.Object("inner", Inner{})
+	person := schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("people", func() []*Person {
+		return people
+	}, schemabuilder.Paginated.WithSortFields("name", "age"))
+
+	var orderBy *string
+	inner.FieldFunc("peopleExternal", func(args EmbeddedArgs) ([]*Person, schemabuilder.PaginationInfo) {
+		orderBy = args.OrderBy
+		return people[:1], schemabuilder.PaginationInfo{}
+	}, schemabuilder.Paginated.WithSortFields("name").WithConnectionName("ExternalPerson"))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				people(first: 2, orderBy: name) {
+					edges {
+						node {
+							name
+						}
+						cursor
+					}
+				}
+				byKey: people(first: 1) {
+					edges {
+						cursor
+					}
+				}
+				peopleExternal(orderBy: name, additional: "jk") {
+					edges {
+						cursor
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"people": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(2),
+							"name":  "alice",
+						},
+						"cursor": "WyJhbGljZSIsIjIiXQ==",
+					},
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(3),
+							"name":  "bob",
+						},
+						"cursor": "WyJib2IiLCIzIl0=",
+					},
+				},
+			},
+			"byKey": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "MQ=="},
+				},
+			},
+			"peopleExternal": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"cursor": "WyJjYXJvbCIsIjEiXQ=="},
+				},
+			},
+		},
+	}, val)
+	if orderBy == nil || *orderBy != "name" {
+		t.Errorf("bad orderBy: %v", orderBy)
+	}
+
+	// Nodes with equal sort values are ordered by key, and get distinct cursors holding both.
+	q = graphql.MustParse(`
+		{
+			inner {
+				people(orderBy: age, after: "WyI0MCIsIjMiXQ==") {
+					edges {
+						node {
+							name
+						}
+						cursor
+					}
+				}
+			}
+		}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	val, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"people": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(4),
+							"name":  "dave",
+						},
+						"cursor": "WyI0MCIsIjQiXQ==",
+					},
+					map[string]interface{}{
+						"node": map[string]interface{}{
+							"__key": int64(2),
+							"name":  "alice",
+						},
+						"cursor": "WyI1MCIsIjIiXQ==",
+					},
+				},
+			},
+		},
+	}, val)
+
+	q = graphql.MustParse(`
+		{
+			inner {
+				people(orderBy: id) {
+					totalCount
+				}
+			}
+		}`, nil)
+	err = graphql.PrepareQuery(builtSchema.Query, q.SelectionSet)
+	if err == nil || err.Error() != `error parsing args for "people": orderBy: unknown enum value id` {
+		t.Errorf("bad error: %v", err)
+	}
+
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	person = schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("people", func() []*Person {
+		return nil
+	}, schemabuilder.Paginated.WithSortFields("height"))
+	_, err = schema.Build()
+	if err == nil || err.Error() != "bad method inner on type schemabuilder.query: sort field height doesn't exist on graphql_test.Person" {
+		t.Errorf("bad error: %v", err)
+	}
+
+	// Sort fields are named like the fields of the node, also with a field name mapper, and the
+	// after key is the sort value.
+	schema = schemabuilder.NewSchema()
+	schema.SetFieldNameMapper(func(goName string) string { return "f_" + strings.ToLower(goName) })
+	var afterKey interface{}
+	schema.Query().FieldFunc("people", func(args struct{ schemabuilder.PaginationArgs }) ([]*Person, schemabuilder.PaginationInfo) {
+		afterKey, _, err = args.AfterKey()
+		return nil, schemabuilder.PaginationInfo{}
+	}, schemabuilder.Paginated.WithSortFields("f_age"))
+	schema.Object("person", Person{}).Key("f_id")
+	builtSchema = schema.MustBuild()
+	q = graphql.MustParse(`{ people(orderBy: f_age, after: "WyI0MCIsIjMiXQ==") { totalCount } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(40), afterKey)
+}
+
+func TestPaginationStableSort(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	people := []*Person{
+		{Id: 3, Name: "carol"},
+		{Id: 1, Name: "alice"},
+		{Id: 2, Name: "bob"},
+		{Id: 1, Name: "dave"},
+	}
+
+	inner := schema.Object("inner", Inner{})
+	person := schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("people", func() []*Person {
+		return people
+	}, schemabuilder.Paginated.WithStableSort())
+	inner.FieldFunc("unsorted", func() []*Person {
+		return people
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	execute := func(query string) interface{} {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+
+	names := func(val interface{}, field string) []string {
+		var names []string
+		edges := val.(map[string]interface{})["inner"].(map[string]interface{})[field].(map[string]interface{})["edges"].([]interface{})
+		for _, edge := range edges {
+			names = append(names, edge.(map[string]interface{})["node"].(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	// Nodes are sorted by key, and nodes with equal keys keep their order.
+	val := execute(`{ inner { people(first: 4) { edges { node { name } } } unsorted(first: 4) { edges { node { name } } } } }`)
+	assert.Equal(t, []string{"alice", "dave", "bob", "carol"}, names(val, "people"))
+	assert.Equal(t, []string{"carol", "alice", "bob", "dave"}, names(val, "unsorted"))
+	assert.Contains(t, logs.String(), "schemabuilder: duplicate cursor MQ== for Id in page")
+
+	// Pages without duplicate cursors don't warn.
+	logs.Reset()
+	val = execute(`{ inner { people(first: 2, after: "Mg==") { edges { node { name } } } } }`)
+	assert.Equal(t, []string{"carol"}, names(val, "people"))
+	assert.Empty(t, logs.String())
+}
+
+func TestPaginationOverfetch(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+	type Args struct {
+		schemabuilder.PaginationArgs
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	var people []*Person
+	for i := int64(1); i <= 5; i++ {
+		people = append(people, &Person{Id: i, Name: fmt.Sprint("person", i)})
+	}
+
+	// people returns the people after the after cursor and before the before cursor, overfetching
+	// one person.
+	inner := schema.Object("inner", Inner{})
+	person := schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("people", func(args Args) []*Person {
+		var nodes []*Person
+		after, _, _ := args.AfterKey()
+		before, _, _ := args.BeforeKey()
+		for _, p := range people {
+			if (after == nil || p.Id > after.(int64)) && (before == nil || p.Id < before.(int64)) {
+				nodes = append(nodes, p)
+			}
+		}
+		if args.First != nil && len(nodes) > int(*args.First)+1 {
+			nodes = nodes[:*args.First+1]
+		}
+		if args.Last != nil && len(nodes) > int(*args.Last)+1 {
+			nodes = nodes[len(nodes)-int(*args.Last)-1:]
+		}
+		return nodes
+	}, schemabuilder.Paginated.WithOverfetch())
+	builtSchema := schema.MustBuild()
+
+	execute := func(args string) (interface{}, error) {
+		q := graphql.MustParse(fmt.Sprintf(`{
+			inner {
+				people(%s) {
+					nodes { name }
+					pageInfo { hasNextPage hasPrevPage }
+				}
+			}
+		}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			return nil, err
+		}
+		people := val.(map[string]interface{})["inner"].(map[string]interface{})["people"].(map[string]interface{})
+		var names []interface{}
+		for _, node := range people["nodes"].([]interface{}) {
+			names = append(names, node.(map[string]interface{})["name"])
+		}
+		return map[string]interface{}{"names": names, "pageInfo": people["pageInfo"]}, nil
+	}
+
+	pageInfo := func(hasNext, hasPrev bool) map[string]interface{} {
+		return map[string]interface{}{"hasNextPage": hasNext, "hasPrevPage": hasPrev}
+	}
+	for _, testCase := range []struct {
+		args     string
+		names    []interface{}
+		pageInfo map[string]interface{}
+	}{
+		{`first: 2`, []interface{}{"person1", "person2"}, pageInfo(true, false)},
+		{`first: 5`, []interface{}{"person1", "person2", "person3", "person4", "person5"}, pageInfo(false, false)},
+		{`first: 2, after: "Mg=="`, []interface{}{"person3", "person4"}, pageInfo(true, true)},
+		{`first: 2, after: "Mw=="`, []interface{}{"person4", "person5"}, pageInfo(false, true)},
+		{`last: 2`, []interface{}{"person4", "person5"}, pageInfo(false, true)},
+		{`last: 2, before: "Mw=="`, []interface{}{"person1", "person2"}, pageInfo(true, false)},
+	} {
+		val, err := execute(testCase.args)
+		if !assert.NoError(t, err, testCase.args) {
+			continue
+		}
+		assert.Equal(t, map[string]interface{}{"names": testCase.names, "pageInfo": testCase.pageInfo}, val, testCase.args)
+	}
+
+	// The total count of overfetched nodes is unknown.
+	q := graphql.MustParse(`{ inner { people(first: 1) { totalCount } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil || err.Error() != `unknown field "totalCount"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+	inner = schema.Object("inner", Inner{})
+	person = schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("people", func() []*Person {
+		return nil
+	}, schemabuilder.Paginated.WithOverfetch())
+	_, err := schema.Build()
+	if err == nil || err.Error() != "bad method inner on type schemabuilder.query: if nodes are overfetched then pagination args must be embedded" {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestPaginationRelativePages(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	var people []*Person
+	for i := int64(1); i <= 10; i++ {
+		people = append(people, &Person{Id: i})
+	}
+
+	inner := schema.Object("inner", Inner{})
+	person := schema.Object("person", Person{})
+	person.Key("id")
+	inner.FieldFunc("relative", func() []*Person {
+		return people
+	}, schemabuilder.Paginated.WithRelativePages())
+	inner.FieldFunc("absolute", func() []*Person {
+		return people
+	}, schemabuilder.Paginated)
+	inner.FieldFunc("withoutPages", func() []*Person {
+		return people
+	}, schemabuilder.Paginated.WithoutPages().WithConnectionName("UnpagedPerson"))
+	builtSchema := schema.MustBuild()
+
+	pages := func(args string) map[string]interface{} {
+		q := graphql.MustParse(fmt.Sprintf(`{
+			inner {
+				relative(%[1]s) { pageInfo { pages } }
+				absolute(%[1]s) { pageInfo { pages } }
+			}
+		}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]interface{})
+		for name, conn := range val.(map[string]interface{})["inner"].(map[string]interface{}) {
+			result[name] = conn.(map[string]interface{})["pageInfo"].(map[string]interface{})["pages"]
+		}
+		return result
+	}
+
+	// The cursors are the base64 encoded ids. Relative pages are anchored to the after cursor 4.
+	assert.Equal(t, map[string]interface{}{
+		"relative": []interface{}{"", "MQ==", "NA==", "Nw=="},
+		"absolute": []interface{}{"", "Mw==", "Ng==", "OQ=="},
+	}, pages(`first: 3, after: "NA=="`))
+
+	// Without a cursor the pages are absolute.
+	assert.Equal(t, map[string]interface{}{
+		"relative": []interface{}{"", "Mw==", "Ng==", "OQ=="},
+		"absolute": []interface{}{"", "Mw==", "Ng==", "OQ=="},
+	}, pages(`first: 3`))
+
+	// Backward pages are anchored to the before cursor 7.
+	assert.Equal(t, map[string]interface{}{
+		"relative": []interface{}{"NA==", "Nw==", "MTA=", ""},
+		"absolute": []interface{}{"Mg==", "NQ==", "OA==", ""},
+	}, pages(`last: 3, before: "Nw=="`))
+
+	// Relative pages don't shift when nodes are added before the anchor.
+	people = append([]*Person{{Id: 0}}, people...)
+	assert.Equal(t, map[string]interface{}{
+		"relative": []interface{}{"", "MQ==", "NA==", "Nw=="},
+		"absolute": []interface{}{"", "Mg==", "NQ==", "OA=="},
+	}, pages(`first: 3, after: "NA=="`))
+
+	q := graphql.MustParse(`{ inner { withoutPages(first: 3) { pageInfo { pages } } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil || err.Error() != `unknown field "pages"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestPaginationReturnsConnection(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+	type Args struct {
+		schemabuilder.PaginationArgs
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func(args Args) (*schemabuilder.Connection, error) {
+		// Pretend that the database already applied first.
+		var edges []schemabuilder.Edge
+		for i := int64(1); i <= *args.First; i++ {
+			edges = append(edges, schemabuilder.Edge{Node: Item{Id: i}, Cursor: fmt.Sprintf("cursor%d", i)})
+		}
+		return &schemabuilder.Connection{
+			TotalCount: 10,
+			Edges:      edges,
+			PageInfo: schemabuilder.PageInfo{
+				HasNextPage: true,
+				StartCursor: edges[0].Cursor,
+				EndCursor:   edges[len(edges)-1].Cursor,
+			},
+		}, nil
+	}, schemabuilder.Paginated.WithNodeType(Item{}).WithDefaultLimit(2))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			inner {
+				innerConnection {
+					totalCount
+					edges {
+						node {
+							id
+						}
+						cursor
+					}
+					pageInfo {
+						hasNextPage
+						endCursor
+					}
+				}
+			}
+		}`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"totalCount": int64(10),
+				"edges": []interface{}{
+					map[string]interface{}{
+						"node":   map[string]interface{}{"id": int64(1), "__key": int64(1)},
+						"cursor": "cursor1",
+					},
+					map[string]interface{}{
+						"node":   map[string]interface{}{"id": int64(2), "__key": int64(2)},
+						"cursor": "cursor2",
+					},
+				},
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": true,
+					"endCursor":   "cursor2",
+				},
+			},
+		},
+	}, val)
+
+	schema = schemabuilder.NewSchema()
+	schema.Object("item", Item{}).Key("id")
+	schema.Query().FieldFunc("inner", func(args Args) (*schemabuilder.Connection, error) {
+		return nil, nil
+	}, schemabuilder.Paginated)
+	_, err = schema.Build()
+	if err == nil || err.Error() != "if a *Connection is returned then the node type must be configured with Paginated.WithNodeType" {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestPaginationScoredNodes(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func() []schemabuilder.ScoredNode {
+		return []schemabuilder.ScoredNode{
+			{Node: Item{Id: 1}, Score: 0.5},
+			{Node: Item{Id: 2}, Score: 0.9},
+			{Node: Item{Id: 4}, Score: 0.5},
+			{Node: Item{Id: 3}, Score: 0.5},
+			{Node: Item{Id: 5}, Score: 0.1},
+		}
+	}, schemabuilder.Paginated.WithNodeType(Item{}))
+	builtSchema := schema.MustBuild()
+
+	execute := func(args string) (interface{}, error) {
+		q := graphql.MustParse(fmt.Sprintf(`
+			{
+				inner {
+					innerConnection%s {
+						edges {
+							node {
+								id
+							}
+							score
+							cursor
+						}
+					}
+				}
+			}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+	edges := func(val interface{}) []interface{} {
+		return val.(map[string]interface{})["inner"].(map[string]interface{})["innerConnection"].(map[string]interface{})["edges"].([]interface{})
+	}
+
+	val, err := execute("(first: 3)")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"node":   map[string]interface{}{"id": int64(2), "__key": int64(2)},
+			"score":  0.9,
+			"cursor": "MC45OjI=",
+		},
+		map[string]interface{}{
+			"node":   map[string]interface{}{"id": int64(1), "__key": int64(1)},
+			"score":  0.5,
+			"cursor": "MC41OjE=",
+		},
+		map[string]interface{}{
+			"node":   map[string]interface{}{"id": int64(3), "__key": int64(3)},
+			"score":  0.5,
+			"cursor": "MC41OjM=",
+		},
+	}, edges(val))
+
+	val, err = execute(`(first: 3, after: "MC41OjM=")`)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"node":   map[string]interface{}{"id": int64(4), "__key": int64(4)},
+			"score":  0.5,
+			"cursor": "MC41OjQ=",
+		},
+		map[string]interface{}{
+			"node":   map[string]interface{}{"id": int64(5), "__key": int64(5)},
+			"score":  0.1,
+			"cursor": "MC4xOjU=",
+		},
+	}, edges(val))
+}
+
+func TestLazyTotalCount(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	counts := 0
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func(args EmbeddedArgs) ([]Item, schemabuilder.PaginationInfo, error) {
+		return []Item{{Id: 1}, {Id: 2}},
+			schemabuilder.PaginationInfo{
+				HasNextPage: true,
+				TotalCount: func() int64 {
+					counts++
+					return 5
+				},
+			}, nil
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	execute := func(selection string) (interface{}, error) {
+		q := graphql.MustParse(fmt.Sprintf(`
+			{
+				inner {
+					innerConnection(first: 2, additional: "jk") {
+						%s
+					}
+				}
+			}`, selection), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	_, err := execute("pageInfo { hasNextPage }")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, counts)
+
+	val, err := execute("totalCount")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, counts)
+	assert.Equal(t, map[string]interface{}{
+		"inner": map[string]interface{}{
+			"innerConnection": map[string]interface{}{
+				"totalCount": int64(5),
+			},
+		},
+	}, val)
+}
+
+func TestEmptyExternalPagination(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	query.FieldFunc("items", func(args EmbeddedArgs) ([]Item, schemabuilder.PaginationInfo, error) {
+		return nil,
+			schemabuilder.PaginationInfo{
+				HasNextPage: true,
+				HasPrevPage: true,
+				TotalCount: func() int64 {
+					return 7
+				},
+			}, nil
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		items(first: 2, after: "Mg==", additional: "jk") {
+			totalCount
+			edges { node { id } }
+			pageInfo { hasNextPage hasPrevPage startCursor endCursor }
+		}
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"items": map[string]interface{}{
+			"totalCount": int64(7),
+			"edges":      []interface{}{},
+			"pageInfo": map[string]interface{}{
+				"hasNextPage": true,
+				"hasPrevPage": true,
+				"startCursor": "",
+				"endCursor":   "",
+			},
+		},
+	}, val)
+}
+
+func TestPaginationCursorKeys(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	type Inner struct {
+	}
+
+	query := schema.Query()
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	})
+
+	type cursorKey struct {
+		Key interface{}
+		Ok  bool
+		Err error
+	}
+	var after, before cursorKey
+
+	inner := schema.Object("inner", Inner{})
+	item := schema.Object("item", Item{})
+	item.Key("id")
+	inner.FieldFunc("innerConnection", func(args EmbeddedArgs) ([]Item, schemabuilder.PaginationInfo, error) {
+		after.Key, after.Ok, after.Err = args.AfterKey()
+		before.Key, before.Ok, before.Err = args.BeforeKey()
+		return []Item{{Id: 3}}, schemabuilder.PaginationInfo{}, nil
+	}, schemabuilder.Paginated)
+	builtSchema := schema.MustBuild()
+
+	execute := func(args string) error {
+		q := graphql.MustParse(fmt.Sprintf(`
+			{
+				inner {
+					innerConnection(first: 1, additional: "jk"%s) {
+						totalCount
+					}
+				}
+			}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return err
+		}
+		e := graphql.Executor{}
+		_, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return err
+	}
+
+	assert.Nil(t, execute(`, after: "Mg=="`))
+	assert.Equal(t, cursorKey{Key: int64(2), Ok: true}, after)
+	assert.Equal(t, cursorKey{}, before)
+
+	assert.Nil(t, execute(`, before: "bm90IGFuIGlk"`))
+	assert.Equal(t, cursorKey{}, after)
+	assert.True(t, before.Ok)
+	if before.Err == nil || before.Err.Error() != `bad cursor: strconv.ParseInt: parsing "not an id": invalid syntax` {
+		t.Errorf("bad error: %v", before.Err)
+	}
+}
+
+func TestEdgesToReturnPageInfo(t *testing.T) {
+	allEdges := []schemabuilder.Edge{{Cursor: "a"}, {Cursor: "b"}, {Cursor: "c"}, {Cursor: "d"}, {Cursor: "e"}}
+	str := func(s string) *string { return &s }
+	num := func(n int64) *int64 { return &n }
+
+	testCases := []struct {
+		name          string
+		before, after *string
+		first, last   *int64
+		cursors       []string
+		hasNext       bool
+		hasPrev       bool
+	}{
+		{name: "none", cursors: []string{"a", "b", "c", "d", "e"}},
+		{name: "first", first: num(2), cursors: []string{"a", "b"}, hasNext: true},
+		{name: "first all", first: num(5), cursors: []string{"a", "b", "c", "d", "e"}},
+		{name: "first after", first: num(2), after: str("a"), cursors: []string{"b", "c"}, hasNext: true},
+		{name: "first after first page", first: num(2), after: str("b"), cursors: []string{"c", "d"}, hasNext: true, hasPrev: true},
+		{name: "first after last page", first: num(2), after: str("c"), cursors: []string{"d", "e"}, hasPrev: true},
+		{name: "first before", first: num(2), before: str("e"), cursors: []string{"a", "b"}, hasNext: true},
+		{name: "first before end", first: num(5), before: str("d"), cursors: []string{"a", "b", "c"}, hasNext: true},
+		{name: "first before last", first: num(5), before: str("e"), cursors: []string{"a", "b", "c", "d"}},
+		{name: "last", last: num(2), cursors: []string{"d", "e"}, hasPrev: true},
+		{name: "last all", last: num(5), cursors: []string{"a", "b", "c", "d", "e"}},
+		{name: "last before", last: num(2), before: str("e"), cursors: []string{"c", "d"}, hasPrev: true},
+		{name: "last before last page", last: num(2), before: str("d"), cursors: []string{"b", "c"}, hasNext: true, hasPrev: true},
+		{name: "last before first page", last: num(2), before: str("c"), cursors: []string{"a", "b"}, hasNext: true},
+		{name: "last after", last: num(5), after: str("b"), cursors: []string{"c", "d", "e"}, hasPrev: true},
+		{name: "last after first", last: num(5), after: str("a"), cursors: []string{"b", "c", "d", "e"}},
+		{name: "after before", after: str("a"), before: str("e"), cursors: []string{"b", "c", "d"}},
+		{name: "after before inner", after: str("b"), before: str("d"), cursors: []string{"c"}, hasNext: true, hasPrev: true},
+		{name: "first last", first: num(4), last: num(2), cursors: []string{"c", "d"}, hasNext: true, hasPrev: true},
+		{name: "first last all", first: num(5), last: num(5), cursors: []string{"a", "b", "c", "d", "e"}},
+		{name: "unknown cursors", after: str("x"), before: str("y"), first: num(2), cursors: []string{"a", "b"}, hasNext: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			edges, hasNext, hasPrev, err := schemabuilder.EdgesToReturn(allEdges, testCase.before, testCase.after, testCase.first, testCase.last)
+			assert.NoError(t, err)
+			cursors := make([]string, 0, len(edges))
+			for _, edge := range edges {
+				cursors = append(cursors, edge.Cursor)
+			}
+			assert.Equal(t, testCase.cursors, cursors)
+			assert.Equal(t, testCase.hasNext, hasNext, "hasNextPage")
+			assert.Equal(t, testCase.hasPrev, hasPrev, "hasPreviousPage")
+		})
+	}
+
+	if _, _, _, err := schemabuilder.EdgesToReturn(allEdges, nil, nil, num(-1), nil); err == nil || err.Error() != "first should be a non-negative integer" {
+		t.Errorf("expected first error, got %v", err)
+	}
+	if _, _, _, err := schemabuilder.EdgesToReturn(allEdges, nil, nil, nil, num(-1)); err == nil || err.Error() != "last should be a non-negative integer" {
+		t.Errorf("expected last error, got %v", err)
+	}
+}
+
+func TestPaginationEdgeStructs(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	type Membership struct {
+		schemabuilder.Edge
+		Role string
+	}
+	type Group struct {
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("group", func() Group {
+		return Group{}
+	})
+	user := schema.Object("User", User{})
+	user.Key("id")
+	group := schema.Object("Group", Group{})
+	group.FieldFunc("members", func() []Membership {
+		return []Membership{
+			{Edge: schemabuilder.Edge{Node: &User{Id: 3, Name: "carol"}}, Role: "member"},
+			{Edge: schemabuilder.Edge{Node: &User{Id: 1, Name: "alice"}}, Role: "owner"},
+			{Edge: schemabuilder.Edge{Node: &User{Id: 2, Name: "bob"}}, Role: "admin"},
+		}
+	}, schemabuilder.Paginated.WithNodeType(&User{}).WithStableSort())
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		group {
+			members(first: 2, after: "MQ==") {
+				edges { node { name } role cursor }
+				pageInfo { hasNextPage }
+			}
+		}
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"group": {
+			"members": {
+				"edges": [
+					{"node": {"name": "bob", "__key": 2}, "role": "admin", "cursor": "Mg=="},
+					{"node": {"name": "carol", "__key": 3}, "role": "member", "cursor": "Mw=="}
+				],
+				"pageInfo": {"hasNextPage": false}
+			}
+		}
+	}`), internal.AsJSON(val))
+
+	members := builtSchema.Query.(*graphql.Object).Fields["group"].Type.(*graphql.NonNull).Type.(*graphql.Object).Fields["members"]
+	connection := members.Type.(*graphql.NonNull).Type.(*graphql.Object)
+	assert.Equal(t, "MembershipConnection", connection.Name)
+	edge := connection.Fields["edges"].Type.(*graphql.NonNull).Type.(*graphql.List).Type.(*graphql.NonNull).Type.(*graphql.Object)
+	assert.Equal(t, "MembershipEdge", edge.Name)
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("members", func() []Membership {
+		return nil
+	}, schemabuilder.Paginated)
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "if edge structs are returned then the node type must be configured with Paginated.WithNodeType") {
+		t.Errorf("expected missing node type error, got %v", err)
+	}
+}
+
+func TestPaginationTimeKeys(t *testing.T) {
+	type Event struct {
+		Created time.Time
+		Name    string
+	}
+
+	base := time.Date(2018, 9, 13, 12, 0, 0, 500, time.UTC)
+	berlin := time.FixedZone("CEST", 2*60*60)
+	calls := 0
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	// Every call returns the times in another location, as if they were loaded anew.
+	query.FieldFunc("events", func() []Event {
+		calls++
+		loc := time.UTC
+		if calls%2 == 0 {
+			loc = berlin
+		}
+		return []Event{
+			{Created: base.Add(2 * time.Hour).In(loc), Name: "c"},
+			{Created: base.In(loc), Name: "a"},
+			{Created: base.Add(time.Hour).In(loc), Name: "b"},
+		}
+	}, schemabuilder.Paginated.WithStableSort())
+	event := schema.Object("Event", Event{})
+	event.Key("created")
+	var afterKey interface{}
+	query.FieldFunc("eventKeys", func(args struct {
+		schemabuilder.PaginationArgs
+	}) ([]Event, schemabuilder.PaginationInfo, error) {
+		afterKey, _, _ = args.AfterKey()
+		return nil, schemabuilder.PaginationInfo{}, nil
+	}, schemabuilder.Paginated.WithConnectionName("EventKey"))
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) map[string]interface{} {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(val).(map[string]interface{})
+	}
+
+	val := execute(`{ events(first: 1) { edges { cursor node { name } } } }`)
+	cursor := base64.URLEncoding.EncodeToString([]byte("2018-09-13T12:00:00.0000005Z"))
+	assert.Equal(t, internal.ParseJSON(`{"events": {"edges": [{"cursor": "`+cursor+`", "node": {"name": "a", "__key": "2018-09-13T12:00:00.0000005Z"}}]}}`), val)
+
+	val = execute(`{ events(first: 1, after: "` + cursor + `") { edges { cursor } pageInfo { hasNextPage } } }`)
+	cursor = base64.URLEncoding.EncodeToString([]byte("2018-09-13T13:00:00.0000005Z"))
+	assert.Equal(t, internal.ParseJSON(`{"events": {"edges": [{"cursor": "`+cursor+`"}], "pageInfo": {"hasNextPage": true}}}`), val)
+
+	execute(`{ eventKeys(first: 1, after: "` + cursor + `") { totalCount } }`)
+	assert.Equal(t, base.Add(time.Hour), afterKey)
+}
+
+func TestPaginationURLSafeCursors(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func() []Item {
+		// Under the standard alphabet, the names encode to "fn5+" and "Pz8/".
+		return []Item{{Name: "~~~"}, {Name: "???"}, {Name: "aaa"}}
+	}, schemabuilder.Paginated)
+	item := schema.Object("Item", Item{})
+	item.Key("name")
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) map[string]interface{} {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(val).(map[string]interface{})
+	}
+
+	val := execute(`{ items { edges { cursor } } }`)
+	assert.Equal(t, internal.ParseJSON(`{"items": {"edges": [{"cursor": "fn5-"}, {"cursor": "Pz8_"}, {"cursor": "YWFh"}]}}`), val)
+
+	// Cursors are decoded with the URL-safe alphabet, and with the standard
+	// alphabet of older cursors.
+	for _, cursor := range []string{"fn5-", "fn5+"} {
+		val = execute(`{ items(first: 1, after: "` + cursor + `") { edges { node { name } } } }`)
+		assert.Equal(t, internal.ParseJSON(`{"items": {"edges": [{"node": {"__key": "???", "name": "???"}}]}}`), val)
+	}
+}
+
+type countingEdgeSource struct {
+	names  []string
+	pulled int
+	closed bool
+}
+
+func (s *countingEdgeSource) Next() (interface{}, string, bool) {
+	if s.pulled == len(s.names) {
+		return nil, "", false
+	}
+	name := s.names[s.pulled]
+	s.pulled++
+	return &edgeSourceItem{Name: name}, "c" + name, true
+}
+
+func (s *countingEdgeSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+type edgeSourceItem struct {
+	Name string
+}
+
+func TestPaginationEdgeSource(t *testing.T) {
+	var source *countingEdgeSource
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func(args struct {
+		schemabuilder.PaginationArgs
+	}) (schemabuilder.EdgeSource, schemabuilder.PaginationInfo, error) {
+		// The source starts after the after cursor, as a database query would.
+		names := []string{"a", "b", "c", "d", "e"}
+		for i, name := range names {
+			if args.After != nil && *args.After == "c"+name {
+				names = names[i+1:]
+				break
+			}
+		}
+		source = &countingEdgeSource{names: names}
+		return source, schemabuilder.PaginationInfo{TotalCount: func() int64 { return 5 }}, nil
+	}, schemabuilder.Paginated.WithNodeType(&edgeSourceItem{}))
+	item := schema.Object("Item", edgeSourceItem{})
+	item.Key("name")
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (map[string]interface{}, error) {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			return nil, err
+		}
+		return internal.AsJSON(val).(map[string]interface{}), nil
+	}
+
+	val, err := execute(`{ items(first: 2, after: "ca") { totalCount edges { cursor node { name } } pageInfo { hasNextPage hasPrevPage endCursor } } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"items": {
+		"totalCount": 5,
+		"edges": [
+			{"cursor": "cb", "node": {"__key": "b", "name": "b"}},
+			{"cursor": "cc", "node": {"__key": "c", "name": "c"}}
+		],
+		"pageInfo": {"hasNextPage": true, "hasPrevPage": true, "endCursor": "cc"}
+	}}`), val)
+	assert.Equal(t, 3, source.pulled)
+	assert.True(t, source.closed)
+
+	if _, err := execute(`{ items(last: 2) { edges { cursor } } }`); err == nil || !strings.Contains(err.Error(), "last and before are not supported") {
+		t.Errorf("expected last to be rejected, got %v", err)
+	}
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("items", func() schemabuilder.EdgeSource {
+		return nil
+	}, schemabuilder.Paginated.WithNodeType(&edgeSourceItem{}))
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "if an EdgeSource is returned then pagination args must be embedded") {
+		t.Errorf("expected missing pagination args error, got %v", err)
+	}
+}
+
+func TestPaginationKeylessEdges(t *testing.T) {
+	// Tags have no key: their cursors are supplied by the resolver.
+	type Tag struct {
+		Label string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("tags", func(args struct {
+		schemabuilder.PaginationArgs
+	}) ([]schemabuilder.Edge, schemabuilder.PaginationInfo, error) {
+		return []schemabuilder.Edge{
+			{Node: Tag{Label: "go"}, Cursor: "offset:1"},
+			{Node: Tag{Label: "graphql"}, Cursor: "offset:2"},
+		}, schemabuilder.PaginationInfo{
+			TotalCount:  func() int64 { return 3 },
+			HasNextPage: true,
+		}, nil
+	}, schemabuilder.Paginated.WithNodeType(Tag{}))
+	query.FieldFunc("streamedTags", func(args struct {
+		schemabuilder.PaginationArgs
+	}) schemabuilder.EdgeSource {
+		return &countingEdgeSource{names: []string{"a", "b"}}
+	}, schemabuilder.Paginated.WithNodeType(&edgeSourceItem{}))
+	schema.Object("Tag", Tag{})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		tags(first: 2) { totalCount edges { cursor node { label } } pageInfo { hasNextPage startCursor endCursor } }
+		streamedTags(first: 1) { edges { cursor node { name } } }
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"tags": {
+			"totalCount": 3,
+			"edges": [
+				{"cursor": "offset:1", "node": {"label": "go"}},
+				{"cursor": "offset:2", "node": {"label": "graphql"}}
+			],
+			"pageInfo": {"hasNextPage": true, "startCursor": "offset:1", "endCursor": "offset:2"}
+		},
+		"streamedTags": {"edges": [{"cursor": "ca", "node": {"name": "a"}}]}
+	}`), internal.AsJSON(val))
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("tags", func() []schemabuilder.Edge {
+		return nil
+	}, schemabuilder.Paginated.WithNodeType(Tag{}))
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "if a []Edge is returned then pagination args must be embedded") {
+		t.Errorf("expected missing pagination args error, got %v", err)
+	}
+}
+
+func TestPaginationTotalCountType(t *testing.T) {
+	type Item struct {
+		Id int64
+	}
+
+	formatCount := func(count int64) string { return strconv.FormatInt(count, 10) }
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func() []Item {
+		return []Item{{Id: 1}, {Id: 2}}
+	}, schemabuilder.Paginated.WithTotalCountType(formatCount))
+	query.FieldFunc("huge", func(args struct {
+		schemabuilder.PaginationArgs
+	}) ([]Item, schemabuilder.PaginationInfo, error) {
+		return []Item{{Id: 1}}, schemabuilder.PaginationInfo{
+			TotalCountString: func() string { return "123456789012345678901234567890" },
+		}, nil
+	}, schemabuilder.Paginated.WithTotalCountType(formatCount).WithConnectionName("ExternalItem"))
+	query.FieldFunc("large", func(args struct {
+		schemabuilder.PaginationArgs
+	}) ([]Item, schemabuilder.PaginationInfo, error) {
+		return []Item{{Id: 1}}, schemabuilder.PaginationInfo{
+			TotalCount: func() int64 { return 1 << 60 },
+		}, nil
+	}, schemabuilder.Paginated.WithTotalCountType(formatCount).WithConnectionName("ExternalItem"))
+	item := schema.Object("Item", Item{})
+	item.Key("id")
+	builtSchema := schema.MustBuild()
+
+	connection := builtSchema.Query.(*graphql.Object).Fields["items"].Type.(*graphql.NonNull).Type.(*graphql.Object)
+	assert.Equal(t, "string!", connection.Fields["totalCount"].Type.String())
+
+	q := graphql.MustParse(`{ items { totalCount } huge(first: 1) { totalCount } large(first: 1) { totalCount } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"items": {"totalCount": "2"},
+		"huge": {"totalCount": "123456789012345678901234567890"},
+		"large": {"totalCount": "1152921504606846976"}
+	}`), internal.AsJSON(val))
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("items", func() []Item {
+		return nil
+	}, schemabuilder.Paginated.WithTotalCountType(func(count int) string { return "" }))
+	schema.Object("Item", Item{}).Key("id")
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "total count type should be configured with a func(int64) T") {
+		t.Errorf("expected bad total count type error, got %v", err)
+	}
+}
+
+func TestPaginationConflictingConnections(t *testing.T) {
+	type Item struct {
+		Id int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func() []Item {
+		return nil
+	}, schemabuilder.Paginated)
+	query.FieldFunc("estimatedItems", func() []Item {
+		return nil
+	}, schemabuilder.Paginated.WithTotalCountEstimate())
+	schema.Object("Item", Item{}).Key("id")
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "conflicting definitions of type ItemConnection") {
+		t.Errorf("expected conflicting connections error, got %v", err)
+	}
+	if err := schema.Validate(); err == nil || !strings.Contains(err.Error(), "conflicting definitions of type ItemConnection") {
+		t.Errorf("expected conflicting connections validation error, got %v", err)
+	}
+
+	schema = schemabuilder.NewSchema()
+	query = schema.Query()
+	query.FieldFunc("items", func() []Item {
+		return nil
+	}, schemabuilder.Paginated)
+	query.FieldFunc("estimatedItems", func() []Item {
+		return nil
+	}, schemabuilder.Paginated.WithTotalCountEstimate().WithConnectionName("EstimatedItem"))
+	schema.Object("Item", Item{}).Key("id")
+	builtSchema := schema.MustBuild()
+	fields := builtSchema.Query.(*graphql.Object).Fields
+	assert.Equal(t, "ItemConnection!", fields["items"].Type.String())
+	assert.Equal(t, "EstimatedItemConnection!", fields["estimatedItems"].Type.String())
+	edges := fields["estimatedItems"].Type.(*graphql.NonNull).Type.(*graphql.Object).Fields["edges"]
+	assert.Equal(t, "[EstimatedItemEdge!]!", edges.Type.String())
+}
+
+func TestPaginationArgDefaults(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	var names []string
+	query.FieldFunc("embedded", func(args struct {
+		schemabuilder.PaginationArgs
+		Name string `graphql:"name;default=alice"`
+	}) ([]Item, schemabuilder.PaginationInfo) {
+		names = append(names, args.Name)
+		return nil, schemabuilder.PaginationInfo{}
+	}, schemabuilder.Paginated.WithConnectionName("EmbeddedItem"))
+	query.FieldFunc("nested", func(args struct {
+		Name string `graphql:"name;default=bob"`
+	}) []Item {
+		names = append(names, args.Name)
+		return nil
+	}, schemabuilder.Paginated)
+	schema.Object("Item", Item{}).Key("id")
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		embedded { totalCount }
+		nested { totalCount }
+		overridden: embedded(name: "carol") { totalCount }
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	_, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	sort.Strings(names)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names)
+
+	sdl := builtSchema.SDL()
+	assert.Contains(t, sdl, `embedded(after: string, before: string, first: int64, last: int64, name: string! = "alice"): EmbeddedItemConnection!`)
+	assert.Contains(t, sdl, `nested(after: string, before: string, first: int64, last: int64, name: string! = "bob"): ItemConnection!`)
+}
@@ -3,11 +3,15 @@ package graphql_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/concurrencylimiter"
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
@@ -211,6 +215,284 @@ func TestEnum(t *testing.T) {
 
 }
 
+type role int32
+
+const (
+	roleAdmin role = iota
+	roleMember
+)
+
+func TestRegisterEnum(t *testing.T) {
+	type Member struct {
+		Name string
+		Role role
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.RegisterEnum("Role", map[interface{}]string{
+		roleAdmin:  "ADMIN",
+		roleMember: "MEMBER",
+	})
+
+	members := []Member{{Name: "alice", Role: roleAdmin}, {Name: "bob", Role: roleMember}}
+	query := schema.Query()
+	query.FieldFunc("members", func(args struct{ Role role }) []Member {
+		var ret []Member
+		for _, member := range members {
+			if member.Role == args.Role {
+				ret = append(ret, member)
+			}
+		}
+		return ret
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			members(role: MEMBER) {
+				name
+				role
+			}
+		}
+		`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"members": []interface{}{
+			map[string]interface{}{"name": "bob", "role": "MEMBER"},
+		},
+	}, val)
+
+	q = graphql.MustParse(`
+		{
+			members(role: OWNER) {
+				name
+			}
+		}
+		`, nil)
+	err = graphql.PrepareQuery(builtSchema.Query, q.SelectionSet)
+	if err == nil || err.Error() != `error parsing args for "members": role: unknown enum value OWNER` {
+		t.Errorf("bad error: %v", err)
+	}
+
+	assert.Contains(t, builtSchema.SDL(), "enum Role {\n  ADMIN\n  MEMBER\n}\n")
+	assert.Contains(t, builtSchema.SDL(), "members(role: Role!): [Member!]!")
+}
+
+func TestArgDefaults(t *testing.T) {
+	type Filter struct {
+		Active bool `graphql:"active;default=true"`
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.RegisterEnum("Role", map[interface{}]string{
+		roleAdmin:  "ADMIN",
+		roleMember: "MEMBER",
+	})
+	schema.Query().FieldFunc("search", func(args struct {
+		Limit  int64   `graphql:"limit;default=25"`
+		Name   *string `graphql:"name;default=anonymous"`
+		Role   role    `graphql:"role;default=MEMBER"`
+		Filter *Filter
+	}) string {
+		active := args.Filter != nil && args.Filter.Active
+		return fmt.Sprintf("%d %s %d %v", args.Limit, *args.Name, args.Role, active)
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			defaults: search
+			overridden: search(limit: 10, name: "bob", role: ADMIN, filter: {})
+		}
+		`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"defaults":   "25 anonymous 1 false",
+		"overridden": "10 bob 0 true",
+	}, val)
+
+	sdl := builtSchema.SDL()
+	assert.Contains(t, sdl, `search(filter: Filter_InputObject, limit: int64! = 25, name: string = "anonymous", role: Role! = MEMBER): string!`)
+	assert.Contains(t, sdl, "active: bool! = true\n")
+}
+
+func TestArgDefaultsBadValue(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("search", func(args struct {
+		Limit int64 `graphql:"limit;default=many"`
+	}) string {
+		return ""
+	})
+
+	_, err := schema.Build()
+	if err == nil || !strings.Contains(err.Error(), "field limit has bad default: not an int") {
+		t.Errorf("expected bad default error, got %v", err)
+	}
+}
+
+func TestFieldTimeout(t *testing.T) {
+	type Inner struct{}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("fast", func() string {
+		return "fast"
+	})
+	query.FieldFunc("slow", func() string {
+		time.Sleep(time.Second)
+		return "slow"
+	}, schemabuilder.WithTimeout(10*time.Millisecond))
+	query.FieldFunc("inner", func() Inner {
+		return Inner{}
+	}, schemabuilder.WithTimeout(10*time.Millisecond))
+	inner := schema.Object("Inner", Inner{})
+	inner.FieldFunc("child", func() string {
+		time.Sleep(50 * time.Millisecond)
+		return "child"
+	})
+	builtSchema := schema.MustBuild()
+
+	// The timeout of inner doesn't apply to its child.
+	q := graphql.MustParse(`{ fast inner { child } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"fast":  "fast",
+		"inner": map[string]interface{}{"child": "child"},
+	}, val)
+
+	q = graphql.MustParse(`{ fast slow }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err == nil || err.Error() != "slow: resolver timed out after 10ms" {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the query to give up after the timeout, took %s", elapsed)
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	type Company struct {
+		Name string
+	}
+	type User struct {
+		Id        int64
+		CompanyId int64
+	}
+
+	var mu sync.Mutex
+	var calls int
+	var loaded []int64
+	companies := graphql.NewBatcher(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			loaded = append(loaded, key.(int64))
+			values[i] = &Company{Name: fmt.Sprintf("company%d", key)}
+		}
+		return values, nil
+	})
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func() []*User {
+		var users []*User
+		for i := int64(0); i < 100; i++ {
+			users = append(users, &User{Id: i, CompanyId: i % 10})
+		}
+		return users
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("company", func(ctx context.Context, u *User) (*Company, error) {
+		company, err := companies.Load(ctx, u.CompanyId)
+		if err != nil {
+			return nil, err
+		}
+		return company.(*Company), nil
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ users { id company { name } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(batch.WithBatching(context.Background()), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+
+	users := val.(map[string]interface{})["users"].([]interface{})
+	assert.Len(t, users, 100)
+	assert.Equal(t, map[string]interface{}{"name": "company7"}, users[17].(map[string]interface{})["company"])
+
+	// The loads are combined into a few batches, rather than one per user.
+	assert.Len(t, loaded, 100)
+	if calls >= 10 {
+		t.Errorf("expected loads to be batched, got %d calls", calls)
+	}
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	type User struct {
+		Name     string
+		Nickname string `graphql:"nickname;deprecated=use name"`
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("me", func() User {
+		return User{Name: "alice", Nickname: "al"}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("displayName", func(u User) string {
+		return u.Name
+	}, schemabuilder.Deprecated("use name"))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			me {
+				nickname
+				displayName
+			}
+		}
+		`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"me": map[string]interface{}{"nickname": "al", "displayName": "alice"},
+	}, val)
+
+	assert.Contains(t, builtSchema.SDL(), `displayName: string! @deprecated(reason: "use name")`)
+	assert.Contains(t, builtSchema.SDL(), `nickname: string! @deprecated(reason: "use name")`)
+}
+
 // TestEndToEndAwaitAndCache tests that slow fields get run in parallel and cached.
 //
 // The test verifies that the `slow` field on user, which sleeps for 100ms, gets
@@ -218,231 +500,1434 @@ func TestEnum(t *testing.T) {
 //
 // The test verifies that a `count` sub-field of the `slow` field is cached by
 // invalidating a single `slow` call, and tracking the number of calls to count.
-func TestEndToEndAwaitAndCache(t *testing.T) {
-	users := []*User{
-		{Name: "Alice", Age: 5, resource: reactive.NewResource()},
-		{Name: "Bob", Age: 6, resource: reactive.NewResource()},
-		{Name: "Charlie", Age: 7, resource: reactive.NewResource()},
+func TestAuthorize(t *testing.T) {
+	type User struct {
+		Name  string
+		Email string `graphql:"-"`
+	}
+	type viewerKey struct{}
+
+	isSelf := func(ctx context.Context, source interface{}) error {
+		if viewer, _ := ctx.Value(viewerKey{}).(string); viewer != source.(*User).Name {
+			return graphql.NewClientError("not authorized")
+		}
+		return nil
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func() []*User {
+		return []*User{{Name: "alice", Email: "alice@example.com"}, {Name: "bob", Email: "bob@example.com"}}
+	})
+	user := schema.Object("User", User{})
+	user.Key("name")
+	user.FieldFunc("email", func(u *User) string {
+		return u.Email
+	}, schemabuilder.Authorize(isSelf))
+	user.FieldFunc("friends", func(u *User) []*User {
+		return []*User{u}
+	}, schemabuilder.Paginated, schemabuilder.Authorize(isSelf))
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ users { name email friends { totalCount } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{PartialResults: true}
+	res, err := e.ExecuteResult(context.WithValue(context.Background(), viewerKey{}, "alice"), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"users": [
+		{"__key": "alice", "name": "alice", "email": "alice@example.com", "friends": {"totalCount": 1}},
+		{"__key": "bob", "name": "bob", "email": null, "friends": null}
+	]}`), internal.AsJSON(res.Data))
+
+	errs := res.Errors
+	sort.Slice(errs, func(i, j int) bool { return fmt.Sprint(errs[i].Path) < fmt.Sprint(errs[j].Path) })
+	assert.Equal(t, internal.ParseJSON(`[
+		{"message": "not authorized", "path": ["users", 1, "email"]},
+		{"message": "not authorized", "path": ["users", 1, "friends"]}
+	]`), internal.AsJSON(errs))
+}
+
+func TestBestEffort(t *testing.T) {
+	type Product struct {
+		Name string
 	}
 
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("product", func() *Product {
+		return &Product{Name: "lamp"}
+	})
+	product := schema.Object("Product", Product{})
+	product.FieldFunc("rating", func(p *Product) (float64, error) {
+		return 0, errors.New("ratings unavailable")
+	}, schemabuilder.BestEffort())
+	product.FieldFunc("reviews", func(ctx context.Context, p *Product) ([]string, error) {
+		return nil, errors.New("reviews unavailable")
+	}, schemabuilder.BestEffort())
+	product.FieldFunc("price", func(p *Product) (int64, error) {
+		return 0, errors.New("prices unavailable")
+	})
+	builtSchema := schema.MustBuild()
+
+	// Best effort fields are nullable.
+	productType := builtSchema.Query.(*graphql.Object).Fields["product"].Type.(*graphql.Object)
+	assert.Equal(t, "float64", productType.Fields["rating"].Type.String())
+
 	var mu sync.Mutex
-	calls := 0
+	var logged []string
+	e := graphql.Executor{
+		OnBestEffortError: func(ctx context.Context, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, err.Error())
+		},
+	}
+	parse := func(query string) *graphql.Query {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	execute := func(query string) (interface{}, error) {
+		return e.Execute(context.Background(), builtSchema.Query, nil, parse(query))
+	}
+
+	res, err := e.ExecuteResult(context.Background(), builtSchema.Query, nil, parse(`{ product { name rating reviews } }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"product": {"name": "lamp", "rating": null, "reviews": null}}`), internal.AsJSON(res.Data))
+	assert.Empty(t, res.Errors)
+	sort.Strings(logged)
+	assert.Equal(t, []string{"ratings unavailable", "reviews unavailable"}, logged)
+
+	// Other fields still fail the query.
+	if _, err := execute(`{ product { rating price } }`); err == nil || !strings.Contains(err.Error(), "prices unavailable") {
+		t.Errorf("expected price error, got %v", err)
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	call := func(field string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[field]++
+	}
 
 	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func() []*User {
+		return []*User{{Name: "alice"}, {Name: "bob"}}
+	})
+	user := schema.Object("User", User{})
+	user.Key("name")
+	user.FieldFunc("score", func(u *User, args struct{ Scale int64 }) int64 {
+		call("score")
+		return int64(len(u.Name)) * args.Scale
+	}, schemabuilder.Memoize())
+	user.FieldFunc("expensiveScore", func(ctx context.Context, u *User) int64 {
+		call("expensiveScore")
+		return int64(len(u.Name))
+	}, schemabuilder.Memoize())
+	builtSchema := schema.MustBuild()
 
+	q := graphql.MustParse(`
+		{ users { ...A ...B } }
+		fragment A on User { a: score(scale: 1) ea: expensiveScore }
+		fragment B on User { b: score(scale: 1) c: score(scale: 2) eb: expensiveScore }
+	`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"users": [
+		{"__key": "alice", "a": 5, "b": 5, "c": 10, "ea": 5, "eb": 5},
+		{"__key": "bob", "a": 3, "b": 3, "c": 6, "ea": 3, "eb": 3}
+	]}`), internal.AsJSON(val))
+
+	// The fields are resolved once per user and args.
+	assert.Equal(t, map[string]int{"score": 4, "expensiveScore": 2}, calls)
+
+	// The memoized values are cleared between executions.
+	_, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"score": 8, "expensiveScore": 4}, calls)
+}
+
+func TestCodedErrors(t *testing.T) {
+	schema := schemabuilder.NewSchema()
 	query := schema.Query()
-	query.FieldFunc("users", func(ctx context.Context) []*User {
-		return users
+	query.FieldFunc("user", func() (string, error) {
+		return "", graphql.NewCodedError("NOT_FOUND", "user not found")
 	})
+	query.FieldFunc("quota", func() (string, error) {
+		return "", graphql.WithExtensions(graphql.NewClientError("quota exceeded"), map[string]interface{}{"code": "QUOTA", "retryAfter": 60})
+	})
+	query.FieldFunc("database", func() (string, error) {
+		return "", graphql.WithExtensions(errors.New("connection refused"), map[string]interface{}{"host": "db1"})
+	})
+	builtSchema := schema.MustBuild()
 
-	_ = schema.Mutation()
+	q := graphql.MustParse(`{ user quota database }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
 
-	user := schema.Object("User", User{})
-	user.FieldFunc("slow", func(ctx context.Context, u *User) *Slow {
-		reactive.AddDependency(ctx, u.resource, nil)
-		time.Sleep(100 * time.Millisecond)
-		return new(Slow)
+	e := graphql.Executor{PartialResults: true}
+	res, err := e.ExecuteResult(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+
+	errs := res.Errors
+	sort.Slice(errs, func(i, j int) bool { return fmt.Sprint(errs[i].Path) < fmt.Sprint(errs[j].Path) })
+	assert.Equal(t, internal.ParseJSON(`[
+		{"message": "Internal server error", "path": ["database"]},
+		{"message": "quota exceeded", "path": ["quota"], "extensions": {"code": "QUOTA", "retryAfter": 60}},
+		{"message": "user not found", "path": ["user"], "extensions": {"code": "NOT_FOUND"}}
+	]`), internal.AsJSON(errs))
+
+	// Coded errors fail queries with their message.
+	q = graphql.MustParse(`{ user }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	strict := graphql.Executor{}
+	_, err = strict.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err == nil || err.Error() != "user not found" {
+		t.Errorf("expected coded error, got %v", err)
+	}
+	if extended, ok := err.(graphql.ExtendedError); !ok || extended.Extensions()["code"] != "NOT_FOUND" {
+		t.Errorf("expected code, got %v", err)
+	}
+}
+
+type emailArgs struct {
+	Email string
+}
+
+func (a *emailArgs) Validate() error {
+	if !strings.Contains(a.Email, "@") {
+		return fmt.Errorf("email %q is not valid", a.Email)
+	}
+	return nil
+}
+
+type pageSizeArgs struct {
+	schemabuilder.PaginationArgs
+	PageSize int64
+}
+
+func (a pageSizeArgs) Validate() error {
+	if a.PageSize < 1 || a.PageSize > 100 {
+		return errors.New("pageSize must be between 1 and 100")
+	}
+	return nil
+}
+
+func TestArgsValidate(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+
+	var called bool
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("invite", func(args emailArgs) bool {
+		called = true
+		return true
 	})
+	query.FieldFunc("users", func(args pageSizeArgs) ([]*User, schemabuilder.PaginationInfo, error) {
+		called = true
+		return nil, schemabuilder.PaginationInfo{TotalCount: func() int64 { return 0 }}, nil
+	}, schemabuilder.Paginated)
+	user := schema.Object("User", User{})
+	user.Key("id")
+	builtSchema := schema.MustBuild()
+
+	for _, c := range []struct {
+		query string
+		err   string
+	}{
+		{`{ invite(email: "a@example.com") }`, ""},
+		{`{ invite(email: "a") }`, `error parsing args for "invite": email "a" is not valid`},
+		{`{ users(pageSize: 10) { totalCount } }`, ""},
+		{`{ users(pageSize: 1000) { totalCount } }`, `error parsing args for "users": pageSize must be between 1 and 100`},
+	} {
+		called = false
+		q := graphql.MustParse(c.query, nil)
+		err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet)
+		if c.err == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected %q, got %v", c.err, err)
+		}
+
+		e := graphql.Executor{}
+		if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err == nil {
+			t.Error("expected execute to fail")
+		}
+		if called {
+			t.Errorf("expected resolver of %s not to be called", c.query)
+		}
+	}
+}
+
+func TestTypename(t *testing.T) {
+	type Company struct {
+		Name string
+	}
+	type User struct {
+		Id      int64
+		Company *Company
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return &User{Id: 1, Company: &Company{Name: "acme"}}
+	})
+	query.FieldFunc("users", func() []*User {
+		return []*User{{Id: 1}}
+	}, schemabuilder.Paginated)
+	user := schema.Object("User", User{})
+	user.Key("id")
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		__typename
+		me { __typename company { __typename name } }
+		users { __typename edges { __typename node { __typename id } } }
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, internal.ParseJSON(`{
+		"__typename": "Query",
+		"me": {"__key": 1, "__typename": "User", "company": {"__typename": "Company", "name": "acme"}},
+		"users": {
+			"__typename": "UserConnection",
+			"edges": [{"__typename": "UserEdge", "node": {"__key": 1, "__typename": "User", "id": 1}}]
+		}
+	}`), internal.AsJSON(val))
+}
+
+func TestSkipInclude(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	var mu sync.Mutex
+	resolved := 0
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return &User{Name: "alice"}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("friends", func() []*User {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved++
+		return []*User{{Name: "bob"}}
+	})
+	builtSchema := schema.MustBuild()
+
+	source := `query Test($flag: Boolean) {
+		me {
+			name @include(if: $flag)
+			friends @skip(if: $flag) { name }
+			... on User @skip(if: true) { friends { name } }
+			...UserFriends @include(if: false)
+		}
+	}
+	fragment UserFriends on User { friends { name } }`
+
+	run := func(flag bool) interface{} {
+		q := graphql.MustParse(source, map[string]interface{}{"flag": flag})
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(val)
+	}
+
+	assert.Equal(t, internal.ParseJSON(`{"me": {"name": "alice"}}`), run(true))
+	assert.Equal(t, 0, resolved)
+
+	assert.Equal(t, internal.ParseJSON(`{"me": {"friends": [{"name": "bob"}]}}`), run(false))
+	assert.Equal(t, 1, resolved)
+
+	if _, err := graphql.Parse(`{ me @skip(if: "yes") { name } }`, nil); err == nil || err.Error() != "@skip if argument must be a boolean" {
+		t.Errorf("expected boolean error, got %v", err)
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return &User{Name: "alice"}
+	})
+	schema.Object("User", User{})
+	schema.RegisterDirective("uppercase", []graphql.DirectiveLocation{graphql.DirectiveLocationField},
+		func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error) {
+			s := strings.ToUpper(value.(string))
+			if suffix, ok := args["suffix"].(string); ok {
+				s += suffix
+			}
+			return s, nil
+		})
+	schema.RegisterDirective("fragmentOnly", []graphql.DirectiveLocation{"FRAGMENT_SPREAD"},
+		func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error) {
+			return value, nil
+		})
+	builtSchema := schema.MustBuild()
+
+	run := func(source string) (interface{}, error) {
+		q := graphql.MustParse(source, map[string]interface{}{"suffix": "!"})
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{Directives: builtSchema.Directives}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(val), err
+	}
+
+	val, err := run(`query Test($suffix: String) {
+		me { name loud: name @uppercase shout: name @uppercase(suffix: $suffix) }
+	}`)
+	assert.Nil(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"me": {"name": "alice", "loud": "ALICE", "shout": "ALICE!"}}`), val)
+
+	if _, err := run(`{ me { name @lowercase } }`); err == nil || err.Error() != "unknown directive @lowercase" {
+		t.Errorf("expected unknown directive error, got %v", err)
+	}
+	if _, err := run(`{ me { name @fragmentOnly } }`); err == nil || err.Error() != "directive @fragmentOnly can't be used on fields" {
+		t.Errorf("expected location error, got %v", err)
+	}
+}
+
+func TestMutationOrder(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	mutation := schema.Mutation()
+	mutation.FieldFunc("append", func(ctx context.Context, args struct {
+		Value string
+		Delay int64
+	}) []string {
+		time.Sleep(time.Duration(args.Delay) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, args.Value)
+		return append([]string(nil), log...)
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`mutation {
+		first: append(value: "first", delay: 20)
+		second: append(value: "second", delay: 10)
+		third: append(value: "third", delay: 0)
+	}`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Mutation, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Mutation, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, log)
+	assert.Equal(t, internal.ParseJSON(`{
+		"first": ["first"],
+		"second": ["first", "second"],
+		"third": ["first", "second", "third"]
+	}`), internal.AsJSON(val))
+}
+
+func TestEndToEndAwaitAndCache(t *testing.T) {
+	users := []*User{
+		{Name: "Alice", Age: 5, resource: reactive.NewResource()},
+		{Name: "Bob", Age: 6, resource: reactive.NewResource()},
+		{Name: "Charlie", Age: 7, resource: reactive.NewResource()},
+	}
+
+	var mu sync.Mutex
+	calls := 0
+
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("users", func(ctx context.Context) []*User {
+		return users
+	})
+
+	_ = schema.Mutation()
+
+	user := schema.Object("User", User{})
+	user.FieldFunc("slow", func(ctx context.Context, u *User) *Slow {
+		reactive.AddDependency(ctx, u.resource, nil)
+		time.Sleep(100 * time.Millisecond)
+		return new(Slow)
+	})
+
+	slow := schema.Object("Slow", Slow{})
+	slow.FieldFunc("count", func() bool {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return true
+	})
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			users {
+				name
+				slow { count }
+            }
+        }`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	results := make(chan interface{})
+
+	start := time.Now()
+	rerunner := reactive.NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
+		e := graphql.Executor{}
+		result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+		if err != nil {
+			t.Error(err)
+		}
+
+		results <- internal.AsJSON(result)
+		return nil, nil
+	}, 0)
+	defer rerunner.Stop()
+
+	result := <-results
+	duration := time.Since(start)
+	if duration > 150*time.Millisecond {
+		t.Errorf("did not execute in parallel; duration %v > 150ms", duration)
+	}
+	if !reflect.DeepEqual(result, internal.ParseJSON(`
+		{"users": [
+			{"name": "Alice", "slow": {"count": true}},
+			{"name": "Bob", "slow": {"count": true}},
+			{"name": "Charlie", "slow": {"count": true}}
+        ]}`)) {
+		t.Error("bad value")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls to slow, got %d", calls)
+	}
+
+	start = time.Now()
+	users[0].resource.Strobe()
+	result = <-results
+	duration = time.Since(start)
+	if duration > 150*time.Millisecond {
+		t.Errorf("did not execute in parallel; duration %v > 150ms", duration)
+	}
+	if !reflect.DeepEqual(result, internal.ParseJSON(`
+		{"users": [
+			{"name": "Alice", "slow": {"count": true}},
+			{"name": "Bob", "slow": {"count": true}},
+			{"name": "Charlie", "slow": {"count": true}}
+        ]}`)) {
+		t.Error("bad value")
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 total calls to slow, got %d", calls)
+	}
+}
+
+func verifyArgumentOption(t *testing.T, query graphql.Type, queryString string, variables map[string]interface{}, expectedResult string) {
+	q := graphql.MustParse(queryString, variables)
+
+	if err := graphql.PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), query, nil, q)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(internal.AsJSON(result), internal.ParseJSON(expectedResult)) {
+		t.Error(internal.AsJSON(result))
+	}
+}
+
+// TestArgumentOptionality tests that optional arguments can be omitted from
+// query variables and that mandatory arguments must be included.
+func TestArgumentOptionality(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+
+	query.FieldFunc("optional", func(args struct{ X *int64 }) int64 {
+		if args.X != nil {
+			return *args.X
+		}
+		return -1
+	})
+
+	query.FieldFunc("mandatory", func(args struct{ X int64 }) int64 {
+		return args.X
+	})
+
+	_ = schema.Mutation()
+	builtSchema := schema.MustBuild()
+	emptyVariables := map[string]interface{}{}
+	filledVariables := map[string]interface{}{
+		"testArg": float64(5),
+	}
+
+	// An optional argument that is passed in returns successfully.
+	verifyArgumentOption(t, builtSchema.Query, `
+		query getOptional($testArg: int64) {
+			optional(x: $testArg)
+		}`, filledVariables, `{"optional": 5}`)
+
+	// An optional argument that is omitted returns successfully.
+	verifyArgumentOption(t, builtSchema.Query, `
+			query getOptional($testArg: int64) {
+				optional(x: $testArg)
+			}`, emptyVariables, `{"optional": -1}`)
+
+	// A mandatory argument that is passed in returns successfully.
+	verifyArgumentOption(t, builtSchema.Query, `
+		query getMandatory($testArg: int64!) {
+			mandatory(x: $testArg)
+		}`, filledVariables, `{"mandatory": 5}`)
+}
+
+// TestConcurrencyLimiterDeadlock tests that the executor does not cause a
+// concurrency limit deadlock by holding on to tokens after a resolver finishes
+// running.
+func TestConcurrencyLimiterDeadlock(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("users", func(ctx context.Context) []*User {
+		var users []*User
+		for i := 0; i < 200; i++ {
+			users = append(users, &User{})
+		}
+		return users
+	})
+
+	_ = schema.Mutation()
+
+	user := schema.Object("User", User{})
+	user.FieldFunc("slow", func(ctx context.Context, u *User) *Slow {
+		time.Sleep(10 * time.Millisecond)
+		return &Slow{}
+	})
+
+	slow := schema.Object("Slow", Slow{})
+	slow.FieldFunc("count", func() bool {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return true
+	})
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			users {
+				one: slow { count }
+				two: slow { count }
+            }
+        }`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	rerunner := reactive.NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
+		defer wg.Done()
+		e := graphql.Executor{}
+		ctx = concurrencylimiter.With(ctx, 100)
+
+		_, err := e.Execute(ctx, builtSchema.Query, nil, q)
+		if err != nil {
+			t.Error(err)
+		}
+
+		assert.Equal(t, 2*200, calls)
+		return nil, nil
+	}, 0)
+
+	wg.Wait()
+	defer rerunner.Stop()
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+
+	var mu sync.Mutex
+	running, peak, calls := 0, 0, 0
+	track := func() {
+		mu.Lock()
+		running++
+		calls++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func(ctx context.Context) []*User {
+		track()
+		var users []*User
+		for i := int64(0); i < 20; i++ {
+			users = append(users, &User{Id: i})
+		}
+		return users
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("friends", func(ctx context.Context, u *User) []*User {
+		track()
+		return []*User{{Id: u.Id + 100}, {Id: u.Id + 200}}
+	})
+	user.FieldFunc("score", func(ctx context.Context, u *User) int64 {
+		track()
+		return u.Id
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ users { score friends { score friends { score } } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{MaxConcurrency: 3}
+	if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err != nil {
+		t.Fatal(err)
+	}
+
+	// users, and 20 users with 2 friends with 2 friends, each with score and friends resolved.
+	assert.Equal(t, 1+20*2+40*2+80, calls)
+	assert.True(t, peak <= 3, "peak concurrency %d exceeds 3", peak)
+	assert.True(t, peak > 1, "expected fields to be resolved concurrently")
+}
+
+func TestIDType(t *testing.T) {
+	type User struct {
+		Id   int64 `graphql:"id;type=ID"`
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(args struct {
+		Id int64 `graphql:"id;type=ID"`
+	}) *User {
+		return &User{Id: args.Id, Name: fmt.Sprint("user ", args.Id)}
+	})
+	builtSchema := schema.MustBuild()
+
+	sdl := builtSchema.SDL()
+	assert.Contains(t, sdl, "  id: ID!\n")
+	assert.Contains(t, sdl, "user(id: ID!): User")
+
+	for _, query := range []string{`{ user(id: "5") { id name } }`, `{ user(id: 5) { id name } }`} {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, internal.ParseJSON(`{"user": {"id": "5", "name": "user 5"}}`), internal.AsJSON(val))
+	}
+
+	q := graphql.MustParse(`{ user(id: "five") { id } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil || !strings.Contains(err.Error(), "ID five is not an integer") {
+		t.Errorf("expected bad ID error, got %v", err)
+	}
+
+	type Pet struct {
+		Id float64 `graphql:"id;type=ID"`
+	}
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("pet", func() *Pet {
+		return nil
+	})
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "field id of type float64 can't be an ID") {
+		t.Errorf("expected bad ID type error, got %v", err)
+	}
+}
+
+func TestListArgs(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("users", func(args struct {
+		Ids    []int64 `graphql:"ids;type=ID"`
+		Groups [][]*int64
+	}) []string {
+		names := []string{}
+		for _, id := range args.Ids {
+			names = append(names, fmt.Sprint("user ", id))
+		}
+		for _, group := range args.Groups {
+			for _, id := range group {
+				if id == nil {
+					names = append(names, "nobody")
+				} else {
+					names = append(names, fmt.Sprint("user ", *id))
+				}
+			}
+		}
+		return names
+	})
+	builtSchema := schema.MustBuild()
+
+	assert.Contains(t, builtSchema.SDL(), "users(groups: [[int64]!]!, ids: [ID!]!): [string!]!")
+
+	execute := func(query string, vars map[string]interface{}) (interface{}, error) {
+		q, err := graphql.Parse(query, vars)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(`{ users(ids: [1, 2, "3"], groups: []) }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"users": ["user 1", "user 2", "user 3"]}`), internal.AsJSON(val))
+
+	val, err = execute(`query Q($id: int64) { users(ids: [], groups: [[4, $id], []]) }`, map[string]interface{}{"id": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"users": ["user 4", "nobody"]}`), internal.AsJSON(val))
+
+	_, err = execute(`query Q($id: ID) { users(ids: [1, $id], groups: []) }`, map[string]interface{}{"id": nil})
+	if err == nil || !strings.Contains(err.Error(), "ids: item 1: should not be null") {
+		t.Errorf("expected null item error, got %v", err)
+	}
+	_, err = execute(`{ users(ids: [], groups: [[1], [2, "three"]]) }`, nil)
+	if err == nil || !strings.Contains(err.Error(), "groups: item 1: item 1: not a number") {
+		t.Errorf("expected bad item error, got %v", err)
+	}
+}
+
+func TestListArgCoercion(t *testing.T) {
+	var tags []string
+	var groups [][]int64
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("tagged", func(args struct {
+		Tags   []string
+		Groups [][]int64
+	}) bool {
+		tags, groups = args.Tags, args.Groups
+		return true
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ tagged(tags: "a", groups: 1) }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err != nil {
+		t.Fatal(err)
+	}
+	// Single values are coerced into lists of one item, at every level of nested lists.
+	assert.Equal(t, []string{"a"}, tags)
+	assert.Equal(t, [][]int64{{1}}, groups)
+}
+
+func TestLenientInputCoercion(t *testing.T) {
+	type Args struct {
+		Count  int64
+		Ratio  float64
+		Active bool
+	}
+	build := func(opts ...schemabuilder.SchemaOption) (*graphql.Schema, *Args) {
+		var got Args
+		schema := schemabuilder.NewSchema(opts...)
+		query := schema.Query()
+		query.FieldFunc("filter", func(args Args) bool {
+			got = args
+			return true
+		})
+		return schema.MustBuild(), &got
+	}
+	execute := func(schema *graphql.Schema, query string) error {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+			return err
+		}
+		e := graphql.Executor{}
+		_, err := e.Execute(context.Background(), schema.Query, nil, q)
+		return err
+	}
+
+	// By default, strings aren't numbers and numbers aren't bools.
+	strict, _ := build()
+	err := execute(strict, `{ filter(count: "5", ratio: 1.5, active: true) }`)
+	if err == nil || !strings.Contains(err.Error(), `not a number, got string "5"`) {
+		t.Errorf("expected error parsing string as number, got %v", err)
+	}
+	err = execute(strict, `{ filter(count: 5, ratio: 1.5, active: 1) }`)
+	if err == nil || !strings.Contains(err.Error(), "not a bool, got number 1") {
+		t.Errorf("expected error parsing number as bool, got %v", err)
+	}
+
+	lenient, got := build(schemabuilder.WithLenientInputCoercion())
+	if err := execute(lenient, `{ filter(count: "5", ratio: "1.5", active: 1) }`); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Args{Count: 5, Ratio: 1.5, Active: true}, *got)
+	if err := execute(lenient, `{ filter(count: 5, ratio: 1.5, active: 0) }`); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Args{Count: 5, Ratio: 1.5, Active: false}, *got)
+
+	// Values that aren't numbers or 0 and 1 are still rejected.
+	err = execute(lenient, `{ filter(count: "five", ratio: 1.5, active: true) }`)
+	if err == nil || !strings.Contains(err.Error(), `not a number, got string "five"`) {
+		t.Errorf("expected error parsing non-numeric string, got %v", err)
+	}
+	err = execute(lenient, `{ filter(count: 5, ratio: 1.5, active: 2) }`)
+	if err == nil || !strings.Contains(err.Error(), "not a bool, got number 2") {
+		t.Errorf("expected error parsing 2 as bool, got %v", err)
+	}
+}
+
+func TestSelectionSetHelpers(t *testing.T) {
+	type Address struct {
+		City string
+		Zip  string
+	}
+	type User struct {
+		Name    string
+		Email   string
+		Age     int64
+		Address Address
+	}
 
-	slow := schema.Object("Slow", Slow{})
-	slow.FieldFunc("count", func() bool {
-		mu.Lock()
-		calls++
-		mu.Unlock()
-		return true
+	var hasEmail, hasAge bool
+	var fieldNames, addressFields []string
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(selectionSet *graphql.SelectionSet) User {
+		hasEmail, hasAge = selectionSet.HasField("email"), selectionSet.HasField("age")
+		fieldNames = selectionSet.FieldNames()
+		addressFields = selectionSet.SubSelection("address").FieldNames()
+		return User{}
 	})
-
+	schema.Object("User", User{})
 	builtSchema := schema.MustBuild()
 
 	q := graphql.MustParse(`
 		{
-			users {
+			user {
+				__typename
 				name
-				slow { count }
-            }
-        }`, nil)
-
+				contact: email
+				address { city }
+				... UserFragment
+			}
+		}
+		fragment UserFragment on User {
+			email
+			... on User { address { zip } }
+		}
+	`, nil)
 	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err != nil {
+		t.Fatal(err)
 	}
 
-	results := make(chan interface{})
-
-	start := time.Now()
-	rerunner := reactive.NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
-		e := graphql.Executor{}
-		result, err := e.Execute(ctx, builtSchema.Query, nil, q)
-		if err != nil {
-			t.Error(err)
-		}
+	assert.True(t, hasEmail)
+	assert.False(t, hasAge)
+	assert.Equal(t, []string{"name", "email", "address"}, fieldNames)
+	assert.Equal(t, []string{"city", "zip"}, addressFields)
 
-		results <- internal.AsJSON(result)
-		return nil, nil
-	}, 0)
-	defer rerunner.Stop()
+	var selectionSet *graphql.SelectionSet
+	assert.False(t, selectionSet.HasField("name"))
+	assert.Nil(t, selectionSet.SubSelection("address"))
+}
 
-	result := <-results
-	duration := time.Since(start)
-	if duration > 150*time.Millisecond {
-		t.Errorf("did not execute in parallel; duration %v > 150ms", duration)
+func TestNestedInputObjects(t *testing.T) {
+	type StringFilter struct {
+		Eq *string
 	}
-	if !reflect.DeepEqual(result, internal.ParseJSON(`
-		{"users": [
-			{"name": "Alice", "slow": {"count": true}},
-			{"name": "Bob", "slow": {"count": true}},
-			{"name": "Charlie", "slow": {"count": true}}
-        ]}`)) {
-		t.Error("bad value")
+	type IntFilter struct {
+		Gt *int64
+		Lt *int64
 	}
-	if calls != 3 {
-		t.Errorf("expected 3 calls to slow, got %d", calls)
+	type UserFilter struct {
+		Name *StringFilter
+		Age  *IntFilter
+		Or   []*UserFilter
 	}
 
-	start = time.Now()
-	users[0].resource.Strobe()
-	result = <-results
-	duration = time.Since(start)
-	if duration > 150*time.Millisecond {
-		t.Errorf("did not execute in parallel; duration %v > 150ms", duration)
+	var filter *UserFilter
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("users", func(args struct{ Filter *UserFilter }) []string {
+		filter = args.Filter
+		return []string{}
+	})
+	builtSchema := schema.MustBuild()
+
+	sdl := builtSchema.SDL()
+	assert.Contains(t, sdl, "input UserFilter_InputObject {\n  age: IntFilter_InputObject\n  name: StringFilter_InputObject\n  or: [UserFilter_InputObject]!\n}")
+
+	execute := func(query string) error {
+		filter = nil
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return err
+		}
+		e := graphql.Executor{}
+		_, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return err
 	}
-	if !reflect.DeepEqual(result, internal.ParseJSON(`
-		{"users": [
-			{"name": "Alice", "slow": {"count": true}},
-			{"name": "Bob", "slow": {"count": true}},
-			{"name": "Charlie", "slow": {"count": true}}
-        ]}`)) {
-		t.Error("bad value")
+
+	if err := execute(`{ users(filter: {name: {eq: "x"}, age: {gt: 18}, or: [{age: {lt: 5}, or: []}]}) }`); err != nil {
+		t.Fatal(err)
 	}
-	if calls != 4 {
-		t.Errorf("expected 4 total calls to slow, got %d", calls)
+	name, gt, lt := "x", int64(18), int64(5)
+	assert.Equal(t, &UserFilter{
+		Name: &StringFilter{Eq: &name},
+		Age:  &IntFilter{Gt: &gt},
+		Or:   []*UserFilter{{Age: &IntFilter{Lt: &lt}, Or: []*UserFilter{}}},
+	}, filter)
+
+	if err := execute(`{ users(filter: {age: {gt: "old"}, or: []}) }`); err == nil || !strings.Contains(err.Error(), "filter: age: gt: not a number") {
+		t.Errorf("expected bad nested arg error, got %v", err)
 	}
 }
 
-func verifyArgumentOption(t *testing.T, query graphql.Type, queryString string, variables map[string]interface{}, expectedResult string) {
-	q := graphql.MustParse(queryString, variables)
+type fieldUsageRecorder struct {
+	usage []map[string]int
+}
 
-	if err := graphql.PrepareQuery(query, q.SelectionSet); err != nil {
-		t.Error(err)
+func (r *fieldUsageRecorder) RecordFieldUsage(ctx context.Context, usage map[string]int) {
+	r.usage = append(r.usage, usage)
+}
+
+func TestFieldUsage(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("users", func() []*User {
+		return []*User{{Id: 1, Name: "alice"}, {Id: 2, Name: "bob"}}
+	}, schemabuilder.Paginated)
+	query.FieldFunc("me", func() *User {
+		return &User{Id: 1, Name: "alice"}
+	})
+	user := schema.Object("User", User{})
+	user.Key("id")
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		users { totalCount edges { node { id ...UserFields } } }
+		me { name }
+		other: me { id }
+	}
+	fragment UserFields on User { name }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
 	}
 
+	recorder := &fieldUsageRecorder{}
+	e := graphql.Executor{FieldUsage: recorder}
+	if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]int{{
+		"users":                 1,
+		"users.totalCount":      1,
+		"users.edges":           1,
+		"users.edges.node":      2,
+		"users.edges.node.id":   2,
+		"users.edges.node.name": 2,
+		"me":                    2,
+		"me.name":               1,
+		"me.id":                 1,
+	}}, recorder.usage)
+}
+
+func TestTypedSource(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	type Pet struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func() *User {
+		return &User{Id: 1, Name: "alice"}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("greeting", func(ctx context.Context, u *User) string {
+		return "hello " + u.Name
+	})
+	user.FieldFunc("shout", func(u User, args struct{ Suffix string }) string {
+		return strings.ToUpper(u.Name) + args.Suffix
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ user { greeting shout(suffix: "!") } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
 	e := graphql.Executor{}
-	result, err := e.Execute(context.Background(), query, nil, q)
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	assert.Equal(t, internal.ParseJSON(`{"user": {"greeting": "hello alice", "shout": "ALICE!"}}`), internal.AsJSON(val))
 
-	if !reflect.DeepEqual(internal.AsJSON(result), internal.ParseJSON(expectedResult)) {
-		t.Error(internal.AsJSON(result))
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func() *User { return nil })
+	schema.Object("User", User{}).FieldFunc("pet", func(ctx context.Context, p *Pet) string {
+		return p.Name
+	})
+	if _, err := schema.Build(); err == nil || !strings.Contains(err.Error(), "source should be graphql_test.User or *graphql_test.User, not *graphql_test.Pet") {
+		t.Errorf("expected bad source error, got %v", err)
 	}
 }
 
-// TestArgumentOptionality tests that optional arguments can be omitted from
-// query variables and that mandatory arguments must be included.
-func TestArgumentOptionality(t *testing.T) {
+type PromotedUser struct {
+	Id   int64
+	Name string
+}
+
+type PromotedProfile struct {
+	Bio string
+}
+
+type PromotedAdmin struct {
+	PromotedUser
+	*PromotedProfile
+	Name  string
+	Level int64
+}
+
+func TestEmbeddedFieldPromotion(t *testing.T) {
 	schema := schemabuilder.NewSchema()
 	query := schema.Query()
-
-	query.FieldFunc("optional", func(args struct{ X *int64 }) int64 {
-		if args.X != nil {
-			return *args.X
+	query.FieldFunc("admins", func() []PromotedAdmin {
+		return []PromotedAdmin{
+			{PromotedUser: PromotedUser{Id: 1, Name: "alice"}, PromotedProfile: &PromotedProfile{Bio: "hi"}, Name: "Alice", Level: 2},
+			{PromotedUser: PromotedUser{Id: 2, Name: "bob"}, Level: 1},
 		}
-		return -1
 	})
+	builtSchema := schema.MustBuild()
 
-	query.FieldFunc("mandatory", func(args struct{ X int64 }) int64 {
-		return args.X
-	})
+	q := graphql.MustParse(`{ admins { id name level bio } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The outer name wins over the promoted one, and fields of nil embedded pointers are null.
+	assert.Equal(t, internal.ParseJSON(`{"admins": [
+		{"id": 1, "name": "Alice", "level": 2, "bio": "hi"},
+		{"id": 2, "name": "", "level": 1, "bio": null}
+	]}`), internal.AsJSON(val))
+
+	admin := builtSchema.Query.(*graphql.Object).Fields["admins"].Type.(*graphql.NonNull).Type.(*graphql.List).Type.(*graphql.NonNull).Type.(*graphql.Object)
+	if _, ok := admin.Fields["promotedUser"]; ok {
+		t.Error("expected embedded struct not to be a field")
+	}
+	assert.Equal(t, "int64!", admin.Fields["id"].Type.String())
+	assert.Equal(t, "string", admin.Fields["bio"].Type.String())
+}
 
-	_ = schema.Mutation()
-	builtSchema := schema.MustBuild()
-	emptyVariables := map[string]interface{}{}
-	filledVariables := map[string]interface{}{
-		"testArg": float64(5),
+func TestNilLists(t *testing.T) {
+	type Lists struct {
+		NilNullable   []int64 `graphql:"nilNullable;nullable"`
+		EmptyNullable []int64 `graphql:"emptyNullable;nullable"`
+		NilNonNull    []int64
+		EmptyNonNull  []int64
 	}
 
-	// An optional argument that is passed in returns successfully.
-	verifyArgumentOption(t, builtSchema.Query, `
-		query getOptional($testArg: int64) {
-			optional(x: $testArg)
-		}`, filledVariables, `{"optional": 5}`)
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("lists", func() Lists {
+		return Lists{EmptyNullable: []int64{}, EmptyNonNull: []int64{}}
+	})
+	builtSchema := schema.MustBuild()
 
-	// An optional argument that is omitted returns successfully.
-	verifyArgumentOption(t, builtSchema.Query, `
-			query getOptional($testArg: int64) {
-				optional(x: $testArg)
-			}`, emptyVariables, `{"optional": -1}`)
+	lists := builtSchema.Query.(*graphql.Object).Fields["lists"].Type.(*graphql.NonNull).Type.(*graphql.Object)
+	assert.Equal(t, "[int64!]", lists.Fields["nilNullable"].Type.String())
+	assert.Equal(t, "[int64!]!", lists.Fields["nilNonNull"].Type.String())
 
-	// A mandatory argument that is passed in returns successfully.
-	verifyArgumentOption(t, builtSchema.Query, `
-		query getMandatory($testArg: int64!) {
-			mandatory(x: $testArg)
-		}`, filledVariables, `{"mandatory": 5}`)
+	q := graphql.MustParse(`{ lists { nilNullable emptyNullable nilNonNull emptyNonNull } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"lists": {"nilNullable": null, "emptyNullable": [], "nilNonNull": [], "emptyNonNull": []}}`), internal.AsJSON(val))
+
+	e = graphql.Executor{EmptyNilLists: true}
+	val, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{"lists": {"nilNullable": [], "emptyNullable": [], "nilNonNull": [], "emptyNonNull": []}}`), internal.AsJSON(val))
 }
 
-// TestConcurrencyLimiterDeadlock tests that the executor does not cause a
-// concurrency limit deadlock by holding on to tokens after a resolver finishes
-// running.
-func TestConcurrencyLimiterDeadlock(t *testing.T) {
-	var mu sync.Mutex
-	calls := 0
+func TestBudget(t *testing.T) {
+	type Item struct {
+		Id int64
+	}
 
+	var called []int64
 	schema := schemabuilder.NewSchema()
-
 	query := schema.Query()
-	query.FieldFunc("users", func(ctx context.Context) []*User {
-		var users []*User
-		for i := 0; i < 200; i++ {
-			users = append(users, &User{})
+	query.FieldFunc("items", func(ctx context.Context) ([]Item, error) {
+		if err := graphql.ChargeBudget(ctx, 1); err != nil {
+			return nil, err
 		}
-		return users
+		return []Item{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}, nil
 	})
+	item := schema.Object("Item", Item{})
+	item.FieldFunc("detail", func(ctx context.Context, i Item) (*string, error) {
+		called = append(called, i.Id)
+		if err := graphql.ChargeBudget(ctx, 3); err != nil {
+			return nil, err
+		}
+		detail := fmt.Sprint("detail ", i.Id)
+		return &detail, nil
+	})
+	builtSchema := schema.MustBuild()
 
-	_ = schema.Mutation()
+	q := graphql.MustParse(`{ items { id detail } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	// A single concurrency slot resolves the details one after another.
+	e := graphql.Executor{Budget: 5, MaxConcurrency: 1}
+	result, err := e.ExecuteResult(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second detail overspends the budget, and the later ones are cut off.
+	assert.Equal(t, internal.ParseJSON(`{"items": [
+		{"id": 1, "detail": "detail 1"},
+		{"id": 2, "detail": null},
+		{"id": 3, "detail": null},
+		{"id": 4, "detail": null}
+	]}`), internal.AsJSON(result.Data))
+	assert.Equal(t, []int64{1, 2}, called)
+	if assert.Len(t, result.Errors, 3) {
+		for _, err := range result.Errors {
+			assert.Equal(t, "query exceeded its budget", err.Message)
+		}
+	}
+}
 
-	user := schema.Object("User", User{})
-	user.FieldFunc("slow", func(ctx context.Context, u *User) *Slow {
-		time.Sleep(10 * time.Millisecond)
-		return &Slow{}
-	})
+func TestSchemaMiddleware(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
 
-	slow := schema.Object("Slow", Slow{})
-	slow.FieldFunc("count", func() bool {
-		mu.Lock()
-		calls++
-		mu.Unlock()
-		return true
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	var order []string
+	var usersArgs interface{}
+	schema := schemabuilder.NewSchema()
+	for _, name := range []string{"outer", "inner"} {
+		name := name
+		schema.Use(func(next schemabuilder.ResolveFunc) schemabuilder.ResolveFunc {
+			return func(ctx context.Context, field schemabuilder.FieldInfo, source, args interface{}) (interface{}, error) {
+				mu.Lock()
+				if name == "outer" {
+					counts[field.Type+"."+field.Name]++
+				}
+				if field.Name == "users" {
+					order = append(order, name)
+					usersArgs = args
+				}
+				mu.Unlock()
+				return next(ctx, field, source, args)
+			}
+		})
+	}
+	query := schema.Query()
+	query.FieldFunc("users", func(args struct{ Prefix string }) []User {
+		return []User{{Id: 1, Name: args.Prefix + "alice"}, {Id: 2, Name: args.Prefix + "bob"}}
+	}, schemabuilder.Paginated)
+	query.FieldFunc("me", func(ctx context.Context) User {
+		return User{Id: 1, Name: "alice"}
 	})
-
+	user := schema.Object("User", User{})
+	user.Key("id")
 	builtSchema := schema.MustBuild()
 
-	q := graphql.MustParse(`
-		{
-			users {
-				one: slow { count }
-				two: slow { count }
-            }
-        }`, nil)
-
+	q := graphql.MustParse(`{
+		users(prefix: "@", first: 2) { totalCount edges { node { name } } }
+		me { name }
+	}`, nil)
 	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
 	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"users": {"totalCount": 2, "edges": [{"node": {"__key": 1, "name": "@alice"}}, {"node": {"__key": 2, "name": "@bob"}}]},
+		"me": {"__key": 1, "name": "alice"}
+	}`), internal.AsJSON(val))
+
+	// Every resolved field runs the middlewares once, including the fields of the connection.
+	assert.Equal(t, map[string]int{
+		"Query.users":               1,
+		"Query.me":                  1,
+		"UserConnection.totalCount": 1,
+		"UserConnection.edges":      1,
+		"UserEdge.node":             2,
+		"User.name":                 3,
+	}, counts)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.NotNil(t, usersArgs)
+}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	rerunner := reactive.NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
-		defer wg.Done()
-		e := graphql.Executor{}
-		ctx = concurrencylimiter.With(ctx, 100)
+func TestOptionalArgs(t *testing.T) {
+	type OptionalString struct {
+		schemabuilder.Optional
+		Value *string
+	}
+	type User struct {
+		Name  string
+		Email *string
+		Age   int64
+	}
+	type OptionalInt64 struct {
+		schemabuilder.Optional
+		Value int64
+	}
 
-		_, err := e.Execute(ctx, builtSchema.Query, nil, q)
+	email := "alice@example.com"
+	user := &User{Name: "alice", Email: &email, Age: 30}
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func() *User { return user })
+	schema.Mutation().FieldFunc("updateUser", func(args struct {
+		Name  OptionalString
+		Email OptionalString
+		Age   OptionalInt64
+	}) *User {
+		if args.Name.Set && args.Name.Value != nil {
+			user.Name = *args.Name.Value
+		}
+		if args.Email.Set {
+			user.Email = args.Email.Value
+		}
+		if args.Age.Set {
+			user.Age = args.Age.Value
+		}
+		return user
+	})
+	schema.Object("User", User{})
+	builtSchema := schema.MustBuild()
+
+	assert.Contains(t, builtSchema.SDL(), "updateUser(age: int64, email: string, name: string): User")
+
+	execute := func(query string, vars map[string]interface{}) interface{} {
+		q, err := graphql.Parse(query, vars)
 		if err != nil {
-			t.Error(err)
+			t.Fatal(err)
+		}
+		if err := graphql.PrepareQuery(builtSchema.Mutation, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Mutation, nil, q)
+		if err != nil {
+			t.Fatal(err)
 		}
+		return internal.AsJSON(val)
+	}
 
-		assert.Equal(t, 2*200, calls)
-		return nil, nil
-	}, 0)
+	// Omitted args are left unchanged.
+	val := execute(`mutation { updateUser(age: 31) { name email age } }`, nil)
+	assert.Equal(t, internal.ParseJSON(`{"updateUser": {"name": "alice", "email": "alice@example.com", "age": 31}}`), val)
 
-	wg.Wait()
-	defer rerunner.Stop()
+	// Null args are set, unlike omitted ones.
+	val = execute(`mutation M($email: string) { updateUser(name: "bob", email: $email) { name email age } }`, map[string]interface{}{"email": nil})
+	assert.Equal(t, internal.ParseJSON(`{"updateUser": {"name": "bob", "email": null, "age": 31}}`), val)
 }
@@ -0,0 +1,428 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ExecuteTo executes query on schema's Query or Mutation root, and writes its
+// result to w as JSON while it executes.
+//
+// Marshaling the result of Execute holds the result in memory twice, once as
+// nested maps and once as the marshaled JSON. ExecuteTo instead writes every
+// field and list item to w through a buffer of a few kilobytes as soon as it
+// is executed, which matters for results of several megabytes. Only the
+// values of expensive fields are held in memory until they are written: the
+// expensive fields of an object, and of all the objects of a list, are
+// started before it is written, so that they run concurrently as in Execute.
+//
+// The fields of objects are written in the order of the query's selection
+// set, followed by the fields of its fragments, as ordered by Flatten. The
+// internal __key fields are omitted. Nothing is written if the query is
+// invalid, but as written fields can't be nulled anymore, every error of a
+// field fails the query, ignoring PartialResults and PanicsFailField, and leaves
+// the JSON written so far incomplete.
+func (e *Executor) ExecuteTo(ctx context.Context, schema *Schema, query *Query, w io.Writer) error {
+	var typ Type
+	switch query.Kind {
+	case "mutation":
+		typ = schema.Mutation
+	case "subscription":
+		return NewClientError("subscriptions can't be executed")
+	default:
+		typ = schema.Query
+	}
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		return err
+	}
+
+	x := e.newExecution()
+	x.PartialResults = false
+	if x.Panics == PanicsFailField {
+		x.Panics = PanicsFailQuery
+	}
+	ctx, err := x.start(ctx, typ, query)
+	if err != nil {
+		return err
+	}
+
+	enc := &resultEncoder{w: bufio.NewWriter(w), flattened: make(map[*SelectionSet][]*Selection)}
+	x.mu.Lock()
+	if object, ok := typ.(*Object); ok && query.Kind == "mutation" {
+		// Not starting the expensive fields early executes the top-level
+		// fields of mutations one after another.
+		err = x.streamObject(ctx, enc, object, nil, query.SelectionSet, nil)
+	} else {
+		err = x.stream(ctx, enc, typ, nil, query.SelectionSet)
+	}
+	x.mu.Unlock()
+	x.finish(ctx)
+
+	if err != nil {
+		if query.Name != "" {
+			err = nestPathError(query.Name, err)
+		}
+		return err
+	}
+	return enc.w.Flush()
+}
+
+// stream executes source like execute, and writes its value to enc.
+func (e *execution) stream(ctx context.Context, enc *resultEncoder, typ Type, source interface{}, selectionSet *SelectionSet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(source)
+	isNil := !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil())
+
+	switch typ := typ.(type) {
+	case *Object:
+		// Objects are only null for nil pointers, e.g. the root has no source.
+		return e.streamObject(ctx, enc, typ, source, selectionSet, e.startExpensive(ctx, enc, typ, source, selectionSet))
+	case *Interface:
+		if !isNil {
+			typString := typ.ResolveType(source)
+			object, ok := typ.Types[typString]
+			if !ok {
+				return fmt.Errorf("interface type %s has no implementation %s", typ.Name, typString)
+			}
+			selectionSet = selectionSetOn(selectionSet, typ.Name, typString)
+			return e.streamObject(ctx, enc, object, source, selectionSet, e.startExpensive(ctx, enc, object, source, selectionSet))
+		}
+	case *Union:
+		if !isNil {
+			member, memberName, memberValue, err := unionMember(typ, source)
+			if err != nil {
+				return err
+			}
+			if member != nil {
+				selectionSet = selectionSetOn(selectionSet, typ.Name, memberName)
+				if err := e.streamObject(ctx, enc, member, memberValue, selectionSet, e.startExpensive(ctx, enc, member, memberValue, selectionSet)); err != nil {
+					return nestPathError(memberName, err)
+				}
+				return nil
+			}
+		}
+	case *List:
+		if !isNilList(source) {
+			return e.streamList(ctx, enc, typ, source, selectionSet)
+		}
+	case *NonNull:
+		if _, ok := typ.Type.(*List); !ok || !isNilList(source) {
+			return e.stream(ctx, enc, typ.Type, source, selectionSet)
+		}
+	}
+
+	// Scalars, enums and empty values are executed as usual.
+	executed, err := e.execute(ctx, typ, source, selectionSet)
+	if err != nil {
+		return err
+	}
+	return enc.encode(executed, selectionSet)
+}
+
+// startExpensive starts resolving the expensive fields of an object of typ,
+// and returns their pending values by alias. Fields with an Authorize check
+// are only resolved once they are written.
+func (e *execution) startExpensive(ctx context.Context, enc *resultEncoder, typ *Object, source interface{}, selectionSet *SelectionSet) map[string]interface{} {
+	if value := reflect.ValueOf(source); value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil
+	}
+
+	var started map[string]interface{}
+	for _, selection := range enc.selections(selectionSet) {
+		field, ok := typ.Fields[selection.Name]
+		if !ok || !field.Expensive || field.Authorize != nil {
+			continue
+		}
+		fieldCtx := e.fieldContext(ctx, typ, selectionSet, selection, field)
+		value, err := e.resolveAndExecute(fieldCtx, field, source, selection)
+		if err != nil {
+			value = fork(func() (interface{}, error) { return nil, err })
+		}
+		if started == nil {
+			started = make(map[string]interface{})
+		}
+		started[selection.Alias] = value
+	}
+	return started
+}
+
+// streamObject executes the selections of an object of typ like
+// executeObject, writing every field once it is executed. started holds the
+// pending values of expensive fields started with startExpensive.
+func (e *execution) streamObject(ctx context.Context, enc *resultEncoder, typ *Object, source interface{}, selectionSet *SelectionSet, started map[string]interface{}) error {
+	if value := reflect.ValueOf(source); value.Kind() == reflect.Ptr && value.IsNil() {
+		enc.w.WriteString("null")
+		return nil
+	}
+
+	enc.w.WriteByte('{')
+	for i, selection := range enc.selections(selectionSet) {
+		if i > 0 {
+			enc.w.WriteByte(',')
+		}
+		enc.writeString(selection.Alias)
+		enc.w.WriteByte(':')
+
+		if selection.Name == "__typename" {
+			enc.writeString(typ.Name)
+			continue
+		}
+		field := typ.Fields[selection.Name]
+		fieldCtx := e.fieldContext(ctx, typ, selectionSet, selection, field)
+		if err := e.streamField(fieldCtx, enc, field, source, selection, started[selection.Alias]); err != nil {
+			return nestPathError(selection.Alias, err)
+		}
+	}
+	enc.w.WriteByte('}')
+	return nil
+}
+
+// streamField executes field and writes its value. The values of expensive
+// fields are awaited before they are written, starting them if they weren't
+// started yet.
+func (e *execution) streamField(ctx context.Context, enc *resultEncoder, field *Field, source interface{}, selection *Selection, started interface{}) error {
+	if started == nil && field.Authorize != nil {
+		if err := field.Authorize(ctx, source); err != nil {
+			e.addError(ctx, err)
+			enc.w.WriteString("null")
+			return nil
+		}
+	}
+
+	if started == nil && field.Expensive {
+		var err error
+		if started, err = e.resolveAndExecute(ctx, field, source, selection); err != nil {
+			return err
+		}
+	}
+	if started != nil {
+		// The thunks of expensive fields lock e.mu to execute their
+		// selections.
+		e.mu.Unlock()
+		value, err := await(started)
+		e.mu.Lock()
+		if err != nil {
+			return err
+		}
+		return enc.encode(value, selection.SelectionSet)
+	}
+
+	value, err := e.resolve(ctx, field, source, selection)
+	if err != nil {
+		return err
+	}
+	return e.stream(ctx, enc, field.Type, value, selection.SelectionSet)
+}
+
+// streamList executes the items of a list like executeList, writing every item
+// once it is executed. The expensive fields of all the objects of the list are
+// started before the first one is written.
+func (e *execution) streamList(ctx context.Context, enc *resultEncoder, typ *List, source interface{}, selectionSet *SelectionSet) error {
+	slice := reflect.ValueOf(source)
+	if slice.Kind() == reflect.Map {
+		slice = reflect.ValueOf(mapEntries(slice))
+	}
+	itemCtxs := make([]context.Context, slice.Len())
+	for i := range itemCtxs {
+		itemCtxs[i] = ctx
+		if e.tracksPaths() {
+			itemCtxs[i] = withTracePath(ctx, i, "", "", nil)
+		}
+	}
+
+	itemType := typ.Type
+	if nonNull, ok := itemType.(*NonNull); ok {
+		itemType = nonNull.Type
+	}
+	object, isObject := itemType.(*Object)
+	var started []map[string]interface{}
+	if isObject {
+		started = make([]map[string]interface{}, slice.Len())
+		for i := range started {
+			started[i] = e.startExpensive(itemCtxs[i], enc, object, slice.Index(i).Interface(), selectionSet)
+		}
+	}
+
+	enc.w.WriteByte('[')
+	for i := 0; i < slice.Len(); i++ {
+		if i > 0 {
+			enc.w.WriteByte(',')
+		}
+		var err error
+		if isObject {
+			err = e.streamObject(itemCtxs[i], enc, object, slice.Index(i).Interface(), selectionSet, started[i])
+		} else {
+			err = e.stream(itemCtxs[i], enc, typ.Type, slice.Index(i).Interface(), selectionSet)
+		}
+		if err != nil {
+			return nestPathError(fmt.Sprint(i), err)
+		}
+	}
+	enc.w.WriteByte(']')
+	return nil
+}
+
+// resultEncoder writes results as JSON.
+type resultEncoder struct {
+	w *bufio.Writer
+	// scratch is reused to format numbers.
+	scratch []byte
+	// flattened caches the flattened selection sets, which are the same for
+	// all items of a list.
+	flattened map[*SelectionSet][]*Selection
+}
+
+// selections returns the flattened selections of selectionSet.
+func (enc *resultEncoder) selections(selectionSet *SelectionSet) []*Selection {
+	selections, ok := enc.flattened[selectionSet]
+	if !ok {
+		selections = Flatten(selectionSet)
+		enc.flattened[selectionSet] = selections
+	}
+	return selections
+}
+
+// encode writes value, whose object fields are ordered by selectionSet. The
+// selections of fragments are merged by Flatten, so that the objects of unions
+// and interfaces are ordered by the same selectionSet whatever their type.
+func (enc *resultEncoder) encode(value interface{}, selectionSet *SelectionSet) error {
+	switch value := value.(type) {
+	case nil:
+		enc.w.WriteString("null")
+	case map[string]interface{}:
+		// Maps without a selection set are scalars, e.g. JSON.
+		if selectionSet == nil {
+			return enc.marshal(value)
+		}
+		enc.w.WriteByte('{')
+		first := true
+		for _, selection := range enc.selections(selectionSet) {
+			field, ok := value[selection.Alias]
+			if !ok {
+				continue
+			}
+			if !first {
+				enc.w.WriteByte(',')
+			}
+			first = false
+			enc.writeString(selection.Alias)
+			enc.w.WriteByte(':')
+			if err := enc.encode(field, selection.SelectionSet); err != nil {
+				return nestPathError(selection.Alias, err)
+			}
+		}
+		enc.w.WriteByte('}')
+	case []interface{}:
+		enc.w.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				enc.w.WriteByte(',')
+			}
+			if err := enc.encode(item, selectionSet); err != nil {
+				return nestPathError(fmt.Sprint(i), err)
+			}
+		}
+		enc.w.WriteByte(']')
+	case string:
+		enc.writeString(value)
+	case bool:
+		enc.w.WriteString(strconv.FormatBool(value))
+	case int:
+		enc.writeInt(int64(value))
+	case int32:
+		enc.writeInt(int64(value))
+	case int64:
+		enc.writeInt(value)
+	case float32:
+		return enc.writeFloat(float64(value), 32)
+	case float64:
+		return enc.writeFloat(value, 64)
+	default:
+		return enc.marshal(value)
+	}
+	return nil
+}
+
+// marshal writes a value with encoding/json, for all values but the common
+// scalars, e.g. times and types implementing json.Marshaler.
+func (enc *resultEncoder) marshal(value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	enc.w.Write(bytes)
+	return nil
+}
+
+func (enc *resultEncoder) writeInt(value int64) {
+	enc.scratch = strconv.AppendInt(enc.scratch[:0], value, 10)
+	enc.w.Write(enc.scratch)
+}
+
+// writeFloat writes value formatted like encoding/json does.
+func (enc *resultEncoder) writeFloat(value float64, bits int) error {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return fmt.Errorf("unsupported value %v", value)
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	enc.scratch = strconv.AppendFloat(enc.scratch[:0], value, format, -1, bits)
+	enc.w.Write(enc.scratch)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeString writes s as a JSON string. Like encoding/json, invalid UTF-8 is
+// replaced by U+FFFD.
+func (enc *resultEncoder) writeString(s string) {
+	enc.w.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			enc.w.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				enc.w.WriteByte('\\')
+				enc.w.WriteByte(c)
+			case '\n':
+				enc.w.WriteString(`\n`)
+			case '\r':
+				enc.w.WriteString(`\r`)
+			case '\t':
+				enc.w.WriteString(`\t`)
+			default:
+				enc.w.WriteString(`\u00`)
+				enc.w.WriteByte(hexDigits[c>>4])
+				enc.w.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			enc.w.WriteString(s[start:i])
+			enc.w.WriteString(`\ufffd`)
+			start = i + size
+		}
+		i += size
+	}
+	enc.w.WriteString(s[start:])
+	enc.w.WriteByte('"')
+}
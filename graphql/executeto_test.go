@@ -0,0 +1,208 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamedUser struct {
+	Id      int64
+	Name    string
+	Score   float64
+	Active  bool
+	Created time.Time
+}
+
+func makeStreamedSchema(count int) *graphql.Schema {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("users", func() []*streamedUser {
+		users := make([]*streamedUser, count)
+		for i := range users {
+			users[i] = &streamedUser{
+				Id:      int64(i),
+				Name:    fmt.Sprintf("user \"%d\"\n", i),
+				Score:   float64(i) / 4,
+				Active:  i%2 == 0,
+				Created: time.Date(2018, 9, 13, 0, 0, i, 0, time.UTC),
+			}
+		}
+		return users
+	})
+	user := schema.Object("User", streamedUser{})
+	user.Key("id")
+	user.FieldFunc("friend", func(u *streamedUser) *streamedUser {
+		if u.Id != 0 {
+			return nil
+		}
+		return &streamedUser{Id: 1, Name: "friend"}
+	})
+	schema.Mutation()
+	return schema.MustBuild()
+}
+
+func TestExecuteTo(t *testing.T) {
+	schema := makeStreamedSchema(2)
+
+	q := graphql.MustParse(`{
+		users {
+			name
+			...Fields
+			friend { name id }
+			other: id
+		}
+	}
+	fragment Fields on User { id score active created }`, nil)
+
+	var buf bytes.Buffer
+	e := graphql.Executor{}
+	if err := e.ExecuteTo(context.Background(), schema, q, &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"users":[`+
+		`{"name":"user \"0\"\n","friend":{"name":"friend","id":1},"other":0,"id":0,"score":0,"active":true,"created":"2018-09-13T00:00:00Z"},`+
+		`{"name":"user \"1\"\n","friend":null,"other":1,"id":1,"score":0.25,"active":false,"created":"2018-09-13T00:00:01Z"}`+
+		`]}`, buf.String())
+
+	// The streamed result matches the marshaled result of Execute, but for the keys.
+	q = graphql.MustParse(`{ users { name id score active created } }`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	value, err := e.Execute(context.Background(), schema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range value.(map[string]interface{})["users"].([]interface{}) {
+		delete(user.(map[string]interface{}), "__key")
+	}
+	marshaled, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := e.ExecuteTo(context.Background(), schema, q, &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, string(marshaled), buf.String())
+
+	buf.Reset()
+	q = graphql.MustParse(`{ users { missing } }`, nil)
+	if err := e.ExecuteTo(context.Background(), schema, q, &buf); err == nil || err.Error() != `unknown field "missing"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	assert.Equal(t, 0, buf.Len())
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	bytes.Buffer
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&w.written, int64(len(p)))
+	return w.Buffer.Write(p)
+}
+
+func TestExecuteToStreams(t *testing.T) {
+	type item struct {
+		Id int64
+	}
+	var w countingWriter
+	const count = 1000
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func() []item {
+		items := make([]item, count)
+		for i := range items {
+			items[i].Id = int64(i)
+		}
+		return items
+	})
+	var started int64
+	obj := schema.Object("Item", item{})
+	obj.FieldFunc("written", func(i item) int64 {
+		return atomic.LoadInt64(&w.written)
+	})
+	obj.FieldFunc("expensive", func(ctx context.Context, i item) (int64, error) {
+		// Fails unless the expensive fields of all items run concurrently.
+		atomic.AddInt64(&started, 1)
+		for atomic.LoadInt64(&started) < count {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return i.Id, nil
+	})
+	builtSchema := schema.MustBuild()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	q := graphql.MustParse(`{ items { id written expensive } }`, nil)
+	e := graphql.Executor{}
+	if err := e.ExecuteTo(ctx, builtSchema, q, &w); err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Items []struct{ Id, Written, Expensive int64 }
+	}
+	if err := json.Unmarshal(w.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, result.Items, count)
+	for i, item := range result.Items {
+		assert.Equal(t, int64(i), item.Id)
+		assert.Equal(t, int64(i), item.Expensive)
+	}
+	// The first items were written before the last ones were executed.
+	assert.Equal(t, int64(0), result.Items[0].Written)
+	assert.NotEqual(t, int64(0), result.Items[count-1].Written)
+}
+
+func BenchmarkExecuteTo(b *testing.B) {
+	schema := makeStreamedSchema(5000)
+	q := graphql.MustParse(`{ users { id name score active created friend { name } } }`, nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := graphql.Executor{}
+		if err := e.ExecuteTo(context.Background(), schema, q, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteMarshal(b *testing.B) {
+	schema := makeStreamedSchema(5000)
+	q := graphql.MustParse(`{ users { id name score active created friend { name } } }`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := graphql.Executor{}
+		value, err := e.Execute(context.Background(), schema.Query, nil, q)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := json.Marshal(value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
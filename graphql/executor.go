@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/samsarahq/thunder/concurrencylimiter"
 	"github.com/samsarahq/thunder/reactive"
@@ -38,6 +40,18 @@ func nestPathError(key string, err error) error {
 	}
 }
 
+// ResponseError is the error of a field, as returned in the Errors of a
+// Result. Its path consists of the aliases of the fields and the indices of
+// the list elements leading to the field, as in GraphQL responses.
+type ResponseError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+
+	// Extensions are the extensions of errors implementing ExtendedError, e.g.
+	// the code of errors created with NewCodedError.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
 func ErrorCause(err error) error {
 	if pe, ok := err.(*pathError); ok {
 		return pe.inner
@@ -118,6 +132,25 @@ func PrepareQuery(typ Type, selectionSet *SelectionSet) error {
 			return NewClientError(`unknown field "%s"`, selection.Name)
 		}
 		return nil
+	case *Interface:
+		if selectionSet == nil {
+			return NewClientError("object field must have selections")
+		}
+
+		for _, selection := range selectionSet.Selections {
+			if selection.Name == "__typename" {
+				continue
+			}
+			if _, ok := typ.Fields[selection.Name]; !ok {
+				return NewClientError(`unknown field "%s"`, selection.Name)
+			}
+		}
+		for typString, graphqlTyp := range typ.Types {
+			if err := PrepareQuery(graphqlTyp, selectionSetOn(selectionSet, typ.Name, typString)); err != nil {
+				return err
+			}
+		}
+		return nil
 	case *Object:
 		if selectionSet == nil {
 			return NewClientError("object field must have selections")
@@ -170,39 +203,215 @@ func PrepareQuery(typ Type, selectionSet *SelectionSet) error {
 	}
 }
 
+// A PanicMode sets how an Executor handles resolvers that panic.
+type PanicMode int
+
+const (
+	// PanicsFailQuery recovers panics and fails the query with them, like
+	// other errors of resolvers.
+	PanicsFailQuery PanicMode = iota
+
+	// PanicsFailField recovers panics and only fails the field of the
+	// panicking resolver, like PartialResults does for all errors. The field
+	// resolves to null and the panic is returned by ExecuteResult with the
+	// field's path.
+	PanicsFailField
+
+	// PanicsPropagate lets panics propagate instead of recovering them, e.g.
+	// to see them in tests.
+	PanicsPropagate
+)
+
+// A panicError is the error of a resolver that panicked.
 type panicError struct {
-	message string
+	message   string
+	recovered interface{}
+	stack     []byte
 }
 
 func (p panicError) Error() string {
 	return p.message
 }
 
+// isPanicError returns whether err is the error of a resolver that panicked.
+func isPanicError(err error) bool {
+	_, ok := ErrorCause(err).(panicError)
+	return ok
+}
+
 func safeResolve(ctx context.Context, field *Field, source, args interface{}, selectionSet *SelectionSet) (result interface{}, err error) {
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
 			const size = 64 << 10
 			buf := make([]byte, size)
 			buf = buf[:runtime.Stack(buf, false)]
-			result, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
+			result, err = nil, panicError{
+				message:   fmt.Sprintf("graphql: panic: %v\n%s", panicErr, buf),
+				recovered: panicErr,
+				stack:     buf,
+			}
 		}
 	}()
 	return field.Resolve(ctx, source, args, selectionSet)
 }
 
+// resolveField resolves field, giving up after the field's Timeout if it is
+// set. Only the field's own resolver is subject to the timeout, not the
+// resolvers of its selections.
+func resolveField(ctx context.Context, field *Field, source, args interface{}, selectionSet *SelectionSet, recoverPanics bool) (interface{}, error) {
+	resolve := field.Resolve
+	if recoverPanics {
+		resolve = func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+			return safeResolve(ctx, field, source, args, selectionSet)
+		}
+	}
+
+	if field.Timeout <= 0 {
+		return resolve(ctx, source, args, selectionSet)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, field.Timeout)
+	defer cancel()
+
+	// Resolve in a separate goroutine, so that resolvers that ignore their
+	// context can't block the query.
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := resolve(timeoutCtx, source, args, selectionSet)
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-timeoutCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("resolver timed out after %s", field.Timeout)
+	}
+}
+
+// memoizeKey identifies the values of a field with Memoize resolved on a
+// source.
+type memoizeKey struct {
+	field  *Field
+	source interface{}
+}
+
+// memoizedValue is the value of a field with Memoize resolved with args. done
+// is closed once value and err are set.
+type memoizedValue struct {
+	args  interface{}
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// resolveMemoized resolves a field with Memoize, reusing the value resolved
+// earlier in the execution on the same source with equal args. Fields of
+// sources that can't be compared aren't memoized.
+func (e *execution) resolveMemoized(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+	if value := reflect.ValueOf(source); value.IsValid() && !value.Type().Comparable() {
+		return e.resolveTraced(ctx, field, source, selection)
+	}
+	key := memoizeKey{field: field, source: source}
+
+	e.memoizedMu.Lock()
+	for _, memoized := range e.memoized[key] {
+		if reflect.DeepEqual(memoized.args, selection.Args) {
+			e.memoizedMu.Unlock()
+			<-memoized.done
+			return memoized.value, memoized.err
+		}
+	}
+	memoized := &memoizedValue{args: selection.Args, done: make(chan struct{})}
+	if e.memoized == nil {
+		e.memoized = make(map[memoizeKey][]*memoizedValue)
+	}
+	e.memoized[key] = append(e.memoized[key], memoized)
+	e.memoizedMu.Unlock()
+
+	defer close(memoized.done)
+	memoized.value, memoized.err = e.resolveTraced(ctx, field, source, selection)
+	return memoized.value, memoized.err
+}
+
+// resolveTraced resolves field, recording its timing if tracing is enabled.
+func (e *execution) resolveTraced(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+	if e.tracing == nil {
+		return resolveField(ctx, field, source, selection.Args, selection.SelectionSet, e.Panics != PanicsPropagate)
+	}
+	start := time.Now()
+	value, err := resolveField(ctx, field, source, selection.Args, selection.SelectionSet, e.Panics != PanicsPropagate)
+	e.tracing.record(ctx, field, start, time.Now())
+	return value, err
+}
+
+// resolve resolves field and applies the selection's directives to its value.
+func (e *execution) resolve(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+	// Don't start resolvers once the query is canceled or timed out, e.g.
+	// expensive fields that were waiting for a concurrency slot.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	var err error
+	if field.Memoize {
+		value, err = e.resolveMemoized(ctx, field, source, selection)
+	} else {
+		value, err = e.resolveTraced(ctx, field, source, selection)
+	}
+	if err != nil {
+		if panicErr, ok := err.(panicError); ok && e.OnPanic != nil {
+			e.OnPanic(ctx, panicErr.recovered, panicErr.stack)
+		}
+		if field.BestEffort && ctx.Err() == nil {
+			if e.OnBestEffortError != nil {
+				e.OnBestEffortError(ctx, err)
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, directive := range selection.Directives {
+		if directive.Name == "defer" {
+			continue
+		}
+		value, err = e.Directives[directive.Name].Apply(ctx, value, directive.Args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
 type resolveAndExecuteCacheKey struct {
 	field     *Field
 	source    interface{}
 	selection *Selection
 }
 
-func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+func (e *execution) resolveAndExecute(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
 	if field.Expensive {
 		// TODO: Skip goroutine for cached value
 		ctx, release := concurrencylimiter.Acquire(ctx)
 		return fork(func() (interface{}, error) {
 			defer release()
 
+			if err := checkBudget(ctx); err != nil {
+				if err := e.fieldError(ctx, field, err); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			}
+
 			value := reflect.ValueOf(source)
 			// cache the body of resolve and excecute so that if the source doesn't change, we
 			// don't need to recompute
@@ -217,7 +426,7 @@ func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source i
 
 			// TODO: Consider cacheing resolve and execute independently
 			resolvedValue, err := reactive.Cache(ctx, key, func(ctx context.Context) (interface{}, error) {
-				value, err := safeResolve(ctx, field, source, selection.Args, selection.SelectionSet)
+				value, err := e.resolve(ctx, field, source, selection)
 				if err != nil {
 					return nil, err
 				}
@@ -235,35 +444,69 @@ func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source i
 				}
 				return await(value)
 			})
+			if err != nil {
+				if err := e.fieldError(ctx, field, err); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			}
 
-			return resolvedValue, err
+			return resolvedValue, nil
 		}), nil
 	}
 
-	value, err := safeResolve(ctx, field, source, selection.Args, selection.SelectionSet)
+	value, err := e.resolve(ctx, field, source, selection)
 	if err != nil {
 		return nil, err
 	}
 	return e.execute(ctx, field.Type, value, selection.SelectionSet)
 }
 
-func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+func (e *execution) executeUnion(ctx context.Context, typ *Union, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
-	if value.Kind() == reflect.Ptr && value.IsNil() {
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
 		return nil, nil
 	}
 
-	fields := make(map[string]interface{})
-	for _, selection := range selectionSet.Selections {
-		if selection.Name == "__typename" {
-			fields[selection.Alias] = typ.Name
-			continue
+	// Execute the fragments on the member type or on the union.
+	member, memberName, memberValue, err := unionMember(typ, source)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		fields := make(map[string]interface{})
+		for _, selection := range selectionSet.Selections {
+			if selection.Name == "__typename" {
+				fields[selection.Alias] = typ.Name
+			}
 		}
+		return fields, nil
+	}
+
+	resolved, err := e.executeObject(ctx, member, memberValue, selectionSetOn(selectionSet, typ.Name, memberName))
+	if err != nil {
+		return nil, nestPathError(memberName, err)
+	}
+	return resolved, nil
+}
+
+// unionMember returns the member type of a union value, its name and the value
+// to execute on it. The member type is picked by the union's ResolveType, or
+// else is the one set in the union struct, which is nil if none is set.
+func unionMember(typ *Union, source interface{}) (*Object, string, interface{}, error) {
+	if typ.ResolveType != nil {
+		typString := typ.ResolveType(source)
+		graphqlTyp, ok := typ.Types[typString]
+		if !ok {
+			return nil, "", nil, fmt.Errorf("union type %s has no member type %s", typ.Name, typString)
+		}
+		return graphqlTyp, typString, source, nil
 	}
 
-	// For every inline fragment spread, check if the current concrete type
-	// matches and execute that object.
 	var possibleTypes []string
+	var member *Object
+	var memberName string
+	var memberValue interface{}
 	for typString, graphqlTyp := range typ.Types {
 		inner := reflect.ValueOf(source)
 		if inner.Kind() == reflect.Ptr && inner.Elem().Kind() == reflect.Struct {
@@ -275,30 +518,50 @@ func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interfac
 			continue
 		}
 		possibleTypes = append(possibleTypes, graphqlTyp.String())
+		member, memberName, memberValue = graphqlTyp, typString, inner.Interface()
+	}
 
-		for _, fragment := range selectionSet.Fragments {
-			if fragment.On != typString {
-				continue
-			}
-			resolved, err := e.executeObject(ctx, graphqlTyp, inner.Interface(), fragment.SelectionSet)
-			if err != nil {
-				return nil, nestPathError(typString, err)
-			}
+	if len(possibleTypes) > 1 {
+		return nil, "", nil, fmt.Errorf("union type field should only return one value, but received: %s", strings.Join(possibleTypes, " "))
+	}
+	return member, memberName, memberValue, nil
+}
 
-			for k, v := range resolved.(map[string]interface{}) {
-				fields[k] = v
-			}
-		}
+// executeInterface executes an interface query on the object type picked by
+// the interface's ResolveType.
+func (e *execution) executeInterface(ctx context.Context, typ *Interface, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+	value := reflect.ValueOf(source)
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return nil, nil
 	}
 
-	if len(possibleTypes) > 1 {
-		return nil, fmt.Errorf("union type field should only return one value, but received: %s", strings.Join(possibleTypes, " "))
+	typString := typ.ResolveType(source)
+	graphqlTyp, ok := typ.Types[typString]
+	if !ok {
+		return nil, fmt.Errorf("interface type %s has no implementation %s", typ.Name, typString)
 	}
-	return fields, nil
+	return e.executeObject(ctx, graphqlTyp, source, selectionSetOn(selectionSet, typ.Name, typString))
+}
+
+// selectionSetOn returns the selections of an interface's or union's
+// selectionSet that apply to one of its object types, that is all fields, and
+// the fragments, including nested fragments, on the interface or union or on
+// the object type.
+func selectionSetOn(selectionSet *SelectionSet, abstractName, typString string) *SelectionSet {
+	filtered := &SelectionSet{Selections: selectionSet.Selections}
+	for _, fragment := range selectionSet.Fragments {
+		if fragment.On == abstractName || fragment.On == typString {
+			filtered.Fragments = append(filtered.Fragments, &Fragment{
+				On:           fragment.On,
+				SelectionSet: selectionSetOn(fragment.SelectionSet, abstractName, typString),
+			})
+		}
+	}
+	return filtered
 }
 
 // executeObject executes an object query
-func (e *Executor) executeObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+func (e *execution) executeObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
@@ -308,6 +571,12 @@ func (e *Executor) executeObject(ctx context.Context, typ *Object, source interf
 
 	fields := make(map[string]interface{})
 
+	// A failed non-null field nulls this object, once the other fields have
+	// been resolved to record their errors too. Non-null fields resolved
+	// asynchronously may still fail later.
+	var nonNullFailed bool
+	var nonNullThunks []*thunk
+
 	// for every selection, resolve the value and store it in the output object
 	for _, selection := range selections {
 		if selection.Name == "__typename" {
@@ -315,10 +584,40 @@ func (e *Executor) executeObject(ctx context.Context, typ *Object, source interf
 			continue
 		}
 
+		if e.incremental && selection.deferred() {
+			e.deferField(ctx, typ, source, selection)
+			continue
+		}
+
 		field := typ.Fields[selection.Name]
-		resolved, err := e.resolveAndExecute(ctx, field, source, selection)
+		fieldCtx := e.fieldContext(ctx, typ, selectionSet, selection, field)
+		if field.Authorize != nil {
+			if err := field.Authorize(fieldCtx, source); err != nil {
+				e.addError(fieldCtx, err)
+				if _, ok := field.Type.(*NonNull); ok && e.propagatesNulls() {
+					nonNullFailed = true
+				}
+				fields[selection.Alias] = nil
+				continue
+			}
+		}
+		resolved, err := e.resolveAndExecute(fieldCtx, field, source, selection)
 		if err != nil {
-			return nil, nestPathError(selection.Alias, err)
+			if err := e.fieldError(fieldCtx, field, err); err != nil {
+				if err != errNullPropagation {
+					return nil, nestPathError(selection.Alias, err)
+				}
+				nonNullFailed = true
+			}
+			fields[selection.Alias] = nil
+			continue
+		}
+		if t, ok := resolved.(*thunk); ok && e.propagatesNulls() {
+			if _, ok := field.Type.(*NonNull); ok {
+				nonNullThunks = append(nonNullThunks, t)
+			} else {
+				resolved = nullOnPropagation(t)
+			}
 		}
 		fields[selection.Alias] = resolved
 	}
@@ -331,42 +630,151 @@ func (e *Executor) executeObject(ctx context.Context, typ *Object, source interf
 		fields["__key"] = value
 	}
 
+	if nonNullFailed {
+		return nil, errNullPropagation
+	}
+	if len(nonNullThunks) > 0 {
+		return awaitNonNull(nonNullThunks, fields), nil
+	}
 	return fields, nil
 }
 
+// fieldContext returns the context in which field executes for a selection of
+// selectionSet on an object of typ.
+func (e *execution) fieldContext(ctx context.Context, typ *Object, selectionSet *SelectionSet, selection *Selection, field *Field) context.Context {
+	if e.tracksPaths() {
+		ctx = withTracePath(ctx, selection.Alias, typ.Name, selection.Name, field)
+	}
+	if e.FieldUsage != nil {
+		ctx = e.recordFieldUsage(ctx, selection.Name)
+	}
+	if selectionSet == e.rootSelectionSet {
+		ctx = e.cacheHints.withRootField(ctx, selection.Alias)
+	}
+	return ctx
+}
+
+// awaitNonNull returns a thunk awaiting value, which fails with
+// errNullPropagation if one of the thunks of its non-null fields or items
+// does.
+func awaitNonNull(thunks []*thunk, value interface{}) *thunk {
+	return fork(func() (interface{}, error) {
+		for _, t := range thunks {
+			if _, err := t.await(); ErrorCause(err) == errNullPropagation {
+				return nil, errNullPropagation
+			}
+		}
+		return await(value)
+	})
+}
+
+// nullOnPropagation returns a thunk awaiting the value of a nullable field or
+// item, which resolves to null if one of its non-null fields fails.
+func nullOnPropagation(t *thunk) *thunk {
+	return fork(func() (interface{}, error) {
+		value, err := t.await()
+		if ErrorCause(err) == errNullPropagation {
+			return nil, nil
+		}
+		return value, err
+	})
+}
+
 var emptyList = []interface{}{}
 
-// executeList executes a set query
-func (e *Executor) executeList(ctx context.Context, typ *List, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
-	if reflect.ValueOf(source).IsNil() {
-		return emptyList, nil
+// MapEntry is a key and value of a map. Lists execute maps with string keys as
+// a list of their entries, sorted by key.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// mapEntries returns the entries of a map with string keys, sorted by key.
+func mapEntries(m reflect.Value) []MapEntry {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	entries := make([]MapEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = MapEntry{Key: key.Interface(), Value: m.MapIndex(key).Interface()}
+	}
+	return entries
+}
+
+// isNilList returns if source is a nil slice or map, or nil.
+func isNilList(source interface{}) bool {
+	value := reflect.ValueOf(source)
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return value.IsNil()
 	}
+	return false
+}
 
+// executeList executes a set query
+func (e *execution) executeList(ctx context.Context, typ *List, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
 	// iterate over arbitrary slice types using reflect
 	slice := reflect.ValueOf(source)
+	if slice.Kind() == reflect.Map {
+		slice = reflect.ValueOf(mapEntries(slice))
+	}
 	items := make([]interface{}, slice.Len())
+	_, nonNull := typ.Type.(*NonNull)
+	var nonNullThunks []*thunk
 
 	// resolve every element in the slice
 	for i := 0; i < slice.Len(); i++ {
 		value := slice.Index(i)
-		resolved, err := e.execute(ctx, typ.Type, value.Interface(), selectionSet)
+		itemCtx := ctx
+		if e.tracksPaths() {
+			itemCtx = withTracePath(ctx, i, "", "", nil)
+		}
+		resolved, err := e.execute(itemCtx, typ.Type, value.Interface(), selectionSet)
 		if err != nil {
+			if err == errNullPropagation {
+				if nonNull {
+					return nil, err
+				}
+				continue
+			}
 			return nil, nestPathError(fmt.Sprint(i), err)
 		}
+		if t, ok := resolved.(*thunk); ok && e.propagatesNulls() {
+			if nonNull {
+				nonNullThunks = append(nonNullThunks, t)
+			} else {
+				resolved = nullOnPropagation(t)
+			}
+		}
 		items[i] = resolved
 	}
 
+	if len(nonNullThunks) > 0 {
+		return awaitNonNull(nonNullThunks, items), nil
+	}
 	return items, nil
 }
 
 // execute executes a query by dispatches according to typ
-func (e *Executor) execute(ctx context.Context, typ Type, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
-	if err := ctx.Err(); err != nil {
+func (e *execution) execute(ctx context.Context, typ Type, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+	// With PartialResults, values resolved before ctx was done are kept, and
+	// only the resolvers that haven't started yet are skipped.
+	if err := ctx.Err(); err != nil && !e.PartialResults {
 		return nil, err
 	}
 	switch typ := typ.(type) {
 	case *Scalar:
-		return unwrap(source), nil
+		value := unwrap(source)
+		if typ.Serialize != nil {
+			// Nil pointers aren't unwrapped, and are returned as-is.
+			if v := reflect.ValueOf(value); v.IsValid() && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+				return typ.Serialize(value), nil
+			}
+		}
+		return value, nil
 	case *Enum:
 		val := unwrap(source)
 		if mapVal, ok := typ.ReverseMap[val]; ok {
@@ -375,11 +783,23 @@ func (e *Executor) execute(ctx context.Context, typ Type, source interface{}, se
 		return nil, errors.New("enum is not valid")
 	case *Union:
 		return e.executeUnion(ctx, typ, source, selectionSet)
+	case *Interface:
+		return e.executeInterface(ctx, typ, source, selectionSet)
 	case *Object:
 		return e.executeObject(ctx, typ, source, selectionSet)
 	case *List:
+		if isNilList(source) {
+			if e.EmptyNilLists {
+				return emptyList, nil
+			}
+			return nil, nil
+		}
 		return e.executeList(ctx, typ, source, selectionSet)
 	case *NonNull:
+		// Non-null lists are never null: nil slices execute as empty lists.
+		if _, ok := typ.Type.(*List); ok && isNilList(source) {
+			return emptyList, nil
+		}
 		return e.execute(ctx, typ.Type, source, selectionSet)
 	default:
 		panic(typ)
@@ -387,20 +807,334 @@ func (e *Executor) execute(ctx context.Context, typ Type, source interface{}, se
 }
 
 type Executor struct {
-	mu sync.Mutex
+	// MaxDepth optionally limits how deeply the fields of a query may be
+	// nested. Fields at the top level of a query have depth 1. Queries
+	// exceeding the limit are rejected before they are executed.
+	MaxDepth int
+
+	// MaxFields optionally limits the total number of fields requested by a
+	// query, counting every alias and every use of a fragment separately. It
+	// can be combined with MaxDepth. Queries exceeding the limit are rejected
+	// before they are executed.
+	MaxFields int
+
+	// MaxCost optionally limits the QueryCost of a query. Queries exceeding
+	// the limit are rejected before they are executed.
+	MaxCost int
+
+	// MaxConcurrency optionally limits how many resolvers of expensive fields
+	// run at the same time during an execution; the others wait for a slot.
+	// Resolvers give up their slot before their selections are executed, so
+	// nested expensive fields can't deadlock. It replaces a limit attached to
+	// the context with concurrencylimiter.With.
+	MaxConcurrency int
+
+	// Budget optionally limits the units that the resolvers of an execution
+	// may spend with ChargeBudget, e.g. on an external API quota. Once it is
+	// spent, expensive fields that haven't started yet fail with a client
+	// error.
+	Budget int
+
+	// CollectTracing enables recording the timing of every resolver, which is
+	// returned in the Tracing of the ExecutionResult of ExecuteResult.
+	CollectTracing bool
+
+	// PartialResults makes fields that fail resolve to null, while their
+	// siblings are still resolved, instead of failing the whole query. The
+	// errors of the failed fields are returned by ExecuteResult. As in the
+	// GraphQL spec, a failed non-null field nulls its nearest nullable
+	// ancestor instead, or the whole result if there is none. If ctx is done
+	// during the execution, the pending fields resolve to null and Execute
+	// returns the partial data with ctx's error.
+	PartialResults bool
+
+	// MinRerunInterval is the minimum time between executions of a
+	// subscription started with Subscribe. If it is 0, DefaultMinRerunInterval
+	// is used.
+	MinRerunInterval time.Duration
+
+	// Panics sets how resolvers that panic are handled. By default, panics
+	// are recovered and fail the query like other errors.
+	Panics PanicMode
+
+	// OnPanic is optionally called with the recovered value and stack of every
+	// panicking resolver, e.g. to log them, unless Panics is PanicsPropagate.
+	OnPanic func(ctx context.Context, recovered interface{}, stack []byte)
+
+	// OnBestEffortError is optionally called with the error of every
+	// BestEffort field whose resolver failed, e.g. to log them, since those
+	// errors are otherwise dropped.
+	OnBestEffortError func(ctx context.Context, err error)
+
+	// EmptyNilLists makes nil slices and maps execute as empty lists for
+	// nullable list types, e.g. for clients that can't handle null lists.
+	// Without it, they execute as null, while empty slices execute as empty
+	// lists. Non-null lists always execute nil slices as empty lists.
+	EmptyNilLists bool
+
+	// FieldUsage optionally receives the fields resolved by every execution,
+	// e.g. to find out which fields clients still query before removing them.
+	FieldUsage FieldUsageSink
+
+	// Directives are the custom directives that can be used in queries,
+	// typically the schema's Directives. Queries using other directives are
+	// rejected before they are executed.
+	Directives map[string]*Directive
+}
+
+// An execution is a single execution of a query by an Executor. It holds the
+// state of the execution, so that an Executor can run several executions at
+// the same time.
+type execution struct {
+	// Executor is a copy of the configuration of the executor.
+	Executor
+
+	// mu serializes the synchronous parts of the execution. Expensive fields
+	// are resolved concurrently.
+	mu      sync.Mutex
+	tracing *Tracing
+
+	// cacheHints aggregates the hints set with SetCacheHint by the fields of
+	// rootSelectionSet.
+	cacheHints       *cacheHints
+	rootSelectionSet *SelectionSet
+
+	// cost computes the QueryCost of the query once it is needed. Executions
+	// run by middlewares share it with their ComputationOutput.
+	cost func() int
+
+	errorsMu       sync.Mutex
+	responseErrors []*ResponseError
+
+	// memoized holds the values of fields with Memoize resolved so far.
+	memoizedMu sync.Mutex
+	memoized   map[memoizeKey][]*memoizedValue
+
+	// fieldUsage counts the fields resolved if FieldUsage is set.
+	fieldUsageMu sync.Mutex
+	fieldUsage   map[string]int
+
+	// incremental is set by ExecuteIncremental, which collects the fields
+	// marked with @defer in deferred.
+	incremental bool
+	deferredMu  sync.Mutex
+	deferred    []*deferredField
+}
+
+// newExecution returns a new execution with the configuration of e.
+func (e *Executor) newExecution() *execution {
+	x := &execution{Executor: *e, cacheHints: &cacheHints{}}
+	if e.CollectTracing {
+		x.tracing = &Tracing{Version: 1}
+	}
+	if e.FieldUsage != nil {
+		x.fieldUsage = make(map[string]int)
+	}
+	return x
+}
+
+// errors returns the errors of the fields that failed with PartialResults,
+// of the fields whose resolver panicked with PanicsFailField, and of the fields
+// that failed their Authorize check.
+func (e *execution) errors() []*ResponseError {
+	e.errorsMu.Lock()
+	defer e.errorsMu.Unlock()
+	return e.responseErrors
+}
+
+// An ExecutionResult is the result of an operation executed by
+// Executor.ExecuteResult or Executor.ExecuteBatch.
+type ExecutionResult struct {
+	// Data is the result of the operation's query.
+	Data interface{}
+
+	// Err is the error of an operation that failed as a whole. It is only set
+	// by ExecuteBatch, as ExecuteResult returns it instead.
+	Err error
+
+	// Errors are the errors of the fields that failed during the execution,
+	// see Executor.PartialResults.
+	Errors []*ResponseError
+
+	// CacheHint is the cache hint aggregated from the hints set with
+	// SetCacheHint, or nil if the query isn't covered by hints.
+	CacheHint *CacheHint
+
+	// Tracing is the timing of the execution if Executor.CollectTracing is
+	// set.
+	Tracing *Tracing
+
+	// Dependencies are the reactive dependencies added while executing the
+	// operation. It is only set by ExecuteBatch when executed by a
+	// reactive.Rerunner.
+	Dependencies []reactive.Dependency
+}
+
+// result returns the result of the execution with data.
+func (e *execution) result(data interface{}) *ExecutionResult {
+	return &ExecutionResult{Data: data, Errors: e.errors(), CacheHint: e.cacheHints.get(), Tracing: e.tracing}
+}
+
+// tracksPaths returns if the path of the executing field is tracked in the
+// context.
+func (e *execution) tracksPaths() bool {
+	return e.tracing != nil || e.PartialResults || e.Panics == PanicsFailField || e.incremental
+}
+
+// isFieldError returns whether err only fails the field it occurred in,
+// instead of the whole query.
+func (e *execution) isFieldError(err error) bool {
+	return e.PartialResults || (e.Panics == PanicsFailField && isPanicError(err))
+}
+
+// propagatesNulls returns whether failed non-null fields null their nearest
+// nullable ancestor, as in the GraphQL spec.
+func (e *execution) propagatesNulls() bool {
+	return e.PartialResults || e.Panics == PanicsFailField
+}
+
+// errNullPropagation fails the parent of a non-null field that failed, after
+// the field's error has been recorded, so that the nearest nullable ancestor
+// resolves to null.
+var errNullPropagation = errors.New("non-null field failed")
+
+// fieldError handles the error of a field executing in ctx. If the error only
+// fails the field, it is recorded and fieldError returns nil, meaning that the
+// field resolves to null, or errNullPropagation if the field is non-null.
+// Other errors are returned as-is and fail the query.
+//
+// Once ctx is done, fields fail the query, except with PartialResults: then
+// the pending fields resolve to null without recording their errors, and
+// Execute returns ctx's error alongside the data resolved so far.
+func (e *execution) fieldError(ctx context.Context, field *Field, err error) error {
+	propagated := ErrorCause(err) == errNullPropagation
+	done := ctx.Err() != nil
+	if done && !e.PartialResults {
+		return err
+	}
+	if !done && !propagated && !e.isFieldError(err) {
+		return err
+	}
+	if !done && !propagated {
+		e.addError(ctx, err)
+	}
+	if _, ok := field.Type.(*NonNull); ok && e.propagatesNulls() {
+		return errNullPropagation
+	}
+	return nil
+}
+
+// addError records the error of the field executing in ctx. Errors that don't
+// implement SanitizedError are masked.
+func (e *execution) addError(ctx context.Context, err error) {
+	var path []interface{}
+	if traceCtx, ok := ctx.Value(traceContextKey{}).(*traceContext); ok {
+		path = append(path, traceCtx.path...)
+	}
+	if pe, ok := err.(*pathError); ok {
+		for i := len(pe.path) - 1; i >= 0; i-- {
+			path = append(path, pe.path[i])
+		}
+	}
+
+	e.errorsMu.Lock()
+	defer e.errorsMu.Unlock()
+	e.responseErrors = append(e.responseErrors, &ResponseError{
+		Message:    sanitizeError(ErrorCause(err)),
+		Path:       path,
+		Extensions: sanitizeExtensions(ErrorCause(err)),
+	})
 }
 
 // Execute executes a query by dispatches according to typ
 func (e *Executor) Execute(ctx context.Context, typ Type, source interface{}, query *Query) (interface{}, error) {
-	e.mu.Lock()
-	value, err := e.execute(ctx, typ, source, query.SelectionSet)
-	e.mu.Unlock()
+	return e.newExecution().run(ctx, typ, source, query)
+}
+
+// start checks a query against the limits of the execution before it is
+// executed, and returns the context to execute it in.
+func (e *execution) start(ctx context.Context, typ Type, query *Query) (context.Context, error) {
+	if e.MaxDepth > 0 {
+		if err := checkDepth(query.SelectionSet, 1, e.MaxDepth, nil); err != nil {
+			return nil, err
+		}
+	}
+	if e.MaxFields > 0 {
+		if count := countFields(query.SelectionSet); count > e.MaxFields {
+			return nil, NewClientError("query requests %d fields, exceeding max fields %d", count, e.MaxFields)
+		}
+	}
+	if e.cost == nil {
+		e.cost = lazyQueryCost(ctx, typ, query.SelectionSet)
+	}
+	if e.MaxCost > 0 {
+		if cost := e.cost(); cost > e.MaxCost {
+			return nil, NewClientError("query cost %d exceeds max cost %d", cost, e.MaxCost)
+		}
+	}
+	if err := checkDirectives(query.SelectionSet, e.Directives); err != nil {
+		return nil, err
+	}
+
+	if e.MaxConcurrency > 0 {
+		ctx = concurrencylimiter.With(ctx, e.MaxConcurrency)
+	}
+	if e.Budget > 0 {
+		ctx = withBudget(ctx, e.Budget)
+	}
+	e.rootSelectionSet = query.SelectionSet
+	if e.tracing != nil {
+		e.tracing.StartTime = time.Now()
+	}
+	return ctx, nil
+}
+
+// finish reports the field usage and ends the tracing of the execution.
+func (e *execution) finish(ctx context.Context) {
+	if e.FieldUsage != nil {
+		e.flushFieldUsage(ctx)
+	}
+
+	if e.tracing != nil {
+		e.tracing.EndTime = time.Now()
+		e.tracing.Duration = e.tracing.EndTime.Sub(e.tracing.StartTime)
+	}
+}
+
+// run executes a query by dispatches according to typ.
+func (e *execution) run(ctx context.Context, typ Type, source interface{}, query *Query) (interface{}, error) {
+	ctx, err := e.start(ctx, typ, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if query.Kind == "mutation" {
+		value, err = e.executeMutation(ctx, typ, source, query.SelectionSet)
+	} else {
+		e.mu.Lock()
+		value, err = e.execute(ctx, typ, source, query.SelectionSet)
+		e.mu.Unlock()
+	}
 
 	// Await the promise if things look good so far.
 	if err == nil {
 		value, err = await(value)
 	}
 
+	// A failed non-null root field nulls the whole result.
+	if ErrorCause(err) == errNullPropagation {
+		value, err = nil, nil
+	}
+
+	// Fields pending when ctx was done resolved to null, so the data is
+	// partial.
+	if err == nil && e.PartialResults {
+		err = ctx.Err()
+	}
+
+	e.finish(ctx)
+
 	// Maybe error wrap if we have an error and a name to attach.
 	if err != nil && query.Name != "" {
 		err = nestPathError(query.Name, err)
@@ -408,3 +1142,126 @@ func (e *Executor) Execute(ctx context.Context, typ Type, source interface{}, qu
 
 	return value, err
 }
+
+// ExecuteResult executes a query like Execute with PartialResults, and
+// returns its data alongside the errors of the fields that failed. The data is
+// nil if a failed non-null field nulled the whole result. The returned error
+// is only set if the query fails as a whole, e.g. if it exceeds MaxDepth, or
+// if ctx is canceled or times out. In the latter case, the result holds the
+// data resolved so far, with the fields that were still pending set to null.
+func (e *Executor) ExecuteResult(ctx context.Context, typ Type, source interface{}, query *Query) (*ExecutionResult, error) {
+	x := e.newExecution()
+	x.PartialResults = true
+
+	data, err := x.run(ctx, typ, source, query)
+	if err != nil && (ctx.Err() == nil || ErrorCause(err) != ctx.Err()) {
+		return nil, err
+	}
+	return x.result(data), err
+}
+
+// executeMutation executes the top-level fields of a mutation one after
+// another in the order of the query, as required by the GraphQL spec. Every
+// field, including its nested fields, completes before the next one starts.
+func (e *execution) executeMutation(ctx context.Context, typ Type, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+	object, ok := typ.(*Object)
+	if !ok {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return e.execute(ctx, typ, source, selectionSet)
+	}
+
+	fields := make(map[string]interface{})
+	for _, selection := range Flatten(selectionSet) {
+		e.mu.Lock()
+		value, err := e.executeObject(ctx, object, source, &SelectionSet{Selections: []*Selection{selection}})
+		e.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		// Await the field before starting the next one.
+		value, err = await(value)
+		if err != nil {
+			return nil, err
+		}
+		executed, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		for alias, field := range executed {
+			fields[alias] = field
+		}
+	}
+	return fields, nil
+}
+
+// checkDirectives returns an error if a field in selectionSet uses a
+// directive that isn't one of directives or can't be used on fields.
+func checkDirectives(selectionSet *SelectionSet, directives map[string]*Directive) error {
+	for _, selection := range selectionSet.Selections {
+		for _, used := range selection.Directives {
+			// @defer is applied by ExecuteIncremental, and ignored otherwise.
+			if used.Name == "defer" {
+				continue
+			}
+			directive, ok := directives[used.Name]
+			if !ok {
+				return NewClientError("unknown directive @%s", used.Name)
+			}
+			if !directive.allowedOn(DirectiveLocationField) {
+				return NewClientError("directive @%s can't be used on fields", used.Name)
+			}
+		}
+		if selection.SelectionSet != nil {
+			if err := checkDirectives(selection.SelectionSet, directives); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if err := checkDirectives(fragment.SelectionSet, directives); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countFields returns the number of fields in selectionSet, including nested
+// fields and the fields of its fragments.
+func countFields(selectionSet *SelectionSet) int {
+	count := 0
+	for _, selection := range selectionSet.Selections {
+		count++
+		if selection.SelectionSet != nil {
+			count += countFields(selection.SelectionSet)
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		count += countFields(fragment.SelectionSet)
+	}
+	return count
+}
+
+// checkDepth returns an error naming the path of the first field in
+// selectionSet, whose fields are at the given depth, that is nested deeper
+// than maxDepth. Fragments don't add to the depth of their fields.
+func checkDepth(selectionSet *SelectionSet, depth int, maxDepth int, path []string) error {
+	for _, selection := range selectionSet.Selections {
+		selectionPath := append(path[:len(path):len(path)], selection.Alias)
+		if depth > maxDepth {
+			return NewClientError("query exceeds max depth %d at %s", maxDepth, strings.Join(selectionPath, "."))
+		}
+		if selection.SelectionSet != nil {
+			if err := checkDepth(selection.SelectionSet, depth+1, maxDepth, selectionPath); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if err := checkDepth(fragment.SelectionSet, depth, maxDepth, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
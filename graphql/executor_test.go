@@ -3,9 +3,13 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/samsarahq/thunder/internal"
@@ -288,4 +292,430 @@ func TestPanic(t *testing.T) {
 	}
 }
 
+func TestPanicModes(t *testing.T) {
+	query := makeQuery(nil)
+
+	q := MustParse(`
+		{
+			static
+			panic
+		}
+	`, nil)
+
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	var recovered interface{}
+	var stack []byte
+	e := Executor{
+		Panics: PanicsFailField,
+		OnPanic: func(ctx context.Context, r interface{}, s []byte) {
+			recovered, stack = r, s
+		},
+	}
+
+	res, err := e.ExecuteResult(context.Background(), query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Data, map[string]interface{}{"static": "static", "panic": nil}) {
+		t.Errorf("unexpected value %v", res.Data)
+	}
+	if len(res.Errors) != 1 || !reflect.DeepEqual(res.Errors[0].Path, []interface{}{"panic"}) {
+		t.Errorf("expected panic error, got %v", res.Errors)
+	}
+	if recovered != "test panic" || !strings.Contains(string(stack), "executor_test.go") {
+		t.Errorf("expected OnPanic to be called, got %v", recovered)
+	}
+
+	e = Executor{Panics: PanicsPropagate}
+	defer func() {
+		if r := recover(); r != "test panic" {
+			t.Errorf("expected raw panic, got %v", r)
+		}
+	}()
+	e.Execute(context.Background(), query, nil, q)
+}
+
 // TODO: Verify caching and concurrency
+
+func TestMaxDepth(t *testing.T) {
+	query := makeQuery(nil)
+
+	q := MustParse(`{
+		static
+		a { value nested { value ...frag } }
+	}
+	fragment frag on A {
+		deep: nested { ... on A { value } }
+	}
+	`, nil)
+
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := Executor{MaxDepth: 4}
+	if _, err := e.Execute(context.Background(), query, nil, q); err != nil {
+		t.Error(err)
+	}
+
+	shallow := Executor{MaxDepth: 3}
+	_, err := shallow.Execute(context.Background(), query, nil, q)
+	if _, ok := err.(ClientError); !ok || err.Error() != "query exceeds max depth 3 at a.nested.deep.value" {
+		t.Errorf("expected max depth error, got %v", err)
+	}
+}
+
+func TestMaxFields(t *testing.T) {
+	query := makeQuery(nil)
+
+	q := MustParse(`{
+		static
+		a { value nested { value ...frag } }
+	}
+	fragment frag on A {
+		deep: nested { ... on A { value } }
+	}
+	`, nil)
+
+	e := Executor{MaxFields: 7, MaxDepth: 4}
+	if _, err := e.Execute(context.Background(), query, nil, q); err != nil {
+		t.Error(err)
+	}
+
+	small := Executor{MaxFields: 6}
+	_, err := small.Execute(context.Background(), query, nil, q)
+	if _, ok := err.(ClientError); !ok || err.Error() != "query requests 7 fields, exceeding max fields 6" {
+		t.Errorf("expected max fields error, got %v", err)
+	}
+
+	var aliases []string
+	for i := 0; i < 1000; i++ {
+		aliases = append(aliases, fmt.Sprintf("a%d: static", i))
+	}
+	q = MustParse("{ "+strings.Join(aliases, " ")+" }", nil)
+
+	_, err = small.Execute(context.Background(), query, nil, q)
+	if _, ok := err.(ClientError); !ok || err.Error() != "query requests 1000 fields, exceeding max fields 6" {
+		t.Errorf("expected max fields error, got %v", err)
+	}
+}
+
+func TestExecuteConcurrently(t *testing.T) {
+	query := makeQuery(nil)
+
+	q := MustParse(`{ static error }`, nil)
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	// Executions sharing an executor don't share their state.
+	e := Executor{CollectTracing: true, PartialResults: true}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := e.ExecuteResult(context.Background(), query, nil, q)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(res.Errors) != 1 {
+				t.Errorf("expected 1 error, got %v", res.Errors)
+			}
+			if res.Tracing == nil || len(res.Tracing.Execution.Resolvers) != 2 {
+				t.Errorf("expected 2 traced resolvers, got %v", res.Tracing)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTracing(t *testing.T) {
+	query := makeQuery(nil)
+
+	q := MustParse(`{
+		static
+		a { value nested { value } }
+		as { value }
+	}`, nil)
+
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := Executor{}
+	res, err := e.ExecuteResult(context.Background(), query, nil, q)
+	if err != nil {
+		t.Error(err)
+	}
+	if res.Tracing != nil {
+		t.Error("expected no tracing without CollectTracing")
+	}
+
+	traced := Executor{CollectTracing: true}
+	res, err = traced.ExecuteResult(context.Background(), query, nil, q)
+	if err != nil {
+		t.Error(err)
+	}
+	tracing := res.Tracing
+
+	var paths []string
+	var last time.Duration
+	for _, resolver := range tracing.Execution.Resolvers {
+		paths = append(paths, fmt.Sprint(resolver.Path))
+		if resolver.StartOffset < last {
+			t.Errorf("expected monotonic start offsets, got %s after %s", resolver.StartOffset, last)
+		}
+		last = resolver.StartOffset
+	}
+	sort.Strings(paths)
+	if !reflect.DeepEqual(paths, []string{
+		"[a nested value]", "[a nested]", "[a value]", "[a]",
+		"[as 0 value]", "[as 1 value]", "[as 2 value]", "[as 3 value]", "[as]",
+		"[static]",
+	}) {
+		t.Errorf("unexpected resolver paths %v", paths)
+	}
+
+	var resolver *ResolverTrace
+	for _, r := range tracing.Execution.Resolvers {
+		if fmt.Sprint(r.Path) == "[a value]" {
+			resolver = r
+		}
+	}
+	if resolver == nil || resolver.ParentType != "A" || resolver.FieldName != "value" || resolver.ReturnType != "int" {
+		t.Errorf("unexpected resolver trace %+v", resolver)
+	}
+	if tracing.Version != 1 || tracing.Duration != tracing.EndTime.Sub(tracing.StartTime) {
+		t.Errorf("unexpected tracing %+v", tracing)
+	}
+}
+
+func TestPartialResults(t *testing.T) {
+	query := makeQuery(nil)
+	query.Fields["safe"] = &Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+			return []int{0, 1}, nil
+		},
+		Type: &List{Type: &Object{
+			Name: "B",
+			Fields: map[string]*Field{
+				"value": {
+					Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+						if source.(int) == 1 {
+							return nil, NewSafeError("bad value")
+						}
+						return source, nil
+					},
+					Type:           &Scalar{Type: "int"},
+					ParseArguments: func(json interface{}) (interface{}, error) { return nil, nil },
+				},
+			},
+		}},
+		ParseArguments: func(json interface{}) (interface{}, error) { return nil, nil },
+	}
+
+	q := MustParse(`{
+		static
+		error
+		safe { value }
+	}`, nil)
+
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := Executor{PartialResults: true}
+	res, err := e.ExecuteResult(context.Background(), query, nil, q)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(internal.AsJSON(res.Data), internal.ParseJSON(`{
+		"static": "static",
+		"error": null,
+		"safe": [{"value": 0}, {"value": null}]
+	}`)) {
+		t.Error("bad value", spew.Sdump(internal.AsJSON(res.Data)))
+	}
+
+	errs := res.Errors
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Message < errs[j].Message })
+	if !reflect.DeepEqual(internal.AsJSON(errs), internal.ParseJSON(`[
+		{"message": "Internal server error", "path": ["error"]},
+		{"message": "bad value", "path": ["safe", 1, "value"]}
+	]`)) {
+		t.Error("bad errors", spew.Sdump(internal.AsJSON(errs)))
+	}
+}
+
+func TestPartialResultsNullPropagation(t *testing.T) {
+	noArguments := func(json interface{}) (interface{}, error) { return nil, nil }
+	fail := func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+		return nil, NewSafeError("failed")
+	}
+	value := func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+		return source, nil
+	}
+
+	user := &Object{
+		Name: "User",
+		Fields: map[string]*Field{
+			"name":  {Resolve: value, Type: &NonNull{Type: &Scalar{Type: "string"}}, ParseArguments: noArguments},
+			"email": {Resolve: fail, Type: &NonNull{Type: &Scalar{Type: "string"}}, ParseArguments: noArguments},
+			"asyncEmail": {Resolve: fail, Type: &NonNull{Type: &Scalar{Type: "string"}}, ParseArguments: noArguments,
+				Expensive: true},
+			"nickname": {Resolve: fail, Type: &Scalar{Type: "string"}, ParseArguments: noArguments},
+		},
+	}
+	item := &Object{
+		Name: "Item",
+		Fields: map[string]*Field{
+			"value": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+					if source.(int) == 1 {
+						return nil, NewSafeError("bad value")
+					}
+					return source, nil
+				},
+				Type:           &NonNull{Type: &Scalar{Type: "int"}},
+				ParseArguments: noArguments,
+			},
+		},
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"user": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+					return "alice", nil
+				},
+				Type:           user,
+				ParseArguments: noArguments,
+			},
+			"items": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+					return []int{0, 1}, nil
+				},
+				Type:           &List{Type: &NonNull{Type: item}},
+				ParseArguments: noArguments,
+			},
+			"required": {Resolve: fail, Type: &NonNull{Type: &Scalar{Type: "string"}}, ParseArguments: noArguments},
+		},
+	}
+
+	execute := func(query *Object, s string) *ExecutionResult {
+		q := MustParse(s, nil)
+		if err := PrepareQuery(query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := Executor{}
+		result, err := e.ExecuteResult(context.Background(), query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(result.Errors, func(i, j int) bool { return fmt.Sprint(result.Errors[i].Path) < fmt.Sprint(result.Errors[j].Path) })
+		return result
+	}
+
+	// Failed non-null fields null their parents, while nullable fields only null themselves.
+	result := execute(query, `{
+		nameOnly: user { name nickname }
+		user { name email }
+		asyncUser: user { name asyncEmail }
+		items { value }
+	}`)
+	if !reflect.DeepEqual(internal.AsJSON(result.Data), internal.ParseJSON(`{
+		"nameOnly": {"name": "alice", "nickname": null},
+		"user": null,
+		"asyncUser": null,
+		"items": null
+	}`)) {
+		t.Error("bad value", spew.Sdump(internal.AsJSON(result.Data)))
+	}
+	if !reflect.DeepEqual(internal.AsJSON(result.Errors), internal.ParseJSON(`[
+		{"message": "failed", "path": ["asyncUser", "asyncEmail"]},
+		{"message": "bad value", "path": ["items", 1, "value"]},
+		{"message": "failed", "path": ["nameOnly", "nickname"]},
+		{"message": "failed", "path": ["user", "email"]}
+	]`)) {
+		t.Error("bad errors", spew.Sdump(internal.AsJSON(result.Errors)))
+	}
+
+	// A failed non-null root field nulls the whole result.
+	result = execute(query, `{ user { name } required }`)
+	if result.Data != nil {
+		t.Error("expected null data", spew.Sdump(result.Data))
+	}
+	if !reflect.DeepEqual(internal.AsJSON(result.Errors), internal.ParseJSON(`[
+		{"message": "failed", "path": ["required"]}
+	]`)) {
+		t.Error("bad errors", spew.Sdump(internal.AsJSON(result.Errors)))
+	}
+}
+
+func TestExecuteCanceled(t *testing.T) {
+	noArguments := func(json interface{}) (interface{}, error) { return nil, nil }
+
+	var mu sync.Mutex
+	var called []string
+	var cancel context.CancelFunc
+	resolver := func(name string) Resolver {
+		return func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			called = append(called, name)
+			// The first field cancels the query, as if the client disconnected.
+			if name == "first" {
+				cancel()
+			}
+			return name, nil
+		}
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"first":     {Resolve: resolver("first"), Type: &Scalar{Type: "string"}, ParseArguments: noArguments},
+			"second":    {Resolve: resolver("second"), Type: &Scalar{Type: "string"}, ParseArguments: noArguments},
+			"expensive": {Resolve: resolver("expensive"), Type: &Scalar{Type: "string"}, ParseArguments: noArguments, Expensive: true},
+		},
+	}
+	q := MustParse(`{ first second expensive }`, nil)
+	if err := PrepareQuery(query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	// With partial results, the pending fields resolve to null.
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	e := Executor{}
+	result, err := e.ExecuteResult(ctx, query, nil, q)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !reflect.DeepEqual(result.Data, map[string]interface{}{"first": "first", "second": nil, "expensive": nil}) {
+		t.Errorf("bad value %v", result.Data)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("expected no field errors, got %v", result.Errors)
+	}
+	if !reflect.DeepEqual(called, []string{"first"}) {
+		t.Errorf("expected pending resolvers not to be called, called %v", called)
+	}
+
+	// Otherwise, the query fails.
+	called = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := e.Execute(ctx, query, nil, q); ErrorCause(err) != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if !reflect.DeepEqual(called, []string{"first"}) {
+		t.Errorf("expected pending resolvers not to be called, called %v", called)
+	}
+}
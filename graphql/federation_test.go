@@ -0,0 +1,196 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFederation(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	type Item struct {
+		Id    int64
+		Title string
+	}
+	users := map[int64]*User{
+		1: {Id: 1, Name: "Alice"},
+		2: {Id: 2, Name: "Bob"},
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.EnableFederation()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return users[1]
+	})
+	query.FieldFunc("item", func() Item {
+		return Item{Id: 1, Title: "Book"}
+	})
+
+	user := schema.Object("User", User{})
+	user.Key("id")
+	user.ResolveReference(func(ctx context.Context, representation map[string]interface{}) (interface{}, error) {
+		id, ok := representation["id"].(float64)
+		if !ok {
+			return nil, errors.New("missing id")
+		}
+		return users[int64(id)], nil
+	})
+
+	item := schema.Object("Item", Item{})
+	item.Key("id")
+	item.ResolveReference(func(ctx context.Context, representation map[string]interface{}) (interface{}, error) {
+		return Item{Id: int64(representation["id"].(float64)), Title: "Book"}, nil
+	})
+
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(`{
+		_entities(representations: [
+			{__typename: "User", id: 2},
+			{__typename: "Item", id: 3},
+			{__typename: "User", id: 4},
+		]) {
+			__typename
+			... on User { name }
+			... on Item { id title }
+		}
+	}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"_entities": []interface{}{
+			map[string]interface{}{"__typename": "User", "name": "Bob", "__key": int64(2)},
+			map[string]interface{}{"__typename": "Item", "id": int64(3), "title": "Book", "__key": int64(3)},
+			nil,
+		},
+	}, val)
+
+	_, err = execute(`{ _entities(representations: [{__typename: "Query"}]) { __typename } }`)
+	if err == nil || err.Error() != `error parsing args for "_entities": representations: unknown entity Query` {
+		t.Errorf("bad error: %v", err)
+	}
+
+	val, err = execute(`{ _service { sdl } }`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"_service": map[string]interface{}{
+			"sdl": `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key", "@external"])
+
+type Item @key(fields: "id") {
+  id: int64!
+  title: string!
+}
+
+type Query {
+  item: Item!
+  me: User
+}
+
+type User @key(fields: "id") {
+  id: int64!
+  name: string!
+}
+
+scalar int64
+
+scalar string
+`,
+		},
+	}, val)
+}
+
+func TestFederationEntityWithoutKey(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.EnableFederation()
+	schema.Query().FieldFunc("me", func() User { return User{} })
+	schema.Object("User", User{}).ResolveReference(func(ctx context.Context, representation map[string]interface{}) (interface{}, error) {
+		return User{}, nil
+	})
+
+	_, err := schema.Build()
+	if err == nil || err.Error() != "bad entity graphql_test.User: should have a key" {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestFederationExternalFields(t *testing.T) {
+	type User struct {
+		Id    int64
+		Email string `graphql:"email;external"`
+	}
+	type Review struct {
+		Id   int64
+		Body string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.EnableFederation()
+	query := schema.Query()
+	query.FieldFunc("reviews", func() []Review { return nil })
+	query.FieldFunc("author", func() *User { return nil })
+
+	schema.Object("User", User{}).Key("id")
+	review := schema.Object("Review", Review{})
+	review.Key("id")
+	review.ResolveReference(func(ctx context.Context, representation map[string]interface{}) (interface{}, error) {
+		return Review{Id: int64(representation["id"].(float64))}, nil
+	})
+
+	builtSchema := schema.MustBuild()
+	q := graphql.MustParse(`{ _service { sdl } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key", "@external"])
+
+type Query {
+  author: User
+  reviews: [Review!]!
+}
+
+type Review @key(fields: "id") {
+  body: string!
+  id: int64!
+}
+
+type User @key(fields: "id", resolvable: false) {
+  email: string! @external
+  id: int64!
+}
+
+scalar int64
+
+scalar string
+`, val.(map[string]interface{})["_service"].(map[string]interface{})["sdl"])
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("author", func() *User { return nil })
+	_, err = schema.Build()
+	if err == nil || !strings.Contains(err.Error(), "field email can only be external if federation is enabled") {
+		t.Errorf("bad error: %v", err)
+	}
+}
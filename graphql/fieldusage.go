@@ -0,0 +1,45 @@
+package graphql
+
+import "context"
+
+// FieldUsageSink receives the fields resolved by executions, see
+// Executor.FieldUsage.
+type FieldUsageSink interface {
+	// RecordFieldUsage is called once per execution with the number of times
+	// every field was resolved, keyed by the path of field names from the
+	// root, e.g. "users.edges.node.id". Fragments are expanded, and aliases
+	// and list indices are not part of the paths.
+	RecordFieldUsage(ctx context.Context, usage map[string]int)
+}
+
+type fieldUsagePathKey struct{}
+
+// recordFieldUsage counts the field name resolved in ctx, and returns the
+// context for resolving the field.
+func (e *execution) recordFieldUsage(ctx context.Context, name string) context.Context {
+	path := name
+	if parent, ok := ctx.Value(fieldUsagePathKey{}).(string); ok {
+		path = parent + "." + name
+	}
+
+	e.fieldUsageMu.Lock()
+	if e.fieldUsage != nil {
+		e.fieldUsage[path]++
+	}
+	e.fieldUsageMu.Unlock()
+
+	return context.WithValue(ctx, fieldUsagePathKey{}, path)
+}
+
+// flushFieldUsage passes the fields resolved by the execution to the
+// FieldUsage sink.
+func (e *execution) flushFieldUsage(ctx context.Context) {
+	e.fieldUsageMu.Lock()
+	usage := e.fieldUsage
+	e.fieldUsage = nil
+	e.fieldUsageMu.Unlock()
+
+	if usage != nil {
+		e.FieldUsage.RecordFieldUsage(ctx, usage)
+	}
+}
@@ -0,0 +1,322 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/samsarahq/thunder/batch"
+)
+
+// GraphQLWSProtocol is the websocket subprotocol spoken by GraphQLWSHandler.
+const GraphQLWSProtocol = "graphql-transport-ws"
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	graphqlWSBadRequest          = 4400
+	graphqlWSUnauthorized        = 4401
+	graphqlWSSubscriberExists    = 4409
+	graphqlWSTooManyInitRequests = 4429
+)
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type graphqlWSSubscribePayload struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphqlWSResult struct {
+	Data interface{} `json:"data"`
+}
+
+type initPayloadKey struct{}
+
+// InitPayload returns the payload of the connection_init message of a
+// GraphQLWSHandler connection, e.g. to authenticate the connection's
+// operations. It returns nil outside of such a connection.
+func InitPayload(ctx context.Context) map[string]interface{} {
+	payload, _ := ctx.Value(initPayloadKey{}).(map[string]interface{})
+	return payload
+}
+
+// GraphQLWSHandler returns a websocket handler speaking the
+// graphql-transport-ws protocol. Subscriptions are run with
+// Executor.Subscribe against the schema's Subscription root and send a next
+// message for every result, followed by complete once the stream of a Stream
+// field is closed; queries and mutations are run with
+// Executor.ExecuteIncremental and send a next message with their result and
+// with every field deferred with @defer, followed by complete.
+func GraphQLWSHandler(schema *Schema) http.Handler {
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{GraphQLWSProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		socket, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("upgrader.Upgrade: %v", err)
+			return
+		}
+		defer socket.Close()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		c := &graphqlWSConn{
+			socket:        socket,
+			schema:        schema,
+			ctx:           ctx,
+			subscriptions: make(map[string]context.CancelFunc),
+		}
+		c.serve()
+	})
+}
+
+type graphqlWSConn struct {
+	writeMu sync.Mutex
+	socket  *websocket.Conn
+
+	schema *Schema
+	ctx    context.Context
+
+	mu            sync.Mutex
+	initialized   bool
+	subscriptions map[string]context.CancelFunc
+}
+
+func (c *graphqlWSConn) serve() {
+	defer c.closeSubscriptions()
+
+	for {
+		var message graphqlWSMessage
+		if err := c.socket.ReadJSON(&message); err != nil {
+			if !isCloseError(err) {
+				log.Println("socket.ReadJSON:", err)
+			}
+			return
+		}
+
+		if code, reason := c.handle(&message); code != 0 {
+			c.close(code, reason)
+			return
+		}
+	}
+}
+
+// handle handles a single message. It returns a close code and reason if the
+// message violates the protocol.
+func (c *graphqlWSConn) handle(message *graphqlWSMessage) (int, string) {
+	switch message.Type {
+	case "connection_init":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.initialized {
+			return graphqlWSTooManyInitRequests, "Too many initialisation requests"
+		}
+
+		var payload map[string]interface{}
+		if len(message.Payload) > 0 {
+			if err := json.Unmarshal(message.Payload, &payload); err != nil {
+				return graphqlWSBadRequest, "Invalid connection_init payload"
+			}
+		}
+		c.ctx = context.WithValue(c.ctx, initPayloadKey{}, payload)
+		c.initialized = true
+		c.write(graphqlWSMessage{Type: "connection_ack"})
+		return 0, ""
+
+	case "ping":
+		c.write(graphqlWSMessage{Type: "pong", Payload: message.Payload})
+		return 0, ""
+
+	case "pong":
+		return 0, ""
+
+	case "subscribe":
+		return c.handleSubscribe(message)
+
+	case "complete":
+		c.finish(message.ID)
+		return 0, ""
+
+	default:
+		return graphqlWSBadRequest, fmt.Sprintf("Unknown message type %q", message.Type)
+	}
+}
+
+func (c *graphqlWSConn) handleSubscribe(message *graphqlWSMessage) (int, string) {
+	var payload graphqlWSSubscribePayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil || message.ID == "" {
+		return graphqlWSBadRequest, "Invalid subscribe message"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.initialized {
+		return graphqlWSUnauthorized, "Unauthorized"
+	}
+	if _, ok := c.subscriptions[message.ID]; ok {
+		return graphqlWSSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", message.ID)
+	}
+
+	query, err := Parse(payload.Query, payload.Variables)
+	if err != nil {
+		c.writeError(message.ID, err)
+		return 0, ""
+	}
+
+	var typ Type
+	switch query.Kind {
+	case "subscription":
+		typ = c.schema.Subscription
+		if typ == nil {
+			c.writeError(message.ID, NewClientError("schema has no subscriptions"))
+			return 0, ""
+		}
+	case "mutation":
+		typ = c.schema.Mutation
+	default:
+		typ = c.schema.Query
+	}
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		c.writeError(message.ID, err)
+		return 0, ""
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.subscriptions[message.ID] = cancel
+	go c.run(ctx, message.ID, typ, query)
+	return 0, ""
+}
+
+// run executes an operation and writes its results until it completes or is
+// canceled.
+func (c *graphqlWSConn) run(ctx context.Context, id string, typ Type, query *Query) {
+	defer c.finish(id)
+
+	e := Executor{Directives: c.schema.Directives}
+	if query.Kind != "subscription" {
+		patches, err := e.ExecuteIncremental(batch.WithBatching(ctx), typ, query)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.writeError(id, err)
+			}
+			return
+		}
+		for patch := range patches {
+			if patch.Err != nil {
+				c.writeError(id, patch.Err)
+				return
+			}
+			c.writePatch(id, patch)
+		}
+		if ctx.Err() == nil {
+			c.write(graphqlWSMessage{ID: id, Type: "complete"})
+		}
+		return
+	}
+
+	results, err := e.Subscribe(ctx, typ, query)
+	if err != nil {
+		c.writeError(id, err)
+		return
+	}
+	failed := false
+	for result := range results {
+		if result.Err != nil {
+			c.writeError(id, result.Err)
+			failed = true
+			continue
+		}
+		c.writeResult(id, result.Data)
+	}
+	// The results of subscriptions to stream fields end without an error
+	// once the stream is closed.
+	if !failed && ctx.Err() == nil {
+		c.write(graphqlWSMessage{ID: id, Type: "complete"})
+	}
+}
+
+// finish cancels the operation with the given id, if it is still running.
+func (c *graphqlWSConn) finish(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.subscriptions[id]; ok {
+		cancel()
+		delete(c.subscriptions, id)
+	}
+}
+
+func (c *graphqlWSConn) closeSubscriptions() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, cancel := range c.subscriptions {
+		cancel()
+		delete(c.subscriptions, id)
+	}
+}
+
+func (c *graphqlWSConn) writeResult(id string, data interface{}) {
+	payload, err := json.Marshal(graphqlWSResult{Data: data})
+	if err != nil {
+		c.writeError(id, err)
+		return
+	}
+	c.write(graphqlWSMessage{ID: id, Type: "next", Payload: payload})
+}
+
+// writePatch writes a patch of a query with deferred fields, or the plain
+// result of a query without.
+func (c *graphqlWSConn) writePatch(id string, patch *Patch) {
+	if patch.Path == nil && !patch.HasNext {
+		c.writeResult(id, patch.Data)
+		return
+	}
+
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		c.writeError(id, err)
+		return
+	}
+	c.write(graphqlWSMessage{ID: id, Type: "next", Payload: payload})
+}
+
+func (c *graphqlWSConn) writeError(id string, err error) {
+	payload, _ := json.Marshal([]*errorMessage{newErrorMessage(err)})
+	c.write(graphqlWSMessage{ID: id, Type: "error", Payload: payload})
+}
+
+func (c *graphqlWSConn) write(message graphqlWSMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.socket.WriteJSON(message); err != nil {
+		if !isCloseError(err) {
+			c.socket.Close()
+			log.Printf("socket.WriteJSON: %s\n", err)
+		}
+	}
+}
+
+func (c *graphqlWSConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.socket.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
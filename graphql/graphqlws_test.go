@@ -0,0 +1,163 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+)
+
+type graphqlWSTestMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func TestGraphQLWSHandler(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("ok", func() bool { return true })
+	schema.Mutation()
+	schema.Subscription().FieldFunc("user", func(ctx context.Context) string {
+		user, _ := graphql.InitPayload(ctx)["user"].(string)
+		return user
+	})
+
+	server := httptest.NewServer(graphql.GraphQLWSHandler(schema.MustBuild()))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphql.GraphQLWSProtocol}}
+	socket, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+	assert.Equal(t, graphql.GraphQLWSProtocol, resp.Header.Get("Sec-Websocket-Protocol"))
+
+	receive := func() graphqlWSTestMessage {
+		socket.SetReadDeadline(time.Now().Add(time.Second))
+		var message graphqlWSTestMessage
+		if err := socket.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{Type: "connection_init", Payload: json.RawMessage(`{"user": "alice"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "connection_ack", receive().Type)
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{Type: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pong", receive().Type)
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { user }"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	next := receive()
+	assert.Equal(t, "1", next.ID)
+	assert.Equal(t, "next", next.Type)
+	assert.JSONEq(t, `{"data": {"user": "alice"}}`, string(next.Payload))
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "1", Type: "complete"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries complete after their result.
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "2", Type: "subscribe", Payload: json.RawMessage(`{"query": "{ ok }"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	next = receive()
+	assert.Equal(t, "next", next.Type)
+	assert.JSONEq(t, `{"data": {"ok": true}}`, string(next.Payload))
+	assert.Equal(t, graphqlWSTestMessage{ID: "2", Type: "complete"}, receive())
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "3", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { missing }"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	failed := receive()
+	assert.Equal(t, "error", failed.Type)
+	assert.JSONEq(t, `[{"message": "unknown field \"missing\""}]`, string(failed.Payload))
+}
+
+func TestGraphQLWSHandlerUnauthorized(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	schema.Mutation()
+
+	server := httptest.NewServer(graphql.GraphQLWSHandler(schema.MustBuild()))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphql.GraphQLWSProtocol}}
+	socket, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query": "{ __typename }"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	socket.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = socket.ReadMessage()
+	if !websocket.IsCloseError(err, 4401) {
+		t.Errorf("expected close 4401, got %v", err)
+	}
+}
+
+func TestGraphQLWSHandlerStream(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	schema.Mutation()
+	schema.Subscription().FieldFunc("numbers", func() <-chan int64 {
+		numbers := make(chan int64, 2)
+		numbers <- 1
+		numbers <- 2
+		close(numbers)
+		return numbers
+	})
+
+	server := httptest.NewServer(graphql.GraphQLWSHandler(schema.MustBuild()))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphql.GraphQLWSProtocol}}
+	socket, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+
+	receive := func() graphqlWSTestMessage {
+		socket.SetReadDeadline(time.Now().Add(time.Second))
+		var message graphqlWSTestMessage
+		if err := socket.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+
+	if err := socket.WriteJSON(graphqlWSTestMessage{Type: "connection_init"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "connection_ack", receive().Type)
+
+	// Subscriptions to streams complete once the stream is closed.
+	if err := socket.WriteJSON(graphqlWSTestMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { numbers }"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`{"data": {"numbers": 1}}`, `{"data": {"numbers": 2}}`} {
+		next := receive()
+		assert.Equal(t, "next", next.Type)
+		assert.JSONEq(t, expected, string(next.Payload))
+	}
+	assert.Equal(t, graphqlWSTestMessage{ID: "1", Type: "complete"}, receive())
+}
@@ -3,41 +3,106 @@ package graphql
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"io"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/reactive"
 )
 
+// HTTPHandler returns a handler executing the queries POSTed to it as JSON,
+// running middlewares around the execution of every query.
 func HTTPHandler(schema *Schema, middlewares ...MiddlewareFunc) http.Handler {
-	return &httpHandler{
-		schema:      schema,
-		middlewares: middlewares,
+	return NewHTTPHandler(schema, WithHTTPMiddlewares(middlewares...))
+}
+
+// NewHTTPHandler returns a handler like HTTPHandler, configured with options,
+// e.g.
+//    graphql.NewHTTPHandler(schema, graphql.WithHTTPPersistedQueries(store))
+func NewHTTPHandler(schema *Schema, options ...HTTPHandlerOption) http.Handler {
+	h := &httpHandler{schema: schema}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// An HTTPHandlerOption configures the handler returned by NewHTTPHandler.
+type HTTPHandlerOption func(*httpHandler)
+
+// WithHTTPMiddlewares runs middlewares around the execution of every query.
+func WithHTTPMiddlewares(middlewares ...MiddlewareFunc) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.middlewares = append(h.middlewares, middlewares...)
+	}
+}
+
+// WithHTTPPersistedQueries supports automatic persisted queries: requests may
+// send the SHA-256 hash of a query in their extensions instead of the query,
+// and queries are saved in store when sent along with their hash.
+func WithHTTPPersistedQueries(store PersistedQueryStore) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.persistedQueries = store
+	}
+}
+
+// WithHTTPResponseCache caches the responses of queries whose top-level fields
+// all have a CacheTTL in cache. Responses are cached by query, variables and
+// the scope set with WithResponseCacheScope on the context, which middlewares
+// may set. Cached responses are looked up after the middlewares ran, and
+// returned without executing the query; responses with errors are not cached.
+func WithHTTPResponseCache(cache ResponseCache) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.responseCache = cache
 	}
 }
 
 type httpHandler struct {
-	schema      *Schema
-	middlewares []MiddlewareFunc
+	schema           *Schema
+	middlewares      []MiddlewareFunc
+	persistedQueries PersistedQueryStore
+	responseCache    ResponseCache
+	// maxUploadSize is set if multipart requests with uploads are accepted.
+	maxUploadSize int64
 }
 
 type httpPostBody struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables"`
+	Extensions map[string]interface{} `json:"extensions"`
 }
 
 type httpResponse struct {
-	Data   interface{} `json:"data"`
-	Errors []string    `json:"errors"`
+	Data   interface{}     `json:"data"`
+	Errors []*errorMessage `json:"errors"`
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// cacheKey is set if the response is cacheable and wasn't cached yet.
+	var cacheKey string
+	var cacheTTL time.Duration
+	var cacheHint *CacheHint
+
 	writeResponse := func(value interface{}, err error) {
 		response := httpResponse{}
 		if err != nil {
-			response.Errors = []string{err.Error()}
+			response.Errors = []*errorMessage{newErrorMessage(err)}
+		} else if cacheKey != "" {
+			data, err := json.Marshal(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cached, err := json.Marshal(&cachedResponse{Data: data, CacheHint: cacheHint, Time: time.Now()})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			h.responseCache.Set(cacheKey, cached, cacheTTL)
+			response.Data = json.RawMessage(data)
 		} else {
 			response.Data = value
 		}
@@ -52,17 +117,40 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != "POST" {
-		writeResponse(nil, errors.New("request must be a POST"))
+		writeResponse(nil, NewClientError("request must be a POST"))
 		return
 	}
 
 	if r.Body == nil {
-		writeResponse(nil, errors.New("request must include a query"))
+		writeResponse(nil, NewClientError("request must include a query"))
 		return
 	}
 
 	var params httpPostBody
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+	var err error
+	if h.maxUploadSize > 0 && isMultipartRequest(r) {
+		var files []io.Closer
+		files, err = decodeMultipartBody(w, r, h.maxUploadSize, &params)
+		defer func() {
+			for _, file := range files {
+				file.Close()
+			}
+			if r.MultipartForm != nil {
+				r.MultipartForm.RemoveAll()
+			}
+		}()
+	} else {
+		if err = json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err = NewClientError("bad request: %s", err.Error())
+		}
+	}
+	if err != nil {
+		writeResponse(nil, err)
+		return
+	}
+
+	params.Query, err = resolvePersistedQuery(h.persistedQueries, params.Query, params.Extensions)
+	if err != nil {
 		writeResponse(nil, err)
 		return
 	}
@@ -82,8 +170,14 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.responseCache != nil {
+		cacheTTL = responseCacheTTL(schema, query)
+	}
+
 	var wg sync.WaitGroup
-	e := Executor{}
+	e := Executor{Directives: h.schema.Directives}
+	// cachedAt is set if the response is served from the response cache.
+	var cachedAt time.Time
 
 	wg.Add(1)
 	runner := reactive.NewRerunner(r.Context(), func(ctx context.Context) (interface{}, error) {
@@ -95,7 +189,33 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		middlewares = append(middlewares, h.middlewares...)
 		middlewares = append(middlewares, func(input *ComputationInput, next MiddlewareNextFunc) *ComputationOutput {
 			output := next(input)
-			output.Current, output.Error = e.Execute(input.Ctx, schema, nil, input.ParsedQuery)
+			output.cost = lazyQueryCost(input.Ctx, schema, input.ParsedQuery.SelectionSet)
+
+			// The cache is checked after the other middlewares, which may
+			// reject the request or set its cache scope.
+			if cacheTTL > 0 {
+				key, err := responseCacheKey(input.Ctx, input.Query, input.Variables)
+				if err != nil {
+					output.Error = err
+					return output
+				}
+				if data, ok := h.responseCache.Get(key); ok {
+					var cached cachedResponse
+					if err := json.Unmarshal(data, &cached); err != nil {
+						output.Error = err
+						return output
+					}
+					output.Current = cached.Data
+					cacheHint, cachedAt = cached.CacheHint, cached.Time
+					return output
+				}
+				cacheKey = key
+			}
+
+			x := e.newExecution()
+			x.cost = output.cost
+			output.Current, output.Error = x.run(input.Ctx, schema, nil, input.ParsedQuery)
+			cacheHint = x.cacheHints.get()
 			return output
 		})
 
@@ -116,6 +236,13 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return nil, err
 		}
 
+		if cacheHint != nil && query.Kind != "mutation" {
+			w.Header().Set("Cache-Control", cacheHint.CacheControl())
+			// Cached responses tell how long ago their hint was set.
+			if !cachedAt.IsZero() {
+				w.Header().Set("Age", strconv.FormatInt(int64(time.Since(cachedAt)/time.Second), 10))
+			}
+		}
 		writeResponse(current, nil)
 		return nil, nil
 	}, DefaultMinRerunInterval)
@@ -1,12 +1,21 @@
 package graphql_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
@@ -41,7 +50,7 @@ func TestHTTPMustPost(t *testing.T) {
 		t.Errorf("expected 200, but received %d", rr.Code)
 	}
 
-	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[\"request must be a POST\"]}\n"); diff != "" {
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[{\"message\":\"request must be a POST\"}]}\n"); diff != "" {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
@@ -58,7 +67,7 @@ func TestHTTPParseQuery(t *testing.T) {
 		t.Errorf("expected 200, but received %d", rr.Code)
 	}
 
-	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[\"request must include a query\"]}\n"); diff != "" {
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[{\"message\":\"request must include a query\"}]}\n"); diff != "" {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
@@ -75,7 +84,7 @@ func TestHTTPMustHaveQuery(t *testing.T) {
 		t.Errorf("expected 200, but received %d", rr.Code)
 	}
 
-	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[\"must have a single query\"]}\n"); diff != "" {
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":null,\"errors\":[{\"message\":\"must have a single query\"}]}\n"); diff != "" {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
@@ -96,3 +105,408 @@ func TestHTTPSuccess(t *testing.T) {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
+
+func TestHTTPErrors(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("internal", func() (int64, error) {
+		return 0, errors.New("database password is hunter2")
+	})
+	query.FieldFunc("coded", func() (int64, error) {
+		return 0, graphql.NewCodedError("NOT_FOUND", "not found")
+	})
+	handler := graphql.HTTPHandler(schema.MustBuild())
+
+	send := func(body string) string {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Body.String()
+	}
+
+	if diff := pretty.Compare(send(`{"query": "{ internal }"}`), "{\"data\":null,\"errors\":[{\"message\":\"Internal server error\"}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(send(`{"query": "{ coded }"}`), "{\"data\":null,\"errors\":[{\"message\":\"not found\",\"extensions\":{\"code\":\"NOT_FOUND\"}}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(send(`{"query": `), "{\"data\":null,\"errors\":[{\"message\":\"bad request: unexpected EOF\"}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPQueryCost(t *testing.T) {
+	type Inner struct {
+		Value int64
+	}
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("inner", func() Inner { return Inner{} })
+	builtSchema := schema.MustBuild()
+
+	var multiplied int
+	builtSchema.Query.(*graphql.Object).Fields["inner"].CostMultiplier = func(ctx context.Context, args interface{}) int {
+		multiplied++
+		return 3
+	}
+
+	var costs []int
+	send := func(middleware graphql.MiddlewareFunc) {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ inner { value } }"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		graphql.HTTPHandler(builtSchema, middleware).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The cost is only computed when it is needed.
+	send(func(input *graphql.ComputationInput, next graphql.MiddlewareNextFunc) *graphql.ComputationOutput {
+		return next(input)
+	})
+	assert.Equal(t, 0, multiplied)
+
+	send(func(input *graphql.ComputationInput, next graphql.MiddlewareNextFunc) *graphql.ComputationOutput {
+		output := next(input)
+		costs = append(costs, output.Cost(), output.Cost())
+		return output
+	})
+	assert.Equal(t, []int{4, 4}, costs)
+	assert.Equal(t, 1, multiplied)
+}
+
+func TestHTTPPersistedQuery(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	handler := graphql.NewHTTPHandler(schema.MustBuild(), graphql.WithHTTPPersistedQueries(graphql.NewMemoryPersistedQueryStore(100)))
+
+	queryText := "{ mirror(value: 2) }"
+	sum := sha256.Sum256([]byte(queryText))
+	extensions := fmt.Sprintf(`{"persistedQuery": {"version": 1, "sha256Hash": "%s"}}`, hex.EncodeToString(sum[:]))
+
+	send := func(body string) string {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Body.String()
+	}
+
+	if diff := pretty.Compare(send(`{"extensions": `+extensions+`}`), "{\"data\":null,\"errors\":[{\"message\":\"PersistedQueryNotFound\",\"extensions\":{\"code\":\"PERSISTED_QUERY_NOT_FOUND\"}}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(send(`{"query": "{ mirror(value: 3) }", "extensions": `+extensions+`}`), "{\"data\":null,\"errors\":[{\"message\":\"provided sha256Hash does not match query\"}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(send(`{"query": "`+queryText+`", "extensions": `+extensions+`}`), "{\"data\":{\"mirror\":-2},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(send(`{"extensions": `+extensions+`}`), "{\"data\":{\"mirror\":-2},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestMemoryPersistedQueryStoreSize(t *testing.T) {
+	store := graphql.NewMemoryPersistedQueryStore(2)
+	store.Set("a", "{ a }")
+	store.Set("b", "{ b }")
+	store.Get("a")
+	store.Set("c", "{ c }")
+
+	if _, ok := store.Get("b"); ok {
+		t.Error("expected least recently used query to be evicted")
+	}
+	for hash, expected := range map[string]string{"a": "{ a }", "c": "{ c }"} {
+		if query, ok := store.Get(hash); !ok || query != expected {
+			t.Errorf("expected query %s, got %q", expected, query)
+		}
+	}
+}
+
+type recordingResponseCache struct {
+	graphql.ResponseCache
+	ttls []time.Duration
+}
+
+func (c *recordingResponseCache) Set(key string, response []byte, ttl time.Duration) {
+	c.ttls = append(c.ttls, ttl)
+	c.ResponseCache.Set(key, response, ttl)
+}
+
+type bannedKey struct{}
+type tenantKey struct{}
+
+func TestHTTPResponseCache(t *testing.T) {
+	calls := 0
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		calls++
+		return args.Value * -1
+	}, schemabuilder.CacheResponse(time.Minute))
+	query.FieldFunc("short", func() int64 {
+		calls++
+		return 0
+	}, schemabuilder.CacheResponse(time.Second))
+	query.FieldFunc("uncached", func() int64 {
+		calls++
+		return 0
+	})
+	cache := &recordingResponseCache{ResponseCache: graphql.NewMemoryResponseCache(100)}
+	handler := graphql.NewHTTPHandler(schema.MustBuild(), graphql.WithHTTPResponseCache(cache), graphql.WithHTTPMiddlewares(func(input *graphql.ComputationInput, next graphql.MiddlewareNextFunc) *graphql.ComputationOutput {
+		if input.Ctx.Value(bannedKey{}) != nil {
+			return &graphql.ComputationOutput{Error: graphql.NewClientError("banned")}
+		}
+		if tenant, ok := input.Ctx.Value(tenantKey{}).(string); ok {
+			input.Ctx = graphql.WithResponseCacheScope(input.Ctx, tenant)
+		}
+		return next(input)
+	}))
+
+	send := func(ctx context.Context, body string) string {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req.WithContext(ctx))
+		return rr.Body.String()
+	}
+
+	ctx := context.Background()
+	body := `{"query": "query Q($value: int64) { mirror(value: $value) }", "variables": {"value": 1}}`
+	for i := 0; i < 2; i++ {
+		if diff := pretty.Compare(send(ctx, body), "{\"data\":{\"mirror\":-1},\"errors\":null}\n"); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second query to hit the cache, but resolved %d times", calls)
+	}
+
+	// Middlewares run before cached responses are returned.
+	if diff := pretty.Compare(send(context.WithValue(ctx, bannedKey{}, true), body), "{\"data\":null,\"errors\":[{\"message\":\"banned\"}]}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// Different variables and scopes are cached separately.
+	if diff := pretty.Compare(send(ctx, `{"query": "query Q($value: int64) { mirror(value: $value) }", "variables": {"value": 2}}`), "{\"data\":{\"mirror\":-2},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	send(context.WithValue(ctx, tenantKey{}, "a"), body)
+	send(context.WithValue(ctx, tenantKey{}, "a"), body)
+	if calls != 3 {
+		t.Errorf("expected 3 resolves, but resolved %d times", calls)
+	}
+
+	// Queries are cached for the shortest TTL of their fields, unless a field isn't cacheable.
+	send(ctx, `{"query": "{ mirror(value: 1) short }"}`)
+	send(ctx, `{"query": "{ mirror(value: 1) uncached }"}`)
+	send(ctx, `{"query": "{ mirror(value: 1) uncached }"}`)
+	if calls != 9 {
+		t.Errorf("expected 9 resolves, but resolved %d times", calls)
+	}
+	if diff := pretty.Compare(cache.ttls, []time.Duration{time.Minute, time.Minute, time.Minute, time.Second}); diff != "" {
+		t.Errorf("expected ttls to match, but received %s", diff)
+	}
+}
+
+func TestMemoryResponseCacheTTL(t *testing.T) {
+	cache := graphql.NewMemoryResponseCache(10)
+	cache.Set("short", []byte("a"), time.Millisecond)
+	cache.Set("long", []byte("b"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := cache.Get("short"); ok {
+		t.Error("expected short response to expire")
+	}
+	if response, ok := cache.Get("long"); !ok || string(response) != "b" {
+		t.Errorf("expected long response, got %q", response)
+	}
+}
+
+func TestMemoryResponseCacheSize(t *testing.T) {
+	cache := graphql.NewMemoryResponseCache(2)
+	cache.Set("a", []byte("a"), time.Hour)
+	cache.Set("b", []byte("b"), time.Hour)
+	cache.Get("a")
+	cache.Set("c", []byte("c"), time.Hour)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected least recently used response to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if response, ok := cache.Get(key); !ok || string(response) != key {
+			t.Errorf("expected response %s, got %q", key, response)
+		}
+	}
+}
+
+func TestHTTPCacheHints(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	catalogCalls := 0
+	query.FieldFunc("catalog", func(ctx context.Context) string {
+		catalogCalls++
+		graphql.SetCacheHint(ctx, time.Minute, graphql.CacheScopePublic)
+		return "catalog"
+	}, schemabuilder.CacheResponse(time.Minute))
+	query.FieldFunc("cart", func(ctx context.Context) string {
+		graphql.SetCacheHint(ctx, 10*time.Second, graphql.CacheScopePrivate)
+		return "cart"
+	})
+	query.FieldFunc("uncached", func() string {
+		return "uncached"
+	})
+	builtSchema := schema.MustBuild()
+	handler := graphql.HTTPHandler(builtSchema)
+
+	sendTo := func(handler http.Handler, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	send := func(body string) *httptest.ResponseRecorder {
+		return sendTo(handler, body)
+	}
+
+	// The hints of all fields aggregate into the shortest max age and the most private scope.
+	rr := send(`{"query": "{ catalog cart }"}`)
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":{\"cart\":\"cart\",\"catalog\":\"catalog\"},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if header := rr.Header().Get("Cache-Control"); header != "max-age=10, private" {
+		t.Errorf("expected aggregated cache hint, got %q", header)
+	}
+
+	if header := send(`{"query": "{ catalog }"}`).Header().Get("Cache-Control"); header != "max-age=60, public" {
+		t.Errorf("expected public cache hint, got %q", header)
+	}
+	// A field without a hint, e.g. returning user data, makes the whole
+	// response uncacheable.
+	if header := send(`{"query": "{ catalog uncached }"}`).Header().Get("Cache-Control"); header != "" {
+		t.Errorf("expected no cache hint, got %q", header)
+	}
+	if header := send(`{"query": "{ uncached }"}`).Header().Get("Cache-Control"); header != "" {
+		t.Errorf("expected no cache hint, got %q", header)
+	}
+
+	// Responses served from the response cache keep their hint.
+	cachedHandler := graphql.NewHTTPHandler(builtSchema, graphql.WithHTTPResponseCache(graphql.NewMemoryResponseCache(10)))
+	catalogCalls = 0
+	for i := 0; i < 2; i++ {
+		rr := sendTo(cachedHandler, `{"query": "{ catalog }"}`)
+		if header := rr.Header().Get("Cache-Control"); header != "max-age=60, public" {
+			t.Errorf("expected public cache hint, got %q", header)
+		}
+		if i == 1 && rr.Header().Get("Age") != "0" {
+			t.Errorf("expected the age of the cached response, got %q", rr.Header().Get("Age"))
+		}
+	}
+	if catalogCalls != 1 {
+		t.Errorf("expected the second query to hit the cache, but resolved %d times", catalogCalls)
+	}
+}
+
+func TestHTTPUploads(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("ping", func() string { return "pong" })
+	mutation := schema.Mutation()
+	mutation.FieldFunc("upload", func(args struct {
+		File  graphql.Upload
+		Notes []graphql.Upload
+	}) (string, error) {
+		data, err := ioutil.ReadAll(args.File.File)
+		if err != nil {
+			return "", err
+		}
+		result := fmt.Sprintf("%s (%s, %d bytes): %s", args.File.Filename, args.File.ContentType, args.File.Size, data)
+		for _, note := range args.Notes {
+			data, err := ioutil.ReadAll(note.File)
+			if err != nil {
+				return "", err
+			}
+			result += fmt.Sprintf("; %s: %s", note.Filename, data)
+		}
+		return result, nil
+	})
+	builtSchema := schema.MustBuild()
+	handler := graphql.NewHTTPHandler(builtSchema, graphql.WithHTTPUploads(1<<20))
+
+	send := func(handler http.Handler, operations, fileMap string, files map[string]string) string {
+		var body strings.Builder
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("operations", operations)
+		writer.WriteField("map", fileMap)
+		for name, content := range files {
+			part, err := writer.CreateFormFile(name, name+".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(content))
+		}
+		writer.Close()
+
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Body.String()
+	}
+
+	response := send(handler,
+		`{"query": "mutation($file: Upload!, $notes: [Upload!]!) { upload(file: $file, notes: $notes) }", "variables": {"file": null, "notes": [null]}}`,
+		`{"0": ["variables.file"], "1": ["variables.notes.0"]}`,
+		map[string]string{"0": "hello", "1": "world"},
+	)
+	if diff := pretty.Compare(response, "{\"data\":{\"upload\":\"0.txt (application/octet-stream, 5 bytes): hello; 1.txt: world\"},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// A file mapped to several variables can be read by each of them.
+	response = send(handler,
+		`{"query": "mutation($file: Upload!, $notes: [Upload!]!) { upload(file: $file, notes: $notes) }", "variables": {"file": null, "notes": [null]}}`,
+		`{"0": ["variables.file", "variables.notes.0"]}`,
+		map[string]string{"0": "hello"},
+	)
+	if diff := pretty.Compare(response, "{\"data\":{\"upload\":\"0.txt (application/octet-stream, 5 bytes): hello; 0.txt: hello\"},\"errors\":null}\n"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	response = send(handler,
+		`{"query": "mutation($file: Upload!) { upload(file: $file, notes: []) }", "variables": {"file": null}}`,
+		`{"0": ["variables.missing.0"]}`,
+		map[string]string{"0": "hello"},
+	)
+	if !strings.Contains(response, "bad file path variables.missing.0") {
+		t.Errorf("expected bad file path error, got %s", response)
+	}
+
+	// Uploads can't be sent as JSON.
+	response = send(handler, `{"query": "mutation { upload(file: \"hello\", notes: []) }"}`, `{}`, nil)
+	if !strings.Contains(response, "not an upload") {
+		t.Errorf("expected upload error, got %s", response)
+	}
+
+	response = send(graphql.NewHTTPHandler(builtSchema, graphql.WithHTTPUploads(1024)),
+		`{"query": "mutation($file: Upload!) { upload(file: $file, notes: []) }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`,
+		map[string]string{"0": strings.Repeat("a", 2048)},
+	)
+	if !strings.Contains(response, "bad multipart request: http: request body too large") {
+		t.Errorf("expected request too large error, got %s", response)
+	}
+}
@@ -0,0 +1,187 @@
+package graphql
+
+import (
+	"context"
+)
+
+// A Patch is a part of the result of a query executed by
+// Executor.ExecuteIncremental.
+type Patch struct {
+	// Data is the initial result of the query for the first patch, and the
+	// fields of a deferred selection for later patches.
+	Data interface{} `json:"data"`
+
+	// Path is the path of the object the deferred fields of Data belong to,
+	// and nil for the first patch.
+	Path []interface{} `json:"path,omitempty"`
+
+	// HasNext is true if more patches follow.
+	HasNext bool `json:"hasNext"`
+
+	// Err is the error of a failed execution. No patches follow it.
+	Err error `json:"-"`
+}
+
+// deferredField is a field marked with @defer, whose execution is postponed
+// until after the initial result of an incremental execution.
+type deferredField struct {
+	ctx       context.Context
+	path      []interface{}
+	typ       *Object
+	source    interface{}
+	selection *Selection
+}
+
+// deferred returns whether the selection is marked with @defer, and isn't
+// disabled with @defer(if: false).
+func (s *Selection) deferred() bool {
+	for _, directive := range s.Directives {
+		if directive.Name != "defer" {
+			continue
+		}
+		if condition, ok := directive.Args["if"].(bool); ok && !condition {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// deferField postpones the execution of a field of source until after the
+// initial result.
+func (e *execution) deferField(ctx context.Context, typ *Object, source interface{}, selection *Selection) {
+	path := []interface{}{}
+	if traceCtx, ok := ctx.Value(traceContextKey{}).(*traceContext); ok {
+		path = append(path, traceCtx.path...)
+	}
+
+	e.deferredMu.Lock()
+	defer e.deferredMu.Unlock()
+	e.deferred = append(e.deferred, &deferredField{
+		ctx:       ctx,
+		path:      path,
+		typ:       typ,
+		source:    source,
+		selection: selection,
+	})
+}
+
+// takeDeferred returns and clears the fields deferred so far.
+func (e *execution) takeDeferred() []*deferredField {
+	e.deferredMu.Lock()
+	defer e.deferredMu.Unlock()
+	deferred := e.deferred
+	e.deferred = nil
+	return deferred
+}
+
+// ExecuteIncremental executes query on typ like Execute, but postpones fields
+// marked with @defer. The initial result, without the deferred fields, is sent
+// as the first patch on the returned channel. The deferred fields are then
+// executed concurrently, and a patch is sent for every deferred field as soon
+// as it completes, so patches can arrive in any order. Deferred fields can
+// contain further deferred fields.
+//
+// The channel is closed after the last patch, or when ctx is canceled.
+func (e *Executor) ExecuteIncremental(ctx context.Context, typ Type, query *Query) (<-chan *Patch, error) {
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		return nil, err
+	}
+
+	x := e.newExecution()
+	x.incremental = true
+	initial, err := x.run(ctx, typ, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	pending := x.takeDeferred()
+
+	patches := make(chan *Patch)
+	send := func(patch *Patch) bool {
+		select {
+		case patches <- patch:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(patches)
+
+		if !send(&Patch{Data: initial, HasNext: len(pending) > 0}) {
+			return
+		}
+
+		type completedField struct {
+			field *deferredField
+			data  interface{}
+			err   error
+		}
+		completed := make(chan completedField)
+		// stop releases the fields still executing once no more patches are
+		// sent.
+		stop := make(chan struct{})
+		defer close(stop)
+
+		running := 0
+		start := func(fields []*deferredField) {
+			for _, field := range fields {
+				running++
+				go func(field *deferredField) {
+					data, err := x.executeDeferred(field)
+					select {
+					case completed <- completedField{field: field, data: data, err: err}:
+					case <-stop:
+					}
+				}(field)
+			}
+		}
+		start(pending)
+
+		for running > 0 {
+			var c completedField
+			select {
+			case c = <-completed:
+			case <-ctx.Done():
+				return
+			}
+			running--
+
+			// Start the fields deferred within the completed field.
+			start(x.takeDeferred())
+
+			if c.err != nil {
+				send(&Patch{Path: c.field.path, Err: nestPathError(c.field.selection.Alias, c.err)})
+				return
+			}
+			if !send(&Patch{Data: c.data, Path: c.field.path, HasNext: running > 0}) {
+				return
+			}
+		}
+	}()
+
+	return patches, nil
+}
+
+// executeDeferred executes a deferred field, returning an object with just
+// the field.
+func (e *execution) executeDeferred(field *deferredField) (interface{}, error) {
+	// The field is not deferred again, but deferred fields in its selections
+	// are.
+	selection := *field.selection
+	selection.Directives = nil
+	for _, directive := range field.selection.Directives {
+		if directive.Name != "defer" {
+			selection.Directives = append(selection.Directives, directive)
+		}
+	}
+
+	e.mu.Lock()
+	value, err := e.executeObject(field.ctx, field.typ, field.source, &SelectionSet{Selections: []*Selection{&selection}})
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return await(value)
+}
@@ -0,0 +1,137 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+)
+
+func TestExecuteIncremental(t *testing.T) {
+	type Report struct {
+		Total int64
+	}
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("users", func() []*User {
+		return []*User{{Name: "alice"}, {Name: "bob"}}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("report", func(u *User) *Report {
+		return &Report{Total: int64(len(u.Name))}
+	})
+	schema.Object("Report", Report{})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		users {
+			name
+			report @defer { total }
+			skipped: report @defer(if: false) { total }
+		}
+	}`, nil)
+
+	e := graphql.Executor{}
+	patches, err := e.ExecuteIncremental(context.Background(), builtSchema.Query, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []*graphql.Patch
+	for patch := range patches {
+		assert.NoError(t, patch.Err)
+		received = append(received, patch)
+	}
+
+	if assert.Len(t, received, 3) {
+		assert.Equal(t, internal.ParseJSON(`{
+			"users": [
+				{"name": "alice", "skipped": {"total": 5}},
+				{"name": "bob", "skipped": {"total": 3}}
+			]
+		}`), internal.AsJSON(received[0].Data))
+		assert.True(t, received[0].HasNext)
+
+		// Deferred fields complete in any order.
+		deferred := map[interface{}]interface{}{}
+		for _, patch := range received[1:] {
+			deferred[patch.Path[1]] = internal.AsJSON(patch.Data)
+			assert.Equal(t, "users", patch.Path[0])
+		}
+		assert.Equal(t, map[interface{}]interface{}{
+			0: internal.ParseJSON(`{"report": {"total": 5}}`),
+			1: internal.ParseJSON(`{"report": {"total": 3}}`),
+		}, deferred)
+		assert.True(t, received[1].HasNext)
+		assert.False(t, received[2].HasNext)
+	}
+
+	// Without ExecuteIncremental, @defer is ignored.
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{
+		"users": [
+			{"name": "alice", "report": {"total": 5}, "skipped": {"total": 5}},
+			{"name": "bob", "report": {"total": 3}, "skipped": {"total": 3}}
+		]
+	}`), internal.AsJSON(val))
+}
+
+func TestExecuteIncrementalConcurrent(t *testing.T) {
+	release := make(chan struct{})
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("slow", func(ctx context.Context) (string, error) {
+		select {
+		case <-release:
+			return "slow", nil
+		case <-time.After(5 * time.Second):
+			return "", errors.New("fast field was not sent first")
+		}
+	})
+	query.FieldFunc("fast", func() string {
+		return "fast"
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		slow @defer
+		fast @defer
+	}`, nil)
+
+	e := graphql.Executor{}
+	patches, err := e.ExecuteIncremental(context.Background(), builtSchema.Query, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The slow field only completes once the fast field has been sent, so
+	// the fields run concurrently and are sent as they complete.
+	var received []interface{}
+	for patch := range patches {
+		if !assert.NoError(t, patch.Err) {
+			break
+		}
+		received = append(received, internal.AsJSON(patch.Data))
+		if patch.HasNext && len(received) == 2 {
+			close(release)
+		}
+	}
+
+	assert.Equal(t, []interface{}{
+		internal.ParseJSON(`{}`),
+		internal.ParseJSON(`{"fast": "fast"}`),
+		internal.ParseJSON(`{"slow": "slow"}`),
+	}, received)
+}
@@ -0,0 +1,94 @@
+package graphql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+type Node interface {
+	isNode()
+}
+
+type NodeUser struct {
+	Id   int64
+	Name string
+}
+
+func (*NodeUser) isNode() {}
+
+type NodeItem struct {
+	Id    int64
+	Price float64
+}
+
+func (*NodeItem) isNode() {}
+
+func TestInterfaceType(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", NodeUser{})
+	schema.Object("Item", NodeItem{})
+	schema.RegisterInterface("Node", (*Node)(nil), func(value interface{}) string {
+		switch value.(type) {
+		case *NodeUser:
+			return "User"
+		default:
+			return "Item"
+		}
+	})
+
+	query := schema.Query()
+	query.FieldFunc("nodes", func() []Node {
+		return []Node{
+			&NodeUser{Id: 1, Name: "alice"},
+			&NodeItem{Id: 2, Price: 9.5},
+		}
+	})
+	query.FieldFunc("node", func() Node {
+		return nil
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			nodes {
+				__typename
+				id
+				... on User { name }
+				... on Item { price }
+			}
+			node { id }
+		}
+	`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`
+		{
+			"nodes": [
+				{"__typename": "User", "id": 1, "name": "alice"},
+				{"__typename": "Item", "id": 2, "price": 9.5}
+			],
+			"node": null
+		}`), internal.AsJSON(result))
+
+	q = graphql.MustParse(`{ nodes { name } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil || err.Error() != `unknown field "name"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+
+	sdl := builtSchema.SDL()
+	assert.Contains(t, sdl, "interface Node {\n  id: int64!\n}\n")
+	assert.Contains(t, sdl, "type Item implements Node {")
+	assert.Contains(t, sdl, "type User implements Node {")
+}
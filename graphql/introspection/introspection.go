@@ -11,9 +11,11 @@ import (
 )
 
 type introspection struct {
-	types    map[string]graphql.Type
-	query    graphql.Type
-	mutation graphql.Type
+	types        map[string]graphql.Type
+	query        graphql.Type
+	mutation     graphql.Type
+	subscription graphql.Type
+	directives   map[string]*graphql.Directive
 }
 
 type DirectiveLocation string
@@ -69,6 +71,33 @@ type Directive struct {
 	Args        []InputValue
 }
 
+// builtinDirectives are the directives supported by every schema.
+var builtinDirectives = []Directive{
+	{
+		Name:        "include",
+		Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locations:   []DirectiveLocation{FIELD, FRAGMENT_SPREAD, INLINE_FRAGMENT},
+		Args: []InputValue{{
+			Name:        "if",
+			Description: "Included when true.",
+			Type:        Type{Inner: booleanType},
+		}},
+	},
+	{
+		Name:        "skip",
+		Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locations:   []DirectiveLocation{FIELD, FRAGMENT_SPREAD, INLINE_FRAGMENT},
+		Args: []InputValue{{
+			Name:        "if",
+			Description: "Skipped when true.",
+			Type:        Type{Inner: booleanType},
+		}},
+	},
+}
+
+// booleanType is the type of the if argument of the builtin directives.
+var booleanType = &graphql.NonNull{Type: &graphql.Scalar{Type: "bool"}}
+
 func (s *introspection) registerDirective(schema *schemabuilder.Schema) {
 	schema.Object("__Directive", Directive{})
 }
@@ -97,6 +126,8 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			return OBJECT
 		case *graphql.Union:
 			return UNION
+		case *graphql.Interface:
+			return INTERFACE
 		case *graphql.Scalar:
 			return SCALAR
 		case *graphql.Enum:
@@ -112,21 +143,26 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		}
 	})
 
-	object.FieldFunc("name", func(t Type) string {
+	// Only named types have a name, lists and non-null types have none.
+	object.FieldFunc("name", func(t Type) *string {
+		var name string
 		switch t := t.Inner.(type) {
 		case *graphql.Object:
-			return t.Name
+			name = t.Name
 		case *graphql.Union:
-			return t.Name
+			name = t.Name
+		case *graphql.Interface:
+			name = t.Name
 		case *graphql.Scalar:
-			return t.Type
+			name = t.Type
 		case *graphql.Enum:
-			return t.Type
+			name = t.Type
 		case *graphql.InputObject:
-			return t.Name
+			name = t.Name
 		default:
-			return ""
+			return nil
 		}
+		return &name
 	})
 
 	object.FieldFunc("description", func(t Type) string {
@@ -135,17 +171,23 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			return t.Description
 		case *graphql.Union:
 			return t.Description
+		case *graphql.Interface:
+			return t.Description
+		case *graphql.InputObject:
+			return t.Description
 		default:
 			return ""
 		}
 	})
 
-	object.FieldFunc("interfaces", func() []Type { return nil })
-	object.FieldFunc("possibleTypes", func(t Type) []Type {
+	object.FieldFunc("interfaces", func(t Type) []Type {
 		switch t := t.Inner.(type) {
-		case *graphql.Union:
-			types := make([]Type, 0, len(t.Types))
-			for _, typ := range t.Types {
+		case *graphql.Object:
+			if len(t.Interfaces) == 0 {
+				return nil
+			}
+			types := make([]Type, 0, len(t.Interfaces))
+			for _, typ := range t.Interfaces {
 				types = append(types, Type{Inner: typ})
 			}
 
@@ -155,6 +197,25 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			return nil
 		}
 	})
+	object.FieldFunc("possibleTypes", func(t Type) []Type {
+		var possibleTypes map[string]*graphql.Object
+		switch t := t.Inner.(type) {
+		case *graphql.Union:
+			possibleTypes = t.Types
+		case *graphql.Interface:
+			possibleTypes = t.Types
+		default:
+			return nil
+		}
+
+		types := make([]Type, 0, len(possibleTypes))
+		for _, typ := range possibleTypes {
+			types = append(types, Type{Inner: typ})
+		}
+
+		sort.Slice(types, func(i, j int) bool { return types[i].Inner.String() < types[j].Inner.String() })
+		return types
+	})
 
 	object.FieldFunc("inputFields", func(t Type) []InputValue {
 		var fields []InputValue
@@ -163,8 +224,10 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		case *graphql.InputObject:
 			for name, f := range t.InputFields {
 				fields = append(fields, InputValue{
-					Name: name,
-					Type: Type{Inner: f},
+					Name:         name,
+					Description:  t.Descriptions[name],
+					Type:         Type{Inner: f},
+					DefaultValue: defaultValue(t.DefaultValues, name),
 				})
 			}
 		}
@@ -178,24 +241,38 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 	}) []field {
 		var fields []field
 
+		var graphqlFields map[string]*graphql.Field
 		switch t := t.Inner.(type) {
 		case *graphql.Object:
-			for name, f := range t.Fields {
-				var args []InputValue
-				for name, a := range f.Args {
-					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
-					})
-				}
-				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
+			graphqlFields = t.Fields
+		case *graphql.Interface:
+			graphqlFields = t.Fields
+		}
+
+		for name, f := range graphqlFields {
+			if f.DeprecationReason != "" && !includeDeprecated(args.IncludeDeprecated) {
+				continue
+			}
 
-				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
+			var args []InputValue
+			for name, a := range f.Args {
+				args = append(args, InputValue{
+					Name:         name,
+					Description:  f.ArgDescriptions[name],
+					Type:         Type{Inner: a},
+					DefaultValue: defaultValue(f.ArgDefaultValues, name),
 				})
 			}
+			sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
+
+			fields = append(fields, field{
+				Name:              name,
+				Description:       f.Description,
+				Type:              Type{Inner: f.Type},
+				Args:              args,
+				IsDeprecated:      f.DeprecationReason != "",
+				DeprecationReason: f.DeprecationReason,
+			})
 		}
 		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
 
@@ -221,9 +298,13 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		case *graphql.Enum:
 			var enumVals []EnumValue
 			for k, v := range t.ReverseMap {
+				reason := t.DeprecationReasons[v]
+				if reason != "" && !includeDeprecated(args.IncludeDeprecated) {
+					continue
+				}
 				val := fmt.Sprintf("%v", k)
 				enumVals = append(enumVals,
-					EnumValue{Name: v, Description: val, IsDeprecated: false, DeprecationReason: ""})
+					EnumValue{Name: v, Description: val, IsDeprecated: reason != "", DeprecationReason: reason})
 			}
 			sort.Slice(enumVals, func(i, j int) bool { return enumVals[i].Name < enumVals[j].Name })
 			return enumVals
@@ -232,6 +313,21 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 	})
 }
 
+// defaultValue returns the default of an input value, or nil if it has none.
+func defaultValue(defaultValues map[string]string, name string) *string {
+	literal, ok := defaultValues[name]
+	if !ok {
+		return nil
+	}
+	return &literal
+}
+
+// includeDeprecated returns the value of an includeDeprecated arg, which
+// defaults to false.
+func includeDeprecated(arg *bool) bool {
+	return arg != nil && *arg
+}
+
 type field struct {
 	Name              string
 	Description       string
@@ -270,6 +366,22 @@ func collectTypes(typ graphql.Type, types map[string]graphql.Type) {
 			collectTypes(graphqlTyp, types)
 		}
 
+	case *graphql.Interface:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+		for _, field := range typ.Fields {
+			collectTypes(field.Type, types)
+
+			for _, arg := range field.Args {
+				collectTypes(arg, types)
+			}
+		}
+		for _, graphqlTyp := range typ.Types {
+			collectTypes(graphqlTyp, types)
+		}
+
 	case *graphql.List:
 		collectTypes(typ.Type, types)
 
@@ -311,11 +423,28 @@ func (s *introspection) registerQuery(schema *schemabuilder.Schema) {
 		}
 		sort.Slice(types, func(i, j int) bool { return types[i].Inner.String() < types[j].Inner.String() })
 
-		return &Schema{
+		schema := &Schema{
 			Types:        types,
 			QueryType:    &Type{Inner: s.query},
 			MutationType: &Type{Inner: s.mutation},
 		}
+		if s.subscription != nil {
+			schema.SubscriptionType = &Type{Inner: s.subscription}
+		}
+		schema.Directives = append(schema.Directives, builtinDirectives...)
+		for _, directive := range s.directives {
+			var locations []DirectiveLocation
+			for _, location := range directive.Locations {
+				locations = append(locations, DirectiveLocation(location))
+			}
+			schema.Directives = append(schema.Directives, Directive{
+				Name:      directive.Name,
+				Locations: locations,
+				Args:      []InputValue{},
+			})
+		}
+		sort.Slice(schema.Directives, func(i, j int) bool { return schema.Directives[i].Name < schema.Directives[j].Name })
+		return schema
 	})
 
 	object.FieldFunc("__type", func(args struct{ Name string }) *Type {
@@ -348,10 +477,16 @@ func AddIntrospectionToSchema(schema *graphql.Schema) {
 	types := make(map[string]graphql.Type)
 	collectTypes(schema.Query, types)
 	collectTypes(schema.Mutation, types)
+	if schema.Subscription != nil {
+		collectTypes(schema.Subscription, types)
+	}
+	collectTypes(booleanType, types)
 	is := &introspection{
-		types:    types,
-		query:    schema.Query,
-		mutation: schema.Mutation,
+		types:        types,
+		query:        schema.Query,
+		mutation:     schema.Mutation,
+		subscription: schema.Subscription,
+		directives:   schema.Directives,
 	}
 	isSchema := is.schema()
 
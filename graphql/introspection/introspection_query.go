@@ -6,6 +6,7 @@ query IntrospectionQuery {
 	__schema {
 		queryType { name }
 		mutationType { name }
+		subscriptionType { name }
 		types {
 			...FullType
 		}
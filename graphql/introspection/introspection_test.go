@@ -1,18 +1,23 @@
 package introspection_test
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
 )
 
 type User struct {
-	Name     string
+	Name     string `graphql:"name;description=The user's display name"`
 	MaybeAge *int64
 }
 
@@ -22,7 +27,8 @@ type Vehicle struct {
 }
 type Asset struct {
 	Name         string
-	BatteryLevel int64
+	BatteryLevel int64   `graphql:"batteryLevel;nullable"`
+	Serial       *string `graphql:"serial;nonnull"`
 }
 
 type Gateway struct {
@@ -34,6 +40,13 @@ type Gateway struct {
 
 type enumType int32
 
+type named interface {
+	isNamed()
+}
+
+func (User) isNamed()  {}
+func (Asset) isNamed() {}
+
 func makeSchema() *schemabuilder.Schema {
 	schema := schemabuilder.NewSchema()
 	user := schema.Object("user", User{})
@@ -44,6 +57,8 @@ func makeSchema() *schemabuilder.Schema {
 		"random1": enumType(2),
 		"random2": enumType(1),
 	})
+	schema.Object("Asset", Asset{})
+	schema.RegisterInterface("Named", (*named)(nil), nil)
 	query := schema.Query()
 	query.FieldFunc("me", func() User {
 		return User{Name: "me"}
@@ -61,6 +76,10 @@ func makeSchema() *schemabuilder.Schema {
 		return nil, nil
 	})
 
+	query.FieldFunc("named", func() []named {
+		return nil
+	})
+
 	query.FieldFunc("gateway", func() (*Gateway, error) {
 		return nil, nil
 	})
@@ -76,16 +95,26 @@ func makeSchema() *schemabuilder.Schema {
 		return nil
 	})
 	user.FieldFunc("greet", func(args struct {
-		Other     string
+		Other     string `graphql:"other;default=friend"`
 		Include   *User
 		Enumfield enumType
 	}) string {
 		return ""
 	})
 
+	user.FieldFunc("fullName", func(u *User) string {
+		return u.Name
+	}, schemabuilder.Deprecated("use name"))
+	schema.DeprecateEnumValue(enumType(1), "use random")
+
 	mutation := schema.Mutation()
 	mutation.FieldFunc("sayHi", func() {})
 
+	schema.RegisterDirective("uppercase", []graphql.DirectiveLocation{graphql.DirectiveLocationField},
+		func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error) {
+			return value, nil
+		})
+
 	return schema
 }
 
@@ -114,3 +143,48 @@ func TestComputeSchemaJSON(t *testing.T) {
 		t.Errorf("schema JSONs do not match:\n---expected---\n%+v\n---actual---\n%+v", expected, actual)
 	}
 }
+
+func TestIntrospectionType(t *testing.T) {
+	schema := makeSchema().MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+
+	q := graphql.MustParse(`{
+		__type(name: "UserConnection") {
+			kind
+			name
+			fields {
+				name
+				type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+			}
+		}
+		missing: __type(name: "Missing") { name }
+	}`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), schema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typ := internal.AsJSON(val).(map[string]interface{})["__type"].(map[string]interface{})
+	assert.Equal(t, "OBJECT", typ["kind"])
+	assert.Equal(t, "UserConnection", typ["name"])
+	assert.Nil(t, internal.AsJSON(val).(map[string]interface{})["missing"])
+
+	var edges interface{}
+	for _, field := range typ["fields"].([]interface{}) {
+		if field := field.(map[string]interface{}); field["name"] == "edges" {
+			edges = field["type"]
+		}
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"kind": "NON_NULL", "name": null, "ofType": {
+			"kind": "LIST", "name": null, "ofType": {
+				"kind": "NON_NULL", "name": null, "ofType": {"kind": "OBJECT", "name": "UserEdge"}
+			}
+		}
+	}`), edges)
+}
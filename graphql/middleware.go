@@ -2,6 +2,7 @@ package graphql
 
 import (
 	"context"
+	"sync"
 )
 
 type ComputationInput struct {
@@ -19,6 +20,32 @@ type ComputationOutput struct {
 	Metadata map[string]interface{}
 	Current  interface{}
 	Error    error
+
+	// cost computes the QueryCost of the executed query once it is needed.
+	cost func() int
+}
+
+// Cost returns the QueryCost of the executed query, e.g. for logging. It is
+// computed when it is first needed, by Cost or by Executor.MaxCost, and only
+// once per execution.
+func (o *ComputationOutput) Cost() int {
+	if o.cost == nil {
+		return 0
+	}
+	return o.cost()
+}
+
+// lazyQueryCost returns a function computing QueryCost(ctx, typ, selectionSet)
+// the first time it is called.
+func lazyQueryCost(ctx context.Context, typ Type, selectionSet *SelectionSet) func() int {
+	var once sync.Once
+	var cost int
+	return func() int {
+		once.Do(func() {
+			cost = QueryCost(ctx, typ, selectionSet)
+		})
+		return cost
+	}
 }
 
 type MiddlewareFunc func(input *ComputationInput, next MiddlewareNextFunc) *ComputationOutput
@@ -0,0 +1,20 @@
+package graphql
+
+import "context"
+
+type maxPageSizeKey struct{}
+
+// WithMaxPageSize returns a context in which paginated fields reject first and
+// last args greater than size, e.g. to allow different page sizes for different
+// user roles. It can only lower the limits configured on the schema and its
+// fields, never raise them.
+func WithMaxPageSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxPageSizeKey{}, size)
+}
+
+// MaxPageSize returns the max page size set with WithMaxPageSize, or 0 if none
+// is set.
+func MaxPageSize(ctx context.Context) int64 {
+	size, _ := ctx.Value(maxPageSizeKey{}).(int64)
+	return size
+}
@@ -1,9 +1,12 @@
 package graphql
 
 import (
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/graphql/language/ast"
 	"github.com/graphql-go/graphql/language/parser"
 )
@@ -38,7 +41,7 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 	case *ast.Variable:
 		actual, ok := vars[value.Name.Value]
 		if !ok {
-			return nil, nil
+			return nil, NewClientError("undeclared variable $%s", value.Name.Value)
 		}
 		return actual, nil
 	case *ast.ObjectValue:
@@ -88,6 +91,57 @@ func argsToJson(input []*ast.Argument, vars map[string]interface{}) (interface{}
 	return args, nil
 }
 
+// shouldInclude evaluates the @skip and @include directives of a fragment,
+// returning false if the fragment should be omitted. Other directives are not
+// supported on fragments.
+func shouldInclude(directives []*ast.Directive, vars map[string]interface{}) (bool, error) {
+	include, custom, err := parseDirectives(directives, vars)
+	if err != nil {
+		return false, err
+	}
+	if len(custom) != 0 {
+		return false, NewClientError("directives not supported")
+	}
+	return include, nil
+}
+
+// parseDirectives evaluates the @skip and @include directives of a selection,
+// returning false if the selection should be omitted, and returns its other
+// directives with their args.
+func parseDirectives(directives []*ast.Directive, vars map[string]interface{}) (bool, []*SelectionDirective, error) {
+	include := true
+	var custom []*SelectionDirective
+	for _, directive := range directives {
+		name := directive.Name.Value
+		if name != "skip" && name != "include" {
+			args, err := argsToJson(directive.Arguments, vars)
+			if err != nil {
+				return false, nil, err
+			}
+			custom = append(custom, &SelectionDirective{
+				Name: name,
+				Args: args.(map[string]interface{}),
+			})
+			continue
+		}
+		if len(directive.Arguments) != 1 || directive.Arguments[0].Name.Value != "if" {
+			return false, nil, NewClientError("@%s expects a single if argument", name)
+		}
+		value, err := valueToJson(directive.Arguments[0].Value, vars)
+		if err != nil {
+			return false, nil, err
+		}
+		condition, ok := value.(bool)
+		if !ok {
+			return false, nil, NewClientError("@%s if argument must be a boolean", name)
+		}
+		if (name == "skip" && condition) || (name == "include" && !condition) {
+			include = false
+		}
+	}
+	return include, custom, nil
+}
+
 // parseSelectionSet takes a grapqhl-go selection set and converts it to a
 // simplified *SelectionSet, bindings vars
 func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Fragment, vars map[string]interface{}) (*SelectionSet, error) {
@@ -105,8 +159,12 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				alias = selection.Alias.Value
 			}
 
-			if len(selection.Directives) != 0 {
-				return nil, NewClientError("directives not supported")
+			include, directives, err := parseDirectives(selection.Directives, vars)
+			if err != nil {
+				return nil, err
+			}
+			if !include {
+				continue
 			}
 
 			args, err := argsToJson(selection.Arguments, vars)
@@ -124,27 +182,37 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				Name:         selection.Name.Value,
 				Args:         args,
 				SelectionSet: selectionSet,
+				Directives:   directives,
 			})
 
 		case *ast.FragmentSpread:
 			name := selection.Name.Value
 
-			if len(selection.Directives) != 0 {
-				return nil, NewClientError("directives not supported")
+			include, err := shouldInclude(selection.Directives, vars)
+			if err != nil {
+				return nil, err
 			}
 
 			fragment, found := globalFragments[name]
 			if !found {
 				return nil, NewClientError("unknown fragment")
 			}
+			if !include {
+				fragment.spreadSkipped = true
+				continue
+			}
 
 			fragments = append(fragments, fragment)
 
 		case *ast.InlineFragment:
 			on := selection.TypeCondition.Name.Value
 
-			if len(selection.Directives) != 0 {
-				return nil, NewClientError("directives not supported")
+			include, err := shouldInclude(selection.Directives, vars)
+			if err != nil {
+				return nil, err
+			}
+			if !include {
+				continue
 			}
 
 			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars)
@@ -224,7 +292,7 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 	}
 
 	for _, fragment := range globalFragments {
-		if state[fragment] != visited {
+		if state[fragment] != visited && !fragment.spreadSkipped {
 			return NewClientError("unused fragment")
 		}
 	}
@@ -286,12 +354,54 @@ func detectConflicts(selectionSet *SelectionSet) error {
 	return visitChild(selectionSet)
 }
 
+// A Query is a parsed GraphQL operation.
 type Query struct {
+	// Name is the name of the operation, e.g. "Foo" for mutation Foo { ... },
+	// or "" for an anonymous operation.
 	Name string
+
+	// Kind is the type of the operation: "query", "mutation" or
+	// "subscription". Shorthand queries like { ... } are of kind "query".
 	Kind string
+
 	*SelectionSet
 }
 
+// SyntaxError is the error returned by Parse for a query that isn't valid
+// GraphQL syntax. Line and Column locate the offending token and start at 1,
+// e.g. for editors to highlight it.
+type SyntaxError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error GraphQL (%d:%d) %s", e.Line, e.Column, e.Message)
+}
+
+func (e *SyntaxError) SanitizedError() string {
+	return e.Error()
+}
+
+// newSyntaxError converts the syntax errors of graphql-go, whose messages are
+// of the form "Syntax Error GraphQL (1:3) Expected Name", followed by the
+// source around the error.
+func newSyntaxError(err *gqlerrors.Error) *SyntaxError {
+	message := err.Message
+	if i := strings.Index(message, "\n\n"); i != -1 {
+		message = message[:i]
+	}
+	if i := strings.Index(message, ") "); i != -1 {
+		message = message[i+2:]
+	}
+	return &SyntaxError{
+		Message: message,
+		Line:    err.Locations[0].Line,
+		Column:  err.Locations[0].Column,
+	}
+}
+
 // Parse parses an input GraphQL string into a *Query
 //
 // Parse validates that the query looks syntactically correct and
@@ -301,7 +411,10 @@ type Query struct {
 func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	document, err := parser.Parse(parser.ParseParams{Source: source})
 	if err != nil {
-		return nil, NewClientError(err.Error())
+		if err, ok := err.(*gqlerrors.Error); ok && len(err.Locations) > 0 {
+			return nil, newSyntaxError(err)
+		}
+		return nil, NewClientError("%s", err.Error())
 	}
 
 	var queryDefinition *ast.OperationDefinition
@@ -317,8 +430,8 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 			fragmentDefinitions[name] = definition
 
 		case *ast.OperationDefinition:
-			if definition.Operation != "query" && definition.Operation != "mutation" {
-				return nil, NewClientError("only support queries or mutations")
+			if definition.Operation != "query" && definition.Operation != "mutation" && definition.Operation != "subscription" {
+				return nil, NewClientError("only support queries, mutations or subscriptions")
 			}
 			if queryDefinition != nil {
 				return nil, NewClientError("only support a single query")
@@ -388,6 +501,11 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 		vars = defaultedVars
 	}
 
+	vars, err = coerceVariables(queryDefinition.VariableDefinitions, vars)
+	if err != nil {
+		return rv, err
+	}
+
 	globalFragments := make(map[string]*Fragment)
 	for name, fragment := range fragmentDefinitions {
 		globalFragments[name] = &Fragment{
@@ -447,9 +565,11 @@ func MustParse(source string, vars map[string]interface{}) *Query {
 //     groups: { name name id { widgets { name } } }
 //
 // Flatten does _not_ flatten out the inner queries, so the name above does not
-// get flattened out yet.
+// get flattened out yet. The selections are returned in the order their
+// aliases first appear in the query.
 func Flatten(selectionSet *SelectionSet) []*Selection {
 	grouped := make(map[string][]*Selection)
+	var aliases []string
 
 	state := make(map[*SelectionSet]visitState)
 	var visit func(*SelectionSet)
@@ -459,6 +579,9 @@ func Flatten(selectionSet *SelectionSet) []*Selection {
 		}
 
 		for _, selection := range selectionSet.Selections {
+			if _, ok := grouped[selection.Alias]; !ok {
+				aliases = append(aliases, selection.Alias)
+			}
 			grouped[selection.Alias] = append(grouped[selection.Alias], selection)
 		}
 		for _, fragment := range selectionSet.Fragments {
@@ -471,7 +594,8 @@ func Flatten(selectionSet *SelectionSet) []*Selection {
 	visit(selectionSet)
 
 	var flattened []*Selection
-	for _, selections := range grouped {
+	for _, alias := range aliases {
+		selections := grouped[alias]
 		if len(selections) == 1 || selections[0].SelectionSet == nil {
 			flattened = append(flattened, selections[0])
 			continue
@@ -488,12 +612,97 @@ func Flatten(selectionSet *SelectionSet) []*Selection {
 			Alias:        selections[0].Alias,
 			Args:         selections[0].Args,
 			SelectionSet: merged,
+			Directives:   selections[0].Directives,
 		})
 	}
 
 	return flattened
 }
 
+// selectionsNamed returns the selections of the field name in selectionSet
+// and its fragments, under any alias.
+func (s *SelectionSet) selectionsNamed(name string) []*Selection {
+	var selections []*Selection
+	s.visitSelections(func(selection *Selection) {
+		if selection.Name == name {
+			selections = append(selections, selection)
+		}
+	})
+	return selections
+}
+
+// visitSelections calls f for every selection of s and its fragments.
+func (s *SelectionSet) visitSelections(f func(*Selection)) {
+	visited := make(map[*SelectionSet]bool)
+	var visit func(*SelectionSet)
+	visit = func(selectionSet *SelectionSet) {
+		if selectionSet == nil || visited[selectionSet] {
+			return
+		}
+		visited[selectionSet] = true
+
+		for _, selection := range selectionSet.Selections {
+			f(selection)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			visit(fragment.SelectionSet)
+		}
+	}
+	visit(s)
+}
+
+// HasField returns if the field name is selected, under any alias and in any
+// fragment. Resolvers can use it to only load the data that is queried.
+func (s *SelectionSet) HasField(name string) bool {
+	return len(s.selectionsNamed(name)) > 0
+}
+
+// FieldNames returns the names of the selected fields, including the fields of
+// fragments, in the order they first appear in the query. Fields selected
+// several times, e.g. under different aliases, are returned once. Introspection
+// fields like __typename aren't returned, as they aren't resolved from the
+// source, so resolvers can use the names to only select the queried columns.
+func (s *SelectionSet) FieldNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	s.visitSelections(func(selection *Selection) {
+		if seen[selection.Name] || strings.HasPrefix(selection.Name, "__") {
+			return
+		}
+		seen[selection.Name] = true
+		names = append(names, selection.Name)
+	})
+	return names
+}
+
+// SubSelection returns the selection set of the field name, merging the
+// selection sets of all its selections, or nil if the field isn't selected or
+// is a scalar.
+// For example, in the selection set of the query
+//
+//     { user { address { city } ... on User { address { zip } } } }
+//
+// passed to the resolver of user, SubSelection("address") selects both city
+// and zip.
+func (s *SelectionSet) SubSelection(name string) *SelectionSet {
+	selections := s.selectionsNamed(name)
+	if len(selections) == 0 {
+		return nil
+	}
+	if len(selections) == 1 {
+		return selections[0].SelectionSet
+	}
+
+	merged := &SelectionSet{}
+	for _, selection := range selections {
+		if selection.SelectionSet != nil {
+			merged.Selections = append(merged.Selections, selection.SelectionSet.Selections...)
+			merged.Fragments = append(merged.Fragments, selection.SelectionSet.Fragments...)
+		}
+	}
+	return merged
+}
+
 /*
 // TODO: precompute flatten
 // TODO: properly typecheck fragments
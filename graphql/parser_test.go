@@ -2,6 +2,7 @@ package graphql_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	. "github.com/samsarahq/thunder/graphql"
@@ -9,7 +10,7 @@ import (
 
 func TestParseSupported(t *testing.T) {
 	query, err := Parse(`
-{
+query ($var: string) {
 	foo {
 		alias: bar
 		alias: bar
@@ -161,6 +162,27 @@ mutation foo($var: bar) {
 	}
 }
 
+func TestParseOperation(t *testing.T) {
+	for _, testCase := range []struct {
+		source string
+		name   string
+		kind   string
+	}{
+		{source: `mutation Foo { bar }`, name: "Foo", kind: "mutation"},
+		{source: `query Bar { baz }`, name: "Bar", kind: "query"},
+		{source: `subscription { baz }`, name: "", kind: "subscription"},
+		{source: `{ baz }`, name: "", kind: "query"},
+	} {
+		query, err := Parse(testCase.source, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query.Name != testCase.name || query.Kind != testCase.kind {
+			t.Errorf("%s: expected %q %q, got %q %q", testCase.source, testCase.kind, testCase.name, query.Kind, query.Name)
+		}
+	}
+}
+
 func TestParseUnsupported(t *testing.T) {
 	_, err := Parse(``, map[string]interface{}{})
 	if err == nil || err.Error() != "must have a single query" {
@@ -210,10 +232,12 @@ func TestParseUnsupported(t *testing.T) {
 
 	_, err = Parse(`
 {
-	a @test
+	... on Foo @test {
+		a
+	}
 }`, map[string]interface{}{})
 	if err == nil || err.Error() != "directives not supported" {
-		t.Error("expected directives to fail", err)
+		t.Error("expected directives on fragments to fail", err)
 	}
 
 	_, err = Parse(`
@@ -280,3 +304,161 @@ query Operation($x: int64 = 2) {
 		t.Errorf("expected 2, received %v", val)
 	}
 }
+
+func TestParseValidateVariables(t *testing.T) {
+	source := `
+query Operation($x: int64!, $names: [string!]) {
+	field(x: $x, names: $names)
+}`
+
+	_, err := Parse(source, map[string]interface{}{})
+	if err == nil || err.Error() != "required variable $x not provided" {
+		t.Error("expected missing required variable to fail, but got", err)
+	}
+
+	_, err = Parse(source, map[string]interface{}{"x": "two"})
+	if err == nil || err.Error() != "variable $x: expected int64, got two" {
+		t.Error("expected mismatched variable to fail, but got", err)
+	}
+
+	_, err = Parse(source, map[string]interface{}{"x": float64(1.5)})
+	if err == nil || err.Error() != "variable $x: expected int64, got 1.5" {
+		t.Error("expected fractional int variable to fail, but got", err)
+	}
+
+	_, err = Parse(source, map[string]interface{}{"x": float64(1), "names": []interface{}{"a", nil}})
+	if err == nil || err.Error() != "variable $names: expected string!, got null" {
+		t.Error("expected null list item to fail, but got", err)
+	}
+
+	_, err = Parse(`{ field(x: $x) }`, map[string]interface{}{"x": float64(1)})
+	if err == nil || err.Error() != "undeclared variable $x" {
+		t.Error("expected undeclared variable to fail, but got", err)
+	}
+
+	// Single values are coerced to lists.
+	query, err := Parse(source, map[string]interface{}{"x": float64(1), "names": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"x": float64(1), "names": []interface{}{"a"}}
+	if args := query.SelectionSet.Selections[0].Args; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, received %v", expected, args)
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	for _, c := range []struct {
+		query string
+		err   SyntaxError
+	}{
+		{
+			query: `{ foo(x: null) }`,
+			err:   SyntaxError{Message: `Unexpected Name "null"`, Line: 1, Column: 10},
+		},
+		{
+			query: "{\n\tfoo {\n\t\tbar(\n\t}\n}",
+			err:   SyntaxError{Message: `Expected Name, found }`, Line: 4, Column: 2},
+		},
+		{
+			query: "query {\n  foo\n  \"bar\n}",
+			err:   SyntaxError{Message: `Unterminated string.`, Line: 3, Column: 7},
+		},
+	} {
+		_, err := Parse(c.query, nil)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("expected syntax error for %q, got %v", c.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(*syntaxErr, c.err) {
+			t.Errorf("expected %#v for %q, got %#v", c.err, c.query, *syntaxErr)
+		}
+	}
+
+	_, err := Parse(`{ foo(x: null) }`, nil)
+	if err == nil || err.Error() != `Syntax Error GraphQL (1:10) Unexpected Name "null"` {
+		t.Error("expected syntax error message", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	a := MustParse(`
+query Users($id: int64) {
+	user(id: $id, filter: {role: "admin", tags: ["a", "b"]}) {
+		... UserFields
+		handle: name @upper
+	}
+}
+
+fragment UserFields on User { id friends(first: 2) { name } }`, map[string]interface{}{"id": float64(3)})
+	b := MustParse(`query Users { user(filter: {tags: ["a", "b"], role: "admin"}, id: 3) {
+		... on User { id, friends(first: 2) { name } }
+		handle: name @upper
+	} }`, nil)
+
+	expected := `query Users {
+  user(filter: {role: "admin", tags: ["a", "b"]}, id: 3) {
+    handle: name @upper
+    ... on User {
+      id
+      friends(first: 2) {
+        name
+      }
+    }
+  }
+}
+`
+	if printed := Print(a); printed != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, printed)
+	}
+	if Print(a) != Print(b) {
+		t.Errorf("expected equal prints, but got\n%s\nand\n%s", Print(a), Print(b))
+	}
+
+	c := MustParse(`{ b a(x: 1.5) }`, nil)
+	d := MustParse(`query { a(x: 1.5), b }`, nil)
+	if Print(c) == Print(d) {
+		t.Errorf("expected field order to be kept, but got\n%s", Print(c))
+	}
+	if PrintSorted(c) != PrintSorted(d) {
+		t.Errorf("expected equal sorted prints, but got\n%s\nand\n%s", PrintSorted(c), PrintSorted(d))
+	}
+	if printed := PrintSorted(c); printed != "query {\n  a(x: 1.5)\n  b\n}\n" {
+		t.Errorf("unexpected sorted print\n%s", printed)
+	}
+}
+
+func TestBindVariables(t *testing.T) {
+	query := `query Users($first: Int!, $after: String, $role: String = "member") {
+	users(first: $first, after: $after, role: $role) { name }
+}`
+
+	vars, err := BindVariables(query, struct {
+		First  int64
+		After  *string
+		Filter string `graphql:"role"`
+	}{First: 10, Filter: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]interface{}{"first": float64(10), "role": "admin"}; !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected %v, but got %v", expected, vars)
+	}
+	if _, err := Parse(query, vars); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := BindVariables(query, struct{ First string }{First: "10"}); err == nil || !strings.Contains(err.Error(), "variable $first: expected Int") {
+		t.Errorf("expected type mismatch, got %v", err)
+	}
+	if _, err := BindVariables(query, struct{ First float64 }{First: 1.5}); err == nil || !strings.Contains(err.Error(), "variable $first: expected Int") {
+		t.Errorf("expected type mismatch, got %v", err)
+	}
+	if _, err := BindVariables(query, struct{ First, Last int64 }{}); err == nil || !strings.Contains(err.Error(), "query has no variable $last") {
+		t.Errorf("expected unknown variable error, got %v", err)
+	}
+	if _, err := BindVariables(query, struct{ After string }{}); err == nil || !strings.Contains(err.Error(), "required variable $first not provided") {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package graphql
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// PersistedQueryStore stores the text of automatic persisted queries, keyed by
+// the hex-encoded SHA-256 hash of the query.
+type PersistedQueryStore interface {
+	Get(hash string) (query string, ok bool)
+	Set(hash string, query string)
+}
+
+// NewMemoryPersistedQueryStore returns a PersistedQueryStore that keeps at most
+// size queries in memory, evicting the least recently used ones. Since any
+// client can save queries, evicted queries are resent by clients as needed.
+func NewMemoryPersistedQueryStore(size int) PersistedQueryStore {
+	return &memoryPersistedQueryStore{
+		size:    size,
+		queries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+type persistedQuery struct {
+	hash  string
+	query string
+}
+
+type memoryPersistedQueryStore struct {
+	mu      sync.Mutex
+	size    int
+	queries map[string]*list.Element
+	lru     *list.List
+}
+
+func (s *memoryPersistedQueryStore) Get(hash string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.queries[hash]
+	if !ok {
+		return "", false
+	}
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*persistedQuery).query, true
+}
+
+func (s *memoryPersistedQueryStore) Set(hash string, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.queries[hash]; ok {
+		s.lru.MoveToFront(elem)
+		return
+	}
+	if s.size <= 0 {
+		return
+	}
+	s.queries[hash] = s.lru.PushFront(&persistedQuery{hash: hash, query: query})
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.queries, oldest.Value.(*persistedQuery).hash)
+	}
+}
+
+// ErrPersistedQueryNotFound is returned for a request that only includes the
+// hash of a query the store does not know. It is sent with the code
+// PERSISTED_QUERY_NOT_FOUND in its extensions, to which clients respond by
+// resending the request with the full query.
+var ErrPersistedQueryNotFound error = persistedQueryNotFoundError{ClientError{message: "PersistedQueryNotFound"}}
+
+// persistedQueryNotFoundError is comparable, unlike errors created with
+// NewCodedError, so callers can check for ErrPersistedQueryNotFound with ==.
+type persistedQueryNotFoundError struct {
+	ClientError
+}
+
+func (persistedQueryNotFoundError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"}
+}
+
+// persistedQueryHash returns the hash in a request's
+// `{"persistedQuery": {"version": 1, "sha256Hash": "..."}}` extensions, or "" if
+// there is none.
+func persistedQueryHash(extensions map[string]interface{}) string {
+	persistedQuery, ok := extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	hash, _ := persistedQuery["sha256Hash"].(string)
+	return hash
+}
+
+// resolvePersistedQuery returns the query to run for a request. Requests
+// without a persisted query hash, or without a store, run query as is. A
+// request with a hash and no query is looked up in the store; a request with
+// both has its query verified against the hash and saved.
+func resolvePersistedQuery(store PersistedQueryStore, query string, extensions map[string]interface{}) (string, error) {
+	hash := persistedQueryHash(extensions)
+	if store == nil || hash == "" {
+		return query, nil
+	}
+	hash = strings.ToLower(hash)
+
+	if query == "" {
+		query, ok := store.Get(hash)
+		if !ok {
+			return "", ErrPersistedQueryNotFound
+		}
+		return query, nil
+	}
+
+	sum := sha256.Sum256([]byte(query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return "", NewClientError("provided sha256Hash does not match query")
+	}
+	store.Set(hash, query)
+	return query, nil
+}
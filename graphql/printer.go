@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Print prints a parsed query in a canonical form, e.g. for logs or as a cache
+// key. Queries that differ only in formatting, argument order or the way
+// fragments are written print identically: named fragments are printed inline,
+// variables are printed as the values they were parsed with, and args are
+// sorted by name. Enum args are printed as strings, since the parsed query
+// doesn't tell them apart.
+func Print(query *Query) string {
+	return printQuery(query, false)
+}
+
+// PrintSorted is like Print, but also sorts the selections of every selection
+// set by alias and the fragments by type, so that queries which only differ in
+// field order print identically.
+func PrintSorted(query *Query) string {
+	return printQuery(query, true)
+}
+
+func printQuery(query *Query, sorted bool) string {
+	var buf bytes.Buffer
+	buf.WriteString(query.Kind)
+	if query.Kind == "" {
+		buf.WriteString("query")
+	}
+	if query.Name != "" {
+		fmt.Fprintf(&buf, " %s", query.Name)
+	}
+	buf.WriteString(" ")
+	printSelectionSet(&buf, query.SelectionSet, "", sorted)
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// printSelectionSet prints a selection set as a block, with its contents
+// indented one level deeper than indent.
+func printSelectionSet(buf *bytes.Buffer, selectionSet *SelectionSet, indent string, sorted bool) {
+	buf.WriteString("{\n")
+	inner := indent + "  "
+
+	if selectionSet != nil {
+		selections := selectionSet.Selections
+		fragments := selectionSet.Fragments
+		if sorted {
+			selections = append([]*Selection(nil), selections...)
+			sort.SliceStable(selections, func(i, j int) bool {
+				return selections[i].Alias < selections[j].Alias
+			})
+			fragments = append([]*Fragment(nil), fragments...)
+			sort.SliceStable(fragments, func(i, j int) bool {
+				return fragments[i].On < fragments[j].On
+			})
+		}
+
+		for _, selection := range selections {
+			buf.WriteString(inner)
+			if selection.Alias != selection.Name {
+				fmt.Fprintf(buf, "%s: ", selection.Alias)
+			}
+			buf.WriteString(selection.Name)
+			if args, ok := selection.Args.(map[string]interface{}); ok {
+				buf.WriteString(printArgs(args))
+			}
+			for _, directive := range selection.Directives {
+				fmt.Fprintf(buf, " @%s%s", directive.Name, printArgs(directive.Args))
+			}
+			if selection.SelectionSet != nil {
+				buf.WriteString(" ")
+				printSelectionSet(buf, selection.SelectionSet, inner, sorted)
+			}
+			buf.WriteString("\n")
+		}
+
+		for _, fragment := range fragments {
+			fmt.Fprintf(buf, "%s... on %s ", inner, fragment.On)
+			printSelectionSet(buf, fragment.SelectionSet, inner, sorted)
+			buf.WriteString("\n")
+		}
+	}
+
+	buf.WriteString(indent)
+	buf.WriteString("}")
+}
+
+// printArgs prints args sorted by name, or "" if there are none.
+func printArgs(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, printValue(args[name])))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// printValue prints a json.Unmarshal-style value as a GraphQL literal.
+func printValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return quoteSDLString(value)
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			fields = append(fields, fmt.Sprintf("%s: %s", name, printValue(value[name])))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,128 @@
+package graphql
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+)
+
+// QueryCache is a fixed-size LRU cache of parsed and prepared queries. Because
+// Parse substitutes variables into the query's arguments, queries are cached
+// by their source, their variables and the type they are prepared against.
+//
+// A QueryCache is safe for concurrent use.
+type QueryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[queryCacheKey]*list.Element
+	lru     *list.List
+}
+
+type queryCacheKey struct {
+	typ       Type
+	source    string
+	variables string
+}
+
+type queryCacheEntry struct {
+	key   queryCacheKey
+	query *Query
+}
+
+// NewQueryCache returns a QueryCache holding at most size queries.
+func NewQueryCache(size int) *QueryCache {
+	return &QueryCache{
+		size:    size,
+		entries: make(map[queryCacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Prepare parses source with Parse and prepares it against typ with
+// PrepareQuery, reusing a cached result for a previously seen source and
+// variables. The returned query is a copy and may be used concurrently with
+// other copies. Queries that fail to parse or prepare are not cached.
+func (c *QueryCache) Prepare(typ Type, source string, vars map[string]interface{}) (*Query, error) {
+	variables, err := json.Marshal(vars)
+	if err != nil {
+		return prepareQuery(typ, source, vars)
+	}
+	key := queryCacheKey{typ: typ, source: source, variables: string(variables)}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		query := elem.Value.(*queryCacheEntry).query
+		c.mu.Unlock()
+		return cloneQuery(query), nil
+	}
+	c.mu.Unlock()
+
+	query, err := prepareQuery(typ, source, vars)
+	if err != nil {
+		return query, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && c.size > 0 {
+		c.entries[key] = c.lru.PushFront(&queryCacheEntry{key: key, query: query})
+		for c.lru.Len() > c.size {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+		}
+	}
+	return cloneQuery(query), nil
+}
+
+// Len returns the number of cached queries.
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func prepareQuery(typ Type, source string, vars map[string]interface{}) (*Query, error) {
+	query, err := Parse(source, vars)
+	if err != nil {
+		return query, err
+	}
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// cloneQuery copies the selection sets of a prepared query. Parsed arguments
+// are shared, as they are not modified once prepared.
+func cloneQuery(query *Query) *Query {
+	return &Query{
+		Name:         query.Name,
+		Kind:         query.Kind,
+		SelectionSet: cloneSelectionSet(query.SelectionSet),
+	}
+}
+
+func cloneSelectionSet(selectionSet *SelectionSet) *SelectionSet {
+	if selectionSet == nil {
+		return nil
+	}
+
+	clone := &SelectionSet{
+		Selections: make([]*Selection, 0, len(selectionSet.Selections)),
+		Fragments:  make([]*Fragment, 0, len(selectionSet.Fragments)),
+	}
+	for _, selection := range selectionSet.Selections {
+		copied := *selection
+		copied.SelectionSet = cloneSelectionSet(selection.SelectionSet)
+		clone.Selections = append(clone.Selections, &copied)
+	}
+	for _, fragment := range selectionSet.Fragments {
+		clone.Fragments = append(clone.Fragments, &Fragment{
+			On:           fragment.On,
+			SelectionSet: cloneSelectionSet(fragment.SelectionSet),
+		})
+	}
+	return clone
+}
@@ -0,0 +1,87 @@
+package graphql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+)
+
+func makeQueryCacheSchema() *graphql.Schema {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	return schema.MustBuild()
+}
+
+func TestQueryCache(t *testing.T) {
+	schema := makeQueryCacheSchema()
+	cache := graphql.NewQueryCache(2)
+
+	run := func(source string, vars map[string]interface{}) interface{} {
+		q, err := cache.Prepare(schema.Query, source, vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := graphql.Executor{}
+		result, err := e.Execute(context.Background(), schema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(result)
+	}
+
+	source := "query Mirror($value: int64) { mirror(value: $value) }"
+	assert.Equal(t, map[string]interface{}{"mirror": float64(-1)}, run(source, map[string]interface{}{"value": float64(1)}))
+	assert.Equal(t, map[string]interface{}{"mirror": float64(-1)}, run(source, map[string]interface{}{"value": float64(1)}))
+	assert.Equal(t, 1, cache.Len())
+
+	// Different variables are cached separately.
+	assert.Equal(t, map[string]interface{}{"mirror": float64(-2)}, run(source, map[string]interface{}{"value": float64(2)}))
+	assert.Equal(t, 2, cache.Len())
+
+	// The least recently used query is evicted.
+	assert.Equal(t, map[string]interface{}{"mirror": float64(-3)}, run("{ mirror(value: 3) }", nil))
+	assert.Equal(t, 2, cache.Len())
+
+	// Invalid queries are not cached.
+	if _, err := cache.Prepare(schema.Query, "{ missing }", nil); err == nil || err.Error() != `unknown field "missing"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	assert.Equal(t, 2, cache.Len())
+}
+
+func BenchmarkQueryCache(b *testing.B) {
+	schema := makeQueryCacheSchema()
+	source := "query Mirror($value: int64) { a: mirror(value: $value) b: mirror(value: 2) c: mirror(value: 3) }"
+	vars := map[string]interface{}{"value": float64(1)}
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			q, err := graphql.Parse(source, vars)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cache := graphql.NewQueryCache(10)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := cache.Prepare(schema.Query, source, vars); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
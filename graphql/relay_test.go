@@ -0,0 +1,88 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayNode(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	type Item struct {
+		Id    int64
+		Title string
+	}
+	users := map[int64]*User{
+		1: {Id: 1, Name: "Alice"},
+		2: {Id: 2, Name: "Bob"},
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.EnableRelayNode()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return users[1]
+	})
+	query.FieldFunc("item", func() Item {
+		return Item{Id: 1, Title: "Book"}
+	})
+
+	user := schema.Object("User", User{})
+	user.Key("id")
+	user.NodeResolver(func(ctx context.Context, key string) (interface{}, error) {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return users[id], nil
+	})
+
+	item := schema.Object("Item", Item{})
+	item.Key("id")
+
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(val), err
+	}
+
+	bobID := base64.StdEncoding.EncodeToString([]byte("User:2"))
+
+	val, err := execute(`{ me { id name } item { id } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{
+		"me": {"__key": 1, "id": "`+base64.StdEncoding.EncodeToString([]byte("User:1"))+`", "name": "Alice"},
+		"item": {"__key": 1, "id": "`+base64.StdEncoding.EncodeToString([]byte("Item:1"))+`"}
+	}`), val)
+
+	val, err = execute(`{ node(id: "` + bobID + `") { __typename id ... on User { name } } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{
+		"node": {"__key": 2, "__typename": "User", "id": "`+bobID+`", "name": "Bob"}
+	}`), val)
+
+	_, err = execute(`{ node(id: "` + base64.StdEncoding.EncodeToString([]byte("Item:1")) + `") { id } }`)
+	if err == nil || err.Error() != "node: unknown node type Item" {
+		t.Errorf("expected unknown node type error, got %v", err)
+	}
+
+	_, err = execute(`{ node(id: "garbage") { id } }`)
+	if err == nil || err.Error() != "node: malformed id" {
+		t.Errorf("expected malformed id error, got %v", err)
+	}
+}
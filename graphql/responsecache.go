@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ResponseCache stores the serialized responses to cacheable queries, keyed by
+// a hash of the query, its variables and the cache scope of the request.
+// Responses expire after their ttl.
+type ResponseCache interface {
+	Get(key string) (data []byte, ok bool)
+	Set(key string, data []byte, ttl time.Duration)
+}
+
+// NewMemoryResponseCache returns a ResponseCache that keeps at most size
+// responses in memory, evicting the least recently used ones. Expired
+// responses are removed when they are looked up or evicted.
+func NewMemoryResponseCache(size int) ResponseCache {
+	return &memoryResponseCache{
+		size:      size,
+		responses: make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// cachedResponse is a response stored in a ResponseCache, with the cache hint
+// set while executing it and the time it was executed.
+type cachedResponse struct {
+	Data      json.RawMessage `json:"data"`
+	CacheHint *CacheHint      `json:"cacheHint,omitempty"`
+	Time      time.Time       `json:"time"`
+}
+
+type memoryResponse struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+type memoryResponseCache struct {
+	mu        sync.Mutex
+	size      int
+	responses map[string]*list.Element
+	lru       *list.List
+}
+
+func (c *memoryResponseCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.responses[key]
+	if !ok {
+		return nil, false
+	}
+	response := elem.Value.(*memoryResponse)
+	if !time.Now().Before(response.expires) {
+		c.lru.Remove(elem)
+		delete(c.responses, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return response.data, true
+}
+
+func (c *memoryResponseCache) Set(key string, data []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	response := &memoryResponse{key: key, data: data, expires: time.Now().Add(ttl)}
+	if elem, ok := c.responses[key]; ok {
+		elem.Value = response
+		c.lru.MoveToFront(elem)
+		return
+	}
+	if c.size <= 0 {
+		return
+	}
+	c.responses[key] = c.lru.PushFront(response)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.responses, oldest.Value.(*memoryResponse).key)
+	}
+}
+
+type responseCacheScopeKey struct{}
+
+// WithResponseCacheScope returns a context in which cached responses are only
+// shared with requests of the same scope, e.g. the tenant of the user. Without
+// a scope, responses are shared by all requests.
+func WithResponseCacheScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, responseCacheScopeKey{}, scope)
+}
+
+// responseCacheTTL returns how long the response of a query may be cached,
+// which is the shortest CacheTTL of its top-level fields, or 0 if any of them
+// isn't cacheable. Mutations are never cached.
+func responseCacheTTL(typ Type, query *Query) time.Duration {
+	object, ok := typ.(*Object)
+	if !ok || query.Kind == "mutation" || query.Kind == "subscription" {
+		return 0
+	}
+
+	var ttl time.Duration
+	for _, selection := range Flatten(query.SelectionSet) {
+		if selection.Name == "__typename" {
+			continue
+		}
+		field, ok := object.Fields[selection.Name]
+		if !ok || field.CacheTTL <= 0 {
+			return 0
+		}
+		if ttl == 0 || field.CacheTTL < ttl {
+			ttl = field.CacheTTL
+		}
+	}
+	return ttl
+}
+
+// responseCacheKey returns the key of the response to a query with its
+// variables in ctx's cache scope.
+func responseCacheKey(ctx context.Context, query string, variables map[string]interface{}) (string, error) {
+	// Maps are marshaled with sorted keys, so equal variables have equal keys.
+	marshaled, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	scope, _ := ctx.Value(responseCacheScopeKey{}).(string)
+
+	hash := sha256.New()
+	for _, part := range []string{scope, query, string(marshaled)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
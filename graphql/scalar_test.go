@@ -0,0 +1,110 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+// Decimal is a fixed-point number with two decimal places.
+type Decimal struct {
+	Cents int64
+}
+
+func parseDecimal(value interface{}) (interface{}, error) {
+	asString, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	parts := strings.SplitN(asString, ".", 2)
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return nil, fmt.Errorf("bad decimal %s", asString)
+	}
+	cents, err := strconv.ParseInt(parts[0]+parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad decimal %s", asString)
+	}
+	return Decimal{Cents: cents}, nil
+}
+
+func serializeDecimal(value interface{}) interface{} {
+	d := value.(Decimal)
+	return fmt.Sprintf("%d.%02d", d.Cents/100, d.Cents%100)
+}
+
+func TestCustomScalar(t *testing.T) {
+	type Product struct {
+		Name  string
+		Price Decimal
+		Sale  *Decimal
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.RegisterScalar("Decimal", Decimal{}, parseDecimal, serializeDecimal)
+
+	query := schema.Query()
+	query.FieldFunc("products", func(args struct {
+		MaxPrice Decimal
+		Prices   []Decimal
+	}) []Product {
+		products := []Product{
+			{Name: "book", Price: Decimal{Cents: 1250}, Sale: &Decimal{Cents: 999}},
+			{Name: "pen", Price: Decimal{Cents: 199}},
+		}
+		var ret []Product
+		for _, product := range products {
+			if product.Price.Cents <= args.MaxPrice.Cents {
+				ret = append(ret, product)
+			}
+		}
+		return ret
+	})
+	builtSchema := schema.MustBuild()
+
+	execute := func(args string) (interface{}, error) {
+		q := graphql.MustParse(fmt.Sprintf(`{
+			products(%s) {
+				name
+				price
+				sale
+			}
+		}`, args), nil)
+		if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(`maxPrice: "20.00", prices: ["1.00"]`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"products": []interface{}{
+			map[string]interface{}{"name": "book", "price": "12.50", "sale": "9.99"},
+			map[string]interface{}{"name": "pen", "price": "1.99", "sale": (*Decimal)(nil)},
+		},
+	}, val)
+
+	val, err = execute(`maxPrice: "5.00", prices: []`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"products": []interface{}{
+			map[string]interface{}{"name": "pen", "price": "1.99", "sale": (*Decimal)(nil)},
+		},
+	}, val)
+
+	_, err = execute(`maxPrice: "5", prices: []`)
+	if err == nil || err.Error() != `error parsing args for "products": maxPrice: bad decimal 5` {
+		t.Errorf("bad error: %v", err)
+	}
+
+	assert.Contains(t, builtSchema.SDL(), "products(maxPrice: Decimal!, prices: [Decimal!]!): [Product!]!")
+	assert.Contains(t, builtSchema.SDL(), "scalar Decimal\n")
+}
@@ -0,0 +1,180 @@
+package schemabuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// EnableFederation exposes the schema as an Apollo Federation subgraph. The
+// built query object gets a _service field returning the schema's SDL, and an
+// _entities field resolving the objects registered with ResolveReference from
+// their representations. Entities are annotated with @key in the SDL. Other
+// objects with a key are annotated with @key(fields: "...", resolvable: false),
+// so that other subgraphs can reference them without resolving them here. As
+// the resolvable argument requires Federation 2, the SDL links the Federation 2
+// spec.
+//
+// Fields resolved by another subgraph are marked with the external tag option,
+// e.g. `graphql:"email;external"`, and annotated with @external in the SDL.
+func (s *Schema) EnableFederation() {
+	s.federation = true
+}
+
+// federationLink opts the SDL into Federation 2, importing the directives that
+// annotate the schema.
+const federationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key", "@external"])` + "\n\n"
+
+// addFederation adds the _service and _entities fields to the built schema.
+func (sb *schemaBuilder) addFederation(schema *graphql.Schema) error {
+	query, ok := schema.Query.(*graphql.Object)
+	if !ok {
+		return fmt.Errorf("bad query type %s: should be an object", schema.Query)
+	}
+
+	resolvers := make(map[string]ReferenceResolver)
+	entityNames := make(map[reflect.Type]string)
+	entity := &graphql.Union{
+		Name:  "_Entity",
+		Types: make(map[string]*graphql.Object),
+		ResolveType: func(value interface{}) string {
+			typ := reflect.TypeOf(value)
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+			return entityNames[typ]
+		},
+	}
+
+	// Add the entities in a stable order, so that errors are deterministic.
+	var typs []reflect.Type
+	for typ := range sb.objects {
+		typs = append(typs, typ)
+	}
+	sort.Slice(typs, func(i, j int) bool { return typs[i].String() < typs[j].String() })
+
+	for _, typ := range typs {
+		object := sb.objects[typ]
+		if object.referenceResolver == nil {
+			continue
+		}
+
+		if err := sb.buildStruct(typ); err != nil {
+			return err
+		}
+		built, ok := sb.types[typ].(*graphql.Object)
+		if !ok {
+			return fmt.Errorf("bad entity %s: should be an object", typ)
+		}
+		keyField, ok := sb.keyFields[built]
+		if !ok {
+			return fmt.Errorf("bad entity %s: should have a key", typ)
+		}
+
+		entity.Types[built.Name] = built
+		resolvers[built.Name] = object.referenceResolver
+		entityNames[typ] = built.Name
+		built.Directives = append(built.Directives, fmt.Sprintf(`@key(fields: "%s")`, keyField))
+	}
+	for built, keyField := range sb.keyFields {
+		if _, ok := entity.Types[built.Name]; !ok {
+			built.Directives = append(built.Directives, fmt.Sprintf(`@key(fields: "%s", resolvable: false)`, keyField))
+		}
+	}
+
+	// The SDL describes the schema without the federation fields; the gateway
+	// adds those itself.
+	sdl := federationLink + schema.SDL()
+
+	service := &graphql.Object{
+		Name: "_Service",
+		Fields: map[string]*graphql.Field{
+			"sdl": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+					return sdl, nil
+				},
+				Type:           &graphql.NonNull{Type: &graphql.Scalar{Type: "string"}},
+				ParseArguments: nilParseArguments,
+			},
+		},
+	}
+	query.Fields["_service"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			return struct{}{}, nil
+		},
+		Type:           &graphql.NonNull{Type: service},
+		ParseArguments: nilParseArguments,
+	}
+
+	if len(resolvers) == 0 {
+		return nil
+	}
+
+	query.Fields["_entities"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			representations := args.([]map[string]interface{})
+			entities := make([]interface{}, len(representations))
+			for i, representation := range representations {
+				typename := representation["__typename"].(string)
+				value, err := resolvers[typename](ctx, representation)
+				if err != nil {
+					return nil, err
+				}
+				if value != nil && entity.ResolveType(value) != typename {
+					return nil, fmt.Errorf("reference resolver for %s returned %T", typename, value)
+				}
+				entities[i] = value
+			}
+			return entities, nil
+		},
+		Type: &graphql.NonNull{Type: &graphql.List{Type: entity}},
+		Args: map[string]graphql.Type{
+			"representations": &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: &graphql.Scalar{Type: "_Any"}}}},
+		},
+		ParseArguments: func(json interface{}) (interface{}, error) {
+			return parseRepresentations(json, resolvers)
+		},
+		Expensive: true,
+	}
+	return nil
+}
+
+// parseRepresentations parses the representations arg of _entities, checking
+// that every representation names a known entity.
+func parseRepresentations(json interface{}, resolvers map[string]ReferenceResolver) ([]map[string]interface{}, error) {
+	args, ok := json.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not an object")
+	}
+	for name := range args {
+		if name != "representations" {
+			return nil, fmt.Errorf("unknown arg %s", name)
+		}
+	}
+
+	list, ok := args["representations"].([]interface{})
+	if !ok {
+		return nil, errors.New("representations: not a list")
+	}
+
+	representations := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		representation, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("representations: not an object")
+		}
+		typename, ok := representation["__typename"].(string)
+		if !ok {
+			return nil, errors.New("representations: missing __typename")
+		}
+		if _, ok := resolvers[typename]; !ok {
+			return nil, fmt.Errorf("representations: unknown entity %s", typename)
+		}
+		representations = append(representations, representation)
+	}
+	return representations, nil
+}
@@ -0,0 +1,133 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// interfaceMapping is a Go interface type registered as a GraphQL interface.
+type interfaceMapping struct {
+	name        string
+	resolveType func(value interface{}) string
+}
+
+// RegisterInterface registers an interface named name for the Go interface type
+// that ifaceValue points to. All registered objects whose Go type, or a pointer
+// to it, implements the Go interface implement the GraphQL interface, which has
+// the fields that all of them have in common. Fields returning the Go
+// interface type return the GraphQL interface, and resolveType picks the name
+// of the object type of their values. If resolveType is nil, the object type is
+// picked by the value's Go type.
+//
+// For example a Node interface could be registered as:
+//   type Node interface {
+//     isNode()
+//   }
+//
+//   s.RegisterInterface("Node", (*Node)(nil), func(value interface{}) string {
+//     switch value.(type) {
+//     case *User:
+//       return "User"
+//     default:
+//       return "Item"
+//     }
+//   })
+func (s *Schema) RegisterInterface(name string, ifaceValue interface{}, resolveType func(interface{}) string) {
+	typ := reflect.TypeOf(ifaceValue)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Interface {
+		panic("interface should be passed as a pointer to an interface")
+	}
+
+	if s.interfaces == nil {
+		s.interfaces = make(map[reflect.Type]*interfaceMapping)
+	}
+	s.interfaces[typ.Elem()] = &interfaceMapping{
+		name:        name,
+		resolveType: resolveType,
+	}
+}
+
+// buildInterface builds the interface for typ with all objects implementing it.
+// The fields of the interface are only known once the objects are built, and
+// are added by addInterfaceFields.
+func (sb *schemaBuilder) buildInterface(typ reflect.Type) error {
+	if sb.types[typ] != nil {
+		return nil
+	}
+	mapping := sb.interfaces[typ]
+
+	objectNames := make(map[reflect.Type]string)
+	iface := &graphql.Interface{
+		Name:   mapping.name,
+		Fields: make(map[string]*graphql.Field),
+		Types:  make(map[string]*graphql.Object),
+		ResolveType: func(value interface{}) string {
+			if mapping.resolveType != nil {
+				return mapping.resolveType(value)
+			}
+			typ := reflect.TypeOf(value)
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+			return objectNames[typ]
+		},
+	}
+	sb.types[typ] = iface
+	sb.builtInterfaces = append(sb.builtInterfaces, iface)
+
+	for objectTyp := range sb.objects {
+		if !objectTyp.Implements(typ) && !reflect.PtrTo(objectTyp).Implements(typ) {
+			continue
+		}
+
+		if err := sb.buildStruct(objectTyp); err != nil {
+			return err
+		}
+		object, ok := sb.types[objectTyp].(*graphql.Object)
+		if !ok {
+			return fmt.Errorf("bad interface %s: implementation %s should be an object", mapping.name, objectTyp)
+		}
+
+		if object.Interfaces == nil {
+			object.Interfaces = make(map[string]*graphql.Interface)
+		}
+		object.Interfaces[iface.Name] = iface
+		iface.Types[object.Name] = object
+		objectNames[objectTyp] = object.Name
+	}
+
+	if len(iface.Types) == 0 {
+		return fmt.Errorf("bad interface %s: should have an implementation", mapping.name)
+	}
+	return nil
+}
+
+// addInterfaceFields adds the fields all implementations of an interface have
+// in common, with the same type, to the interface.
+func (sb *schemaBuilder) addInterfaceFields() {
+	for _, iface := range sb.builtInterfaces {
+		var names []string
+		for name := range iface.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		first := iface.Types[names[0]]
+		for fieldName, field := range first.Fields {
+			shared := true
+			for _, name := range names[1:] {
+				other, ok := iface.Types[name].Fields[fieldName]
+				if !ok || other.Type.String() != field.Type.String() {
+					shared = false
+					break
+				}
+			}
+			if shared {
+				iface.Fields[fieldName] = field
+			}
+		}
+	}
+}
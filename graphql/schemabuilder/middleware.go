@@ -0,0 +1,101 @@
+package schemabuilder
+
+import (
+	"context"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// FieldInfo describes the field resolved by a FieldMiddleware.
+type FieldInfo struct {
+	// Type is the name of the object the field belongs to, e.g. "User", or
+	// "UserConnection" for the fields of connections.
+	Type string
+	// Name is the name of the field.
+	Name         string
+	SelectionSet *graphql.SelectionSet
+}
+
+// ResolveFunc resolves a field on source with its parsed args.
+type ResolveFunc func(ctx context.Context, field FieldInfo, source, args interface{}) (interface{}, error)
+
+// A FieldMiddleware wraps the resolvers of all fields of a schema, see
+// Schema.Use.
+type FieldMiddleware func(next ResolveFunc) ResolveFunc
+
+// Use adds a middleware wrapping the resolver of every field of the schema,
+// including the fields of connections, e.g. for logging or metrics. The
+// middleware calls next to resolve the field. Middlewares run in the order
+// they are added, so the first one wraps all others, e.g.
+//    schema.Use(func(next schemabuilder.ResolveFunc) schemabuilder.ResolveFunc {
+//        return func(ctx context.Context, field schemabuilder.FieldInfo, source, args interface{}) (interface{}, error) {
+//            start := time.Now()
+//            defer func() { metrics.Observe(field.Type+"."+field.Name, time.Since(start)) }()
+//            return next(ctx, field, source, args)
+//        }
+//    })
+func (s *Schema) Use(middleware FieldMiddleware) {
+	s.middlewares = append(s.middlewares, middleware)
+}
+
+// applyMiddlewares wraps the resolvers of the fields of all objects reachable
+// from the schema's root types with middlewares.
+func applyMiddlewares(schema *graphql.Schema, middlewares []FieldMiddleware) {
+	if len(middlewares) == 0 {
+		return
+	}
+
+	visited := make(map[graphql.Type]bool)
+	wrapped := make(map[*graphql.Field]bool)
+	var visit func(typ graphql.Type)
+	visit = func(typ graphql.Type) {
+		if typ == nil || visited[typ] {
+			return
+		}
+		visited[typ] = true
+
+		switch typ := typ.(type) {
+		case *graphql.NonNull:
+			visit(typ.Type)
+		case *graphql.List:
+			visit(typ.Type)
+		case *graphql.Union:
+			for _, member := range typ.Types {
+				visit(member)
+			}
+		case *graphql.Interface:
+			// The fields of interfaces are shared with their objects, which are
+			// executed instead.
+			for _, object := range typ.Types {
+				visit(object)
+			}
+		case *graphql.Object:
+			for name, field := range typ.Fields {
+				if !wrapped[field] {
+					wrapped[field] = true
+					wrapResolver(field, typ.Name, name, middlewares)
+				}
+				visit(field.Type)
+			}
+		}
+	}
+
+	visit(schema.Query)
+	visit(schema.Mutation)
+	visit(schema.Subscription)
+}
+
+// wrapResolver wraps the resolver of a field with middlewares.
+func wrapResolver(field *graphql.Field, typeName string, name string, middlewares []FieldMiddleware) {
+	resolve := field.Resolve
+	next := ResolveFunc(func(ctx context.Context, info FieldInfo, source, args interface{}) (interface{}, error) {
+		return resolve(ctx, source, args, info.SelectionSet)
+	})
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+
+	field.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+		return next(ctx, FieldInfo{Type: typeName, Name: name, SelectionSet: selectionSet}, source, args)
+	}
+}
@@ -3,22 +3,39 @@ package schemabuilder
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/samsarahq/thunder/graphql"
 )
 
 // Connection conforms to the GraphQL Connection type in the Relay Pagination spec.
+// TotalCountEstimate is only exposed on paginated fields configured with
+// Paginated.WithTotalCountEstimate.
 type Connection struct {
-	TotalCount int64
-	Edges      []Edge
-	PageInfo   PageInfo
+	TotalCount         int64
+	TotalCountEstimate *int64
+	Edges              []Edge
+	PageInfo           PageInfo
+
+	// totalCountFunc lazily computes TotalCount when the totalCount field is selected.
+	totalCountFunc func() int64
+	// totalCountStringFunc lazily computes the total count of string types.
+	totalCountStringFunc func() string
 }
 
 // PageInfo contains information for pagination on a connection type. The list of Pages is used for
-// page-number based pagination where the ith index corresponds to the start cursor of (i+1)st page.
+// page-number based pagination where the ith index corresponds to the cursor of the (i+1)st page.
+// When paginating forward this is the after cursor of the page, and when paginating backward (with
+// last and without first) this is the before cursor of the page.
 type PageInfo struct {
 	HasNextPage bool
 	EndCursor   string
@@ -27,50 +44,302 @@ type PageInfo struct {
 	Pages       []string
 }
 
-// Edge consists of a node paired with its b64 encoded cursor.
+// Edge consists of a node paired with its b64 encoded cursor. Highlight is only set if the
+// paginated field was configured with an EdgeHighlighter, and Score is only set if the resolver
+// returned ScoredNodes.
+//
+// Externally managed paginated field funcs, which embed PaginationArgs and return PaginationInfo,
+// can also return a []Edge, configured with Paginated.WithNodeType, to supply the cursor of every
+// node themselves. The edges are then returned as is, and the nodes need no Key.
+//
+// Paginated field funcs can also return a slice of edge structs that embed Edge, configured with
+// Paginated.WithNodeType, to expose data that belongs to the edge rather than to the node, e.g. the
+// role of a user in a group:
+//   type Membership struct {
+//     schemabuilder.Edge
+//     Role string
+//   }
+// The other exported fields of the edge struct are exposed on the edge type, which is named after
+// the edge struct (MembershipEdge, in a MembershipConnection). Only the Node of the embedded Edge is
+// used; the cursor is computed as usual.
 type Edge struct {
-	Node   interface{}
-	Cursor string
+	Node      interface{}
+	Cursor    string
+	Highlight *Highlight
+	Score     *float64
+
+	// value is the edge struct returned by the resolver, if any, from which the extra fields of
+	// the edge type are resolved.
+	value interface{}
+}
+
+// ScoredNode pairs a node with its relevance score. Paginated field funcs can return a slice of
+// ScoredNodes, configured with Paginated.WithNodeType, to order the edges by descending score. The
+// score is exposed as the score field on the edges, and is part of their cursors.
+type ScoredNode struct {
+	Node  interface{}
+	Score float64
 }
 
+// Highlight contains the snippets of a node that matched a search query. It is exposed as the
+// highlight field on edges of paginated fields configured with an EdgeHighlighter.
+type Highlight struct {
+	Snippets []string
+}
+
+// EdgeHighlighter computes the Highlight of a node for the given search query. It is called for
+// every returned edge after pagination has been applied.
+type EdgeHighlighter func(ctx context.Context, node interface{}, query string) Highlight
+
 // ConnectionArgs conform to the pagination arguments as specified by the Relay Spec for Connection
 // types. The Args field consits of the user-facing args.
 type ConnectionArgs struct {
-	First  *int64
-	Last   *int64
-	After  *string
-	Before *string
-	Args   interface{}
+	First   *int64
+	Last    *int64
+	After   *string
+	Before  *string
+	OrderBy *string `graphql:"-"`
+	Args    interface{}
 }
 
-// PaginationArgs are embedded in a struct
+// PaginationArgs are embedded in a struct. OrderBy is the sort field chosen with the orderBy arg on
+// paginated fields configured with Paginated.WithSortFields, or nil if the nodes are ordered by
+// their key. The args keep the names of the Relay spec with a field name mapper.
 type PaginationArgs struct {
-	First  *int64
-	Last   *int64
-	After  *string
-	Before *string
+	First   *int64  `graphql:"first"`
+	Last    *int64  `graphql:"last"`
+	After   *string `graphql:"after"`
+	Before  *string `graphql:"before"`
+	OrderBy *string `graphql:"-"`
+
+	// cursorType is the type of the node field the cursors are built from, and scoredCursor is set
+	// if the cursors are prefixed with a score.
+	cursorType   reflect.Type
+	scoredCursor bool
+
+	// sortField is the name of the Go field of the sort field chosen with orderBy.
+	sortField string
+
+	// stableSort is set if the nodes are sorted by their key before the cursors are built, see
+	// Paginated.WithStableSort.
+	stableSort bool
+
+	// overfetch is set if the resolver applies the cursors itself and returns an extra node to
+	// indicate that more nodes exist, see Paginated.WithOverfetch.
+	overfetch bool
+
+	// relativePages is set if the pages are anchored to the after or before cursor, see
+	// Paginated.WithRelativePages.
+	relativePages bool
+}
+
+// AfterKey decodes the after cursor into the value of the node field it was built from, i.e. the
+// node's key, or the sort field chosen with orderBy. The cursors of sorted nodes also hold their
+// key, so that nodes with equal sort values get distinct cursors, but only the sort value is
+// returned. ok is false if there is no after cursor.
+func (p PaginationArgs) AfterKey() (key interface{}, ok bool, err error) {
+	return p.decodeCursor(p.After)
+}
+
+// BeforeKey decodes the before cursor into the value of the node field it was built from, i.e.
+// the node's key, or the sort field chosen with orderBy. ok is false if there is no before cursor.
+func (p PaginationArgs) BeforeKey() (key interface{}, ok bool, err error) {
+	return p.decodeCursor(p.Before)
+}
+
+// decodeCursor reverses the cursor encoding of getConnection.
+func (p PaginationArgs) decodeCursor(cursor *string) (interface{}, bool, error) {
+	if cursor == nil || *cursor == "" {
+		return nil, false, nil
+	}
+	if p.cursorType == nil {
+		return nil, true, errors.New("cursor type is unknown")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(normalizeCursor(*cursor))
+	if err != nil {
+		return nil, true, graphql.NewClientError("bad cursor: %s", err)
+	}
+	keyString := string(decoded)
+	if p.sortField != "" {
+		var pair []string
+		if err := json.Unmarshal(decoded, &pair); err != nil || len(pair) != 2 {
+			return nil, true, graphql.NewClientError("bad cursor: expected sort value and key")
+		}
+		keyString = pair[0]
+	}
+	if p.scoredCursor {
+		i := strings.Index(keyString, ":")
+		if i == -1 {
+			return nil, true, graphql.NewClientError("bad cursor: missing score")
+		}
+		keyString = keyString[i+1:]
+	}
+
+	key, err := parseCursorKey(keyString, p.cursorType)
+	if err != nil {
+		return nil, true, graphql.NewClientError("bad cursor: %s", err)
+	}
+	return key, true, nil
+}
+
+// formatCursorKey formats the value of the node field a cursor is built from. Times are formatted
+// as RFC 3339 in UTC, so that equal times get equal cursors regardless of their location or
+// monotonic clock reading.
+func formatCursorKey(value reflect.Value) string {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	}
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parseCursorKey parses the formatted value of a cursor's node field back into a value of typ.
+func parseCursorKey(s string, typ reflect.Type) (interface{}, error) {
+	if typ == timeType {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		value.SetBool(b)
+	default:
+		return nil, fmt.Errorf("can't decode a cursor into %s", typ)
+	}
+	return value.Interface(), nil
 }
 
 // PaginationInfo can be returned in a PaginateFieldFunc. The TotalCount function returns the
-// totalCount field on the connection Type. If the resolver makes a SQL Query, then HasNextPage and
-// HasPrevPage can be resolved in an efficient manner by requesting first/last:n + 1 items in the
-// query. Then the flags can be filled in by checking the result size.
+// totalCount field on the connection Type, and is only called if the field is selected. If the
+// resolver makes a SQL Query, then HasNextPage and HasPrevPage can be resolved in an efficient
+// manner by requesting first/last:n + 1 items in the query. Then the flags can be filled in by
+// checking the result size.
+// The EstimatedTotalCount function returns the totalCountEstimate field, which is cheaper to compute
+// than an exact count for large tables, e.g. from table statistics.
+// The TotalCountString function returns the totalCount field instead of TotalCount if the field's
+// type is a string type configured with Paginated.WithTotalCountType, e.g. for counts that don't fit
+// an int64 or that are formatted estimates.
 type PaginationInfo struct {
-	TotalCount  func() int64
-	HasNextPage bool
-	HasPrevPage bool
+	TotalCount          func() int64
+	EstimatedTotalCount func() int64
+	TotalCountString    func() string
+	HasNextPage         bool
+	HasPrevPage         bool
+}
+
+// EdgeSource lazily produces the nodes of a paginated field, for datasets too large to load before
+// they are paginated. Paginated field funcs can return an EdgeSource, configured with
+// Paginated.WithNodeType, instead of a slice of nodes. They must embed PaginationArgs, and start
+// the source after the after cursor themselves. Next is then called for at most first+1 nodes: the
+// extra node only tells that there is a next page. Next returns false once there are no more nodes.
+// The cursors are used as returned, and are typically encoded by the source.
+//
+// Edge sources only support paginating forward with first and after. The total count is only
+// exposed if the field func also returns PaginationInfo. If the source implements io.Closer, it
+// is closed once the page is pulled.
+type EdgeSource interface {
+	Next() (node interface{}, cursor string, ok bool)
+}
+
+var edgeSourceType = reflect.TypeOf((*EdgeSource)(nil)).Elem()
+
+var connectionPtrType = reflect.TypeOf(&Connection{})
+var scoredNodesType = reflect.TypeOf([]ScoredNode{})
+var edgeType = reflect.TypeOf(Edge{})
+var edgesType = reflect.TypeOf([]Edge{})
+
+// isEdgeStruct returns if typ is an edge struct, i.e. a struct embedding Edge.
+func isEdgeStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.Anonymous && field.Type == edgeType {
+			return true
+		}
+	}
+	return false
 }
 
+// getTypeName returns the name of the node type typ that the names of its
+// connection types are derived from. Nodes and pointers to nodes get the same
+// name.
 func getTypeName(typ reflect.Type) string {
 	if typ.Kind() == reflect.Ptr {
 		return typ.Elem().Name()
 	}
-	return fmt.Sprintf("NonNull%s", typ.Name())
+	return typ.Name()
+}
+
+// getEdgeIndex returns the index of the embedded Edge in the edge struct typ.
+func getEdgeIndex(typ reflect.Type) []int {
+	field, _ := typ.FieldByName("Edge")
+	return field.Index
+}
+
+// getConnectionTypeName returns the name that the connection, edge and orderBy types of nodes of
+// type typ are derived from. Connections of edge structs are named after the edge struct instead,
+// since their edge types have extra fields. A name set with Paginated.WithConnectionName takes
+// precedence.
+func getConnectionTypeName(typ reflect.Type, edgeStructType reflect.Type, m *method) string {
+	if m.ConnectionName != "" {
+		return m.ConnectionName
+	}
+	if edgeStructType != nil {
+		return strings.TrimSuffix(edgeStructType.Name(), "Edge")
+	}
+	return getTypeName(typ)
 }
 
-// constructEdgeType wraps the typ (which is the type of the Node) in an Edge type conforming to the
-// Relay spec.
-func (sb *schemaBuilder) constructEdgeType(typ reflect.Type) (graphql.Type, error) {
+// constructEdgeType wraps the typ (which is the type of the Node) in an Edge type named name
+// conforming to the Relay spec. If withHighlight is set, the edge type also exposes the highlight field, and if
+// withScore is set, the score field. If edgeStructType is set, the edge type also exposes the
+// fields of the edge struct.
+func (sb *schemaBuilder) constructEdgeType(name string, typ reflect.Type, withHighlight bool, withScore bool, edgeStructType reflect.Type) (graphql.Type, error) {
+	// Edges always have a node, so the node is non-null for both nodes and
+	// pointers to nodes, and both get identical edge types.
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
 	nodeType, err := sb.getType(typ)
 	if err != nil {
 		return nil, err
@@ -110,9 +379,51 @@ func (sb *schemaBuilder) constructEdgeType(typ reflect.Type) (graphql.Type, erro
 
 	fieldMap["cursor"] = cursorField
 
+	if withHighlight {
+		highlightType, err := sb.getType(reflect.TypeOf(Highlight{}))
+		if err != nil {
+			return nil, err
+		}
+
+		fieldMap["highlight"] = &graphql.Field{
+			Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				if value, ok := source.(Edge); ok && value.Highlight != nil {
+					return *value.Highlight, nil
+				}
+				return nil, fmt.Errorf("error resolving highlight in edge")
+			},
+			Type:           highlightType,
+			ParseArguments: nilParseArguments,
+		}
+	}
+
+	if withScore {
+		scoreType, err := sb.getType(reflect.TypeOf(float64(0)))
+		if err != nil {
+			return nil, err
+		}
+
+		fieldMap["score"] = &graphql.Field{
+			Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				if value, ok := source.(Edge); ok && value.Score != nil {
+					return *value.Score, nil
+				}
+				return nil, fmt.Errorf("error resolving score in edge")
+			},
+			Type:           scoreType,
+			ParseArguments: nilParseArguments,
+		}
+	}
+
+	if edgeStructType != nil {
+		if err := sb.addEdgeStructFields(fieldMap, edgeStructType); err != nil {
+			return nil, err
+		}
+	}
+
 	return &graphql.NonNull{
 		Type: &graphql.Object{
-			Name:        fmt.Sprintf("%sEdge", getTypeName(typ)),
+			Name:        name,
 			Description: "",
 			Fields:      fieldMap,
 		},
@@ -120,8 +431,84 @@ func (sb *schemaBuilder) constructEdgeType(typ reflect.Type) (graphql.Type, erro
 
 }
 
+// convertTotalCount changes the type of the totalCount field to the type that convert, a
+// func(int64) T, converts the count to. Counts of string types can also be returned by
+// PaginationInfo.TotalCountString.
+func (sb *schemaBuilder) convertTotalCount(countField *graphql.Field, converter interface{}) error {
+	convert := reflect.ValueOf(converter)
+	if convert.Kind() != reflect.Func || convert.Type().NumIn() != 1 || convert.Type().In(0) != reflect.TypeOf(int64(0)) || convert.Type().NumOut() != 1 {
+		return fmt.Errorf("total count type should be configured with a func(int64) T, not %T", converter)
+	}
+	countType := convert.Type().Out(0)
+	builtType, err := sb.getType(countType)
+	if err != nil {
+		return fmt.Errorf("bad total count type: %s", err)
+	}
+
+	resolve := countField.Resolve
+	countField.Type = builtType
+	countField.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+		if value, ok := source.(Connection); ok && value.totalCountStringFunc != nil && countType.Kind() == reflect.String {
+			return reflect.ValueOf(value.totalCountStringFunc()).Convert(countType).Interface(), nil
+		}
+		count, err := resolve(ctx, source, args, selectionSet)
+		if err != nil {
+			return nil, err
+		}
+		return convert.Call([]reflect.Value{reflect.ValueOf(count)})[0].Interface(), nil
+	}
+	return nil
+}
+
+// addEdgeStructFields adds the exported fields of the edge struct typ to the fields of its edge
+// type. They are resolved from the edge struct returned by the resolver.
+func (sb *schemaBuilder) addEdgeStructFields(fieldMap map[string]*graphql.Field, typ reflect.Type) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || (field.Anonymous && field.Type == edgeType) {
+			continue
+		}
+
+		tag, options, err := parseTagOptions(field.Tag.Get("graphql"))
+		if err != nil {
+			return fmt.Errorf("bad edge type %s: %s", typ, err)
+		}
+		name := tag
+		if name == "" {
+			name = sb.fieldName(field.Name)
+		}
+		if name == "-" {
+			continue
+		}
+		for option := range options {
+			if option != "description" && option != "deprecated" {
+				return fmt.Errorf("bad edge type %s: field %s has unexpected tag option %s", typ, name, option)
+			}
+		}
+		if _, ok := fieldMap[name]; ok {
+			return fmt.Errorf("bad edge type %s: field %s conflicts with the %s field of edges", typ, name, name)
+		}
+
+		built, err := sb.buildField(field)
+		if err != nil {
+			return fmt.Errorf("bad field %s on edge type %s: %s", name, typ, err)
+		}
+		resolve := built.Resolve
+		built.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			if value, ok := source.(Edge); ok && value.value != nil {
+				return resolve(ctx, value.value, args, selectionSet)
+			}
+			return nil, fmt.Errorf("error resolving %s in edge", name)
+		}
+		built.Description = options["description"]
+		built.DeprecationReason = options["deprecated"]
+		fieldMap[name] = built
+	}
+	return nil
+}
+
 // constructConnType wraps typ (type of the Node) in a Connection Type conforming to the Relay spec.
-func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Type, returnsPageInfo bool) (graphql.Type, error) {
+func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Type, returnsPageInfo bool, returnsScores bool, edgeStructType reflect.Type, m *method) (graphql.Type, error) {
 	fieldMap := make(map[string]*graphql.Field)
 
 	countType, _ := reflect.TypeOf(Connection{}).FieldByName("TotalCount")
@@ -130,13 +517,48 @@ func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Typ
 		return nil, err
 	}
 
-	fieldMap["totalCount"] = countField
-	edgeType, err := sb.constructEdgeType(typ)
+	// The total count of resolvers returning PaginationInfo is only computed if the totalCount
+	// field is selected, since it usually requires a separate COUNT query.
+	countField.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+		if value, ok := source.(Connection); ok {
+			if value.totalCountFunc != nil {
+				return value.totalCountFunc(), nil
+			}
+			if value.totalCountStringFunc != nil {
+				return nil, fmt.Errorf("TotalCountString requires a string total count type configured with Paginated.WithTotalCountType")
+			}
+			return value.TotalCount, nil
+		}
+		return nil, fmt.Errorf("error resolving totalCount in connection")
+	}
+	if m.TotalCountConverter != nil {
+		if err := sb.convertTotalCount(countField, m.TotalCountConverter); err != nil {
+			return nil, err
+		}
+	}
+
+	// The total count of overfetched nodes and of edge sources is unknown, unless the resolver
+	// returns it with PaginationInfo.
+	if !(m.Overfetch || funcCtx.returnsEdgeSource) || returnsPageInfo {
+		fieldMap["totalCount"] = countField
+	}
+
+	if m.TotalCountEstimate {
+		estimateType, _ := reflect.TypeOf(Connection{}).FieldByName("TotalCountEstimate")
+		estimateField, err := sb.buildField(estimateType)
+		if err != nil {
+			return nil, err
+		}
+		fieldMap["totalCountEstimate"] = estimateField
+	}
+
+	name := getConnectionTypeName(typ, edgeStructType, m)
+	edgeObjType, err := sb.constructEdgeType(name+"Edge", typ, m.EdgeHighlighter != nil, returnsScores, edgeStructType)
 	if err != nil {
 		return nil, err
 	}
 
-	edgesSliceType := &graphql.NonNull{Type: &graphql.List{Type: edgeType}}
+	edgesSliceType := &graphql.NonNull{Type: &graphql.List{Type: edgeObjType}}
 
 	edgesSliceField := &graphql.Field{
 		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
@@ -151,6 +573,33 @@ func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Typ
 
 	fieldMap["edges"] = edgesSliceField
 
+	nodeType, err := sb.getType(typ)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := nodeType.(*graphql.NonNull); !ok {
+		nodeType = &graphql.NonNull{Type: nodeType}
+	}
+
+	// The nodes field is a shortcut for edges { node }, and so is resolved from the same sliced
+	// edges.
+	nodesSliceField := &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			if value, ok := source.(Connection); ok {
+				nodes := make([]interface{}, 0, len(value.Edges))
+				for _, edge := range value.Edges {
+					nodes = append(nodes, edge.Node)
+				}
+				return nodes, nil
+			}
+			return nil, fmt.Errorf("error resolving nodes in connection")
+		},
+		Type:           &graphql.List{Type: nodeType},
+		ParseArguments: nilParseArguments,
+	}
+
+	fieldMap["nodes"] = nodesSliceField
+
 	pageInfoType, _ := reflect.TypeOf(Connection{}).FieldByName("PageInfo")
 	pageInfoField, err := sb.buildField(pageInfoType)
 	pageInfoNonNull, _ := pageInfoField.Type.(*graphql.NonNull)
@@ -158,9 +607,10 @@ func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Typ
 
 	// If a PaginateFieldFunc returns connection info then it means that the resolver needs to
 	// handle slicing according to the connection args. Hence, it's no longer feasible to determine
-	// the entire set of pages on the connection.
-	if returnsPageInfo {
-		delete(pageInfoObj.Fields, "pages")
+	// the entire set of pages on the connection. The PageInfo type is shared by all connections, so
+	// these connections use a copy without the pages field.
+	if returnsPageInfo || m.Overfetch || m.WithoutPages || funcCtx.returnsEdgeSource {
+		pageInfoField.Type = &graphql.NonNull{Type: sb.getPageInfoWithoutPages(pageInfoObj)}
 	}
 	if err != nil {
 		return nil, err
@@ -168,7 +618,7 @@ func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Typ
 	fieldMap["pageInfo"] = pageInfoField
 	retObject := &graphql.NonNull{
 		Type: &graphql.Object{
-			Name:        fmt.Sprintf("%sConnection", getTypeName(typ)),
+			Name:        name + "Connection",
 			Description: "",
 			Fields:      fieldMap,
 		},
@@ -176,50 +626,86 @@ func (funcCtx *funcContext) constructConnType(sb *schemaBuilder, typ reflect.Typ
 	return retObject, nil
 }
 
+// getPageInfoWithoutPages returns a copy of the PageInfo type without the pages field, for
+// connections whose pages can't be computed or are disabled.
+func (sb *schemaBuilder) getPageInfoWithoutPages(pageInfo *graphql.Object) *graphql.Object {
+	if sb.pageInfoWithoutPages == nil {
+		fields := make(map[string]*graphql.Field, len(pageInfo.Fields))
+		for name, field := range pageInfo.Fields {
+			if name != "pages" {
+				fields[name] = field
+			}
+		}
+		sb.pageInfoWithoutPages = &graphql.Object{
+			Name:        pageInfo.Name + "WithoutPages",
+			Description: pageInfo.Description,
+			Key:         pageInfo.Key,
+			Fields:      fields,
+		}
+	}
+	return sb.pageInfoWithoutPages
+}
+
 // EdgesToReturn returns the slice of edges by appyling the pagination arguments. It also returns
 // the hasNextPage and hasPrevPage values respectively. The behavior is expected to conform to the
 // Relay Cursor spec: https://facebook.github.io/relay/graphql/connections.htm#EdgesToReturn()
+//
+// Per the spec, hasNextPage is true if first is set and there are more than first edges between
+// the cursors, or if before is set and there are elements following before. Symmetrically,
+// hasPrevPage is true if last is set and there are more than last edges between the cursors, or if
+// after is set and there are elements prior to after.
 func EdgesToReturn(allEdges []Edge, before *string, after *string, first *int64, last *int64) ([]Edge, bool, bool, error) {
 	edges, elemsAfter, elemsBefore := applyCursorsToAllEdges(allEdges, before, after)
 
 	prevPage := false
 	nextPage := false
 
-	if first != nil {
-		if *first < 0 {
-			return nil, nextPage, prevPage, graphql.NewClientError("first should be a non-negative integer")
-		}
-		if len(edges) > int(*first) {
-			edges = edges[:int(*first)]
-			nextPage = true
-		}
+	if first != nil && *first < 0 {
+		return nil, nextPage, prevPage, graphql.NewClientError("first should be a non-negative integer")
 	}
-	if before != nil {
-		if elemsAfter {
-			nextPage = true
-		}
+	if last != nil && *last < 0 {
+		return nil, nextPage, prevPage, graphql.NewClientError("last should be a non-negative integer")
 	}
 
-	if last != nil {
-		if *last < 0 {
-			return nil, nextPage, prevPage, graphql.NewClientError("last should be a non-negative integer")
-		}
-		if len(edges) > int(*last) {
-			edges = edges[len(edges)-int(*last):]
-			prevPage = true
-		}
+	// Both flags are derived from the edges between the cursors, before first and last are
+	// applied.
+	if first != nil && len(edges) > int(*first) {
+		nextPage = true
 	}
-	if after != nil {
-		if elemsBefore {
-			prevPage = true
-		}
+	if before != nil && elemsAfter {
+		nextPage = true
+	}
+	if last != nil && len(edges) > int(*last) {
+		prevPage = true
+	}
+	if after != nil && elemsBefore {
+		prevPage = true
+	}
+
+	if first != nil && len(edges) > int(*first) {
+		edges = edges[:int(*first)]
+	}
+	if last != nil && len(edges) > int(*last) {
+		edges = edges[len(edges)-int(*last):]
 	}
 
 	return edges, nextPage, prevPage, nil
 }
 
+// stdToURLEncoding replaces the characters of the standard base64 alphabet
+// that differ from the URL-safe alphabet.
+var stdToURLEncoding = strings.NewReplacer("+", "-", "/", "_")
+
+// normalizeCursor converts a cursor to the URL-safe base64 alphabet cursors are
+// encoded with. Cursors used to be encoded with the standard alphabet, which
+// clients may still send.
+func normalizeCursor(cursor string) string {
+	return stdToURLEncoding.Replace(cursor)
+}
+
 // getCursorIndex returns the index corresponding to the cursor in the slice.
 func getCursorIndex(edges []Edge, cursor string) int {
+	cursor = normalizeCursor(cursor)
 	for i, val := range edges {
 		if val.Cursor == cursor {
 			return i
@@ -230,7 +716,7 @@ func getCursorIndex(edges []Edge, cursor string) int {
 
 // applyCursorsToAllEdges returns the slice of edges after applying the after and before arguments.
 // It also implements part of the hasNextPage and hasPrevPage algorithm by returning if there are
-// elements after or before the arguments.
+// elements following before or prior to after in allEdges.
 func applyCursorsToAllEdges(allEdges []Edge, before *string, after *string) ([]Edge, bool, bool) {
 	edges := allEdges
 
@@ -238,28 +724,89 @@ func applyCursorsToAllEdges(allEdges []Edge, before *string, after *string) ([]E
 	elemsBefore := false
 
 	if after != nil {
-		i := getCursorIndex(edges, *after)
+		i := getCursorIndex(allEdges, *after)
 		if i != -1 {
 			edges = edges[i+1:]
-			if i != 0 {
-				elemsBefore = true
-			}
+			elemsBefore = i > 0
 		}
-
 	}
 	if before != nil {
-		i := getCursorIndex(edges, *before)
+		i := getCursorIndex(allEdges, *before)
 		if i != -1 {
+			elemsAfter = i < len(allEdges)-1
+		}
+		if i := getCursorIndex(edges, *before); i != -1 {
 			edges = edges[:i]
-			if i != len(allEdges)-1 {
-				elemsAfter = true
-			}
 		}
-
 	}
 
 	return edges, elemsAfter, elemsBefore
+}
+
+// getPages returns the cursors used for page-number based pagination over allEdges. The pages are
+// computed in the direction of traversal given by args:
+//
+// When paginating forward (first/after), the pages are grouped from the start of the list and the
+// ith entry is the after cursor that returns the (i+1)st page. The first entry is always "".
+//
+// When paginating backward (last/before), the pages are grouped from the end of the list and the
+// ith entry is the before cursor that returns the (i+1)st page. The last entry is always "".
+//
+// With relative pages the pages are instead grouped around the after (or before) cursor of the
+// current page, which is always one of the entries. See Paginated.WithRelativePages.
+func getPages(allEdges []Edge, args PaginationArgs) []string {
+	if len(allEdges) == 0 {
+		return nil
+	}
+
+	if args.First == nil && args.Last != nil {
+		lim := int(*args.Last)
+		if lim <= 0 {
+			return []string{""}
+		}
+
+		// Walk backward from the last page end, pushing the cursor following each page. Relative
+		// pages end at the before cursor and every lim entries around it.
+		last := len(allEdges) - lim
+		if anchor := getRelativePageAnchor(allEdges, args.Before, args.relativePages); anchor > 0 {
+			last = anchor + (len(allEdges)-1-anchor)/lim*lim
+		}
+		pages := []string{""}
+		for end := last; end > 0; end -= lim {
+			pages = append([]string{allEdges[end].Cursor}, pages...)
+		}
+		return pages
+	}
+
+	lim := 0
+	if args.First != nil {
+		lim = int(*args.First)
+	}
+
+	pages := []string{""}
+	if lim <= 0 {
+		return pages
+	}
+	// If the next cursor is the start cursor of a page then push the current cursor to the list.
+	// If an end cursor is the last cursor, then it cannot be followed by a page. Relative pages end
+	// at the after cursor and every lim entries around it.
+	first := lim - 1
+	if anchor := getRelativePageAnchor(allEdges, args.After, args.relativePages); anchor >= 0 {
+		first = anchor % lim
+	}
+	for i := first; i < len(allEdges)-1; i += lim {
+		pages = append(pages, allEdges[i].Cursor)
+	}
+	return pages
+}
 
+// getRelativePageAnchor returns the index of the cursor that relative pages are anchored to, or -1
+// if the pages are absolute.
+func getRelativePageAnchor(allEdges []Edge, cursor *string, relativePages bool) int {
+	if !relativePages || cursor == nil {
+		return -1
+	}
+	return getCursorIndex(allEdges, *cursor)
 }
 
 // getConnection applies the ConnectionArgs to nodes and returns the result in a wrapped Connection
@@ -269,36 +816,72 @@ func getConnection(key string, out []reflect.Value, args PaginationArgs, returns
 	nodes := castSlice(out[0].Interface())
 	var edges []Edge
 
-	lim := int64(0)
-	if args.First != nil {
-		lim = *args.First
-	} else if args.Last != nil {
-		lim = *args.Last
+	// Scored nodes are ordered by descending score, and their cursors are prefixed with the score.
+	var scores []float64
+	if scored, ok := out[0].Interface().([]ScoredNode); ok {
+		nodes, scores = sortScoredNodes(scored, key)
 	}
 
-	var pages []string
-	if len(nodes) > 0 {
-		pages = append(pages, "")
+	// Edge structs are kept on their edges to resolve their extra fields.
+	var values []interface{}
+	if elemType := out[0].Type().Elem(); isEdgeStruct(elemType) {
+		edgeIndex := getEdgeIndex(elemType)
+		values = nodes
+		nodes = make([]interface{}, len(values))
+		for i, value := range values {
+			nodes[i] = reflect.ValueOf(value).FieldByIndex(edgeIndex).Interface().(Edge).Node
+		}
 	}
+
 	for i, val := range nodes {
-		// Get the value of the key field and then b64 encode it for the cursor.
-		keyValue := reflect.ValueOf(val)
-		if keyValue.Kind() == reflect.Ptr {
-			keyValue = keyValue.Elem()
+		edge := Edge{Node: val}
+		if scores != nil {
+			edge.Score = &scores[i]
+		}
+		if values != nil {
+			edge.value = values[i]
+		}
+		edges = append(edges, edge)
+	}
+
+	// If the nodes are ordered by a sort field then they are sorted by the sort field and then by
+	// key, and the cursor is built from both. Resolvers returning PaginationInfo are expected to sort
+	// the nodes themselves.
+	if args.sortField != "" {
+		if !returnsPageInfo {
+			sortEdges(edges, args.sortField, key)
 		}
-		keyString := []byte(fmt.Sprintf("%v", keyValue.FieldByName(key).Interface()))
-		cursorVal := base64.StdEncoding.EncodeToString(keyString)
-		// If the next cursor is the start cursor of a page then push the current cursor to the
-		// list. If an end cursor is the last cursor, then it cannot be followed by a page.
-		if lim != 0 && i != len(nodes)-1 && (int64(i+1)%lim) == 0 {
-			pages = append(pages, cursorVal)
+	} else if args.stableSort && scores == nil && !returnsPageInfo {
+		sortEdges(edges, key)
+	}
+
+	for i := range edges {
+		// Get the value of the key field and then b64 encode it for the cursor,
+		// with the URL-safe alphabet as cursors are often sent in query strings.
+		keyString := formatCursorKey(nodeFieldValue(edges[i].Node, key))
+		if edges[i].Score != nil {
+			keyString = fmt.Sprintf("%v:%s", *edges[i].Score, keyString)
 		}
-		edges = append(edges, Edge{Node: val, Cursor: cursorVal})
+		if args.sortField != "" {
+			// Both values may contain any character, so they are encoded as a JSON pair.
+			pair, err := json.Marshal([]string{formatCursorKey(nodeFieldValue(edges[i].Node, args.sortField)), keyString})
+			if err != nil {
+				return Connection{}, err
+			}
+			keyString = string(pair)
+		}
+		edges[i].Cursor = base64.URLEncoding.EncodeToString([]byte(keyString))
+	}
+	if args.overfetch {
+		return getOverfetchedConnection(key, edges, out, args, returnsPageInfo)
 	}
+	pages := getPages(edges, args)
+
 	edges, nextPage, prevPage, err := EdgesToReturn(edges, args.Before, args.After, args.First, args.Last)
 	if err != nil {
 		return Connection{}, err
 	}
+	warnDuplicateCursors(edges, key)
 
 	endCursor := ""
 	if len(edges) > 0 {
@@ -317,15 +900,67 @@ func getConnection(key string, out []reflect.Value, args PaginationArgs, returns
 			StartCursor: startCursor,
 			EndCursor:   endCursor,
 		}
-		totalCount := int64(0)
-		if connInfo.TotalCount != nil {
-			totalCount = connInfo.TotalCount()
+		var totalCountEstimate *int64
+		if connInfo.EstimatedTotalCount != nil {
+			estimate := connInfo.EstimatedTotalCount()
+			totalCountEstimate = &estimate
 		}
-		return Connection{TotalCount: totalCount, Edges: edges, PageInfo: pageInfo}, nil
+		return Connection{TotalCountEstimate: totalCountEstimate, Edges: edges, PageInfo: pageInfo, totalCountFunc: connInfo.TotalCount, totalCountStringFunc: connInfo.TotalCountString}, nil
 	}
+	totalCount := int64(len(nodes))
 	pageInfo := PageInfo{HasNextPage: nextPage, EndCursor: endCursor, StartCursor: startCursor, HasPrevPage: prevPage, Pages: pages}
-	return Connection{TotalCount: int64(len(nodes)), Edges: edges, PageInfo: pageInfo}, nil
+	return Connection{TotalCount: totalCount, TotalCountEstimate: &totalCount, Edges: edges, PageInfo: pageInfo}, nil
+
+}
+
+// getOverfetchedConnection returns the connection of a resolver configured with
+// Paginated.WithOverfetch. The resolver has already applied the after and before cursors, so the
+// edges are only trimmed to first and last, and the extra edge returned by the resolver determines
+// hasNextPage and hasPreviousPage.
+func getOverfetchedConnection(key string, edges []Edge, out []reflect.Value, args PaginationArgs, returnsPageInfo bool) (Connection, error) {
+	edges, nextPage, prevPage, err := EdgesToReturn(edges, nil, nil, args.First, args.Last)
+	if err != nil {
+		return Connection{}, err
+	}
+	warnDuplicateCursors(edges, key)
 
+	// The node of the after cursor precedes the page, and the node of the before cursor follows it.
+	if args.After != nil {
+		prevPage = true
+	}
+	if args.Before != nil {
+		nextPage = true
+	}
+
+	pageInfo := PageInfo{HasNextPage: nextPage, HasPrevPage: prevPage}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = edges[0].Cursor
+		pageInfo.EndCursor = edges[len(edges)-1].Cursor
+	}
+	conn := Connection{Edges: edges, PageInfo: pageInfo}
+	if returnsPageInfo {
+		connInfo := out[1].Interface().(PaginationInfo)
+		conn.totalCountFunc = connInfo.TotalCount
+		conn.totalCountStringFunc = connInfo.TotalCountString
+		if connInfo.EstimatedTotalCount != nil {
+			estimate := connInfo.EstimatedTotalCount()
+			conn.TotalCountEstimate = &estimate
+		}
+	}
+	return conn, nil
+}
+
+// warnDuplicateCursors logs a warning if several edges of a page share a cursor, because their
+// nodes have the same key. Pages resumed from such a cursor may silently skip nodes.
+func warnDuplicateCursors(edges []Edge, key string) {
+	seen := make(map[string]bool, len(edges))
+	for _, edge := range edges {
+		if seen[edge.Cursor] {
+			log.Printf("schemabuilder: duplicate cursor %s for %s in page, resuming pagination from it may skip nodes", edge.Cursor, key)
+			return
+		}
+		seen[edge.Cursor] = true
+	}
 }
 
 // PaginateFieldFunc registers a function that is also paginated according to the Relay
@@ -393,14 +1028,14 @@ func (sb *schemaBuilder) getKeyFieldOnStruct(nodeType reflect.Type) (string, err
 	if nodeObj == nil {
 		return "", fmt.Errorf("%s must be a struct and registered as an object along with its key", nodeType)
 	}
-	nodeKey := reverseGraphqlFieldName(nodeObj.key)
-	if nodeKey == "" {
-		return nodeKey, fmt.Errorf("a key field must be registered for paginated objects")
+	if nodeObj.key == "" {
+		return "", fmt.Errorf("a key field must be registered for paginated objects")
 	}
 	if nodeType.Kind() == reflect.Ptr {
 		nodeType = nodeType.Elem()
 	}
-	if _, ok := nodeType.FieldByName(nodeKey); !ok {
+	nodeKey := sb.goFieldName(nodeType, nodeObj.key)
+	if nodeKey == "" {
 		return nodeKey, fmt.Errorf("field doesn't exist on struct")
 	}
 
@@ -408,8 +1043,47 @@ func (sb *schemaBuilder) getKeyFieldOnStruct(nodeType reflect.Type) (string, err
 
 }
 
-// Parses the return types and checks if there's a pageInfo struct being returned by the resolver
-func (funcCtx *funcContext) parsePaginatedReturnSignature(m *method) (retPageInfo bool, err error) {
+// A nodeIDCheck is the check of the node type of the paginated field at path configured with
+// Paginated.WithRequiredNodeID.
+type nodeIDCheck struct {
+	nodeType reflect.Type
+	path     string
+}
+
+// checkNodeIDs runs the checks of paginated fields configured with Paginated.WithRequiredNodeID.
+func (sb *schemaBuilder) checkNodeIDs() error {
+	for _, check := range sb.nodeIDChecks {
+		if err := sb.checkNodeID(check.nodeType); err != nil {
+			err = fmt.Errorf("%s: %s", check.path, err)
+			if sb.collectErrors {
+				sb.errors = append(sb.errors, err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNodeID checks that the built object for nodeType exposes an id field, which clients need to
+// normalize nodes in their store.
+func (sb *schemaBuilder) checkNodeID(nodeType reflect.Type) error {
+	if nodeType.Kind() == reflect.Ptr {
+		nodeType = nodeType.Elem()
+	}
+	nodeObj, ok := sb.types[nodeType].(*graphql.Object)
+	if !ok {
+		return fmt.Errorf("%s must be an object to require an id field", nodeType)
+	}
+	if _, ok := nodeObj.Fields["id"]; !ok {
+		return fmt.Errorf("%s must expose an id field for client-side normalization", nodeObj.Name)
+	}
+	return nil
+}
+
+// Parses the return types and checks if there's a pageInfo struct being returned by the resolver,
+// or if the resolver returns a *Connection that it has already sliced.
+func (funcCtx *funcContext) parsePaginatedReturnSignature(m *method) (retPageInfo bool, retConnection bool, err error) {
 	retPageInfo = false
 
 	out := make([]reflect.Type, 0, funcCtx.funcType.NumOut())
@@ -419,10 +1093,11 @@ func (funcCtx *funcContext) parsePaginatedReturnSignature(m *method) (retPageInf
 
 	if len(out) > 0 && out[0] != errType {
 		funcCtx.hasRet = true
+		retConnection = out[0] == connectionPtrType
 		out = out[1:]
 	}
 
-	if len(out) > 0 && out[0] == reflect.TypeOf(PaginationInfo{}) {
+	if len(out) > 0 && out[0] == reflect.TypeOf(PaginationInfo{}) && !retConnection {
 		retPageInfo = true
 		out = out[1:]
 	}
@@ -456,6 +1131,14 @@ func (sb *schemaBuilder) buildPaginatedField(typ reflect.Type, m *method) (*grap
 
 	in := funcCtx.getFuncInputTypes()
 	in = funcCtx.consumeContextAndSource(in)
+	if err := funcCtx.checkSource(in); err != nil {
+		return nil, err
+	}
+
+	var userArgType reflect.Type
+	if len(in) > 0 && in[0] != selectionSetType {
+		userArgType = in[0]
+	}
 
 	argParser, argType, in, embedsArgs, err := funcCtx.consumePaginatedArgs(sb, in)
 	if err != nil {
@@ -463,6 +1146,39 @@ func (sb *schemaBuilder) buildPaginatedField(typ reflect.Type, m *method) (*grap
 	}
 	funcCtx.hasArgs = true
 
+	// Resolvers returning a *Connection, ScoredNodes, edges or edge structs are configured with their node
+	// type instead.
+	var retSliceType reflect.Type
+	if funcCtx.funcType.NumOut() > 0 {
+		retSliceType = funcCtx.funcType.Out(0)
+	}
+	returnsScores := retSliceType == scoredNodesType
+	var edgeStructType reflect.Type
+	if retSliceType != nil && retSliceType.Kind() == reflect.Slice && isEdgeStruct(retSliceType.Elem()) {
+		edgeStructType = retSliceType.Elem()
+	}
+	funcCtx.returnsEdgeSource = retSliceType != nil && retSliceType.Kind() != reflect.Slice && retSliceType.Implements(edgeSourceType)
+	funcCtx.returnsEdges = retSliceType == edgesType
+	if (retSliceType == connectionPtrType || returnsScores || edgeStructType != nil || funcCtx.returnsEdgeSource || funcCtx.returnsEdges) && m.NodeType != nil {
+		retSliceType = reflect.SliceOf(m.NodeType)
+	}
+
+	var sortFields map[string]string
+	if len(m.SortFields) > 0 {
+		argParser, sortFields, err = sb.addOrderByArg(argParser, argType, retSliceType, m, embedsArgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var searchQueryIndex []int
+	if m.EdgeHighlighter != nil {
+		searchQueryIndex, err = sb.getSearchQueryFieldIndex(userArgType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	in = funcCtx.consumeSelectionSet(in)
 
 	// We have succeeded if no arguments remain.
@@ -472,36 +1188,135 @@ func (sb *schemaBuilder) buildPaginatedField(typ reflect.Type, m *method) (*grap
 
 	// Parse return values. The first return value must be the actual value, and
 	// the second value can optionally be an error.
-	returnsPageInfo, err := funcCtx.parsePaginatedReturnSignature(&method{MarkedNonNullable: true})
+	returnsPageInfo, returnsConnection, err := funcCtx.parsePaginatedReturnSignature(&method{MarkedNonNullable: true})
 	if err != nil {
 		return nil, err
 	}
-	if (embedsArgs || returnsPageInfo) && !(embedsArgs && returnsPageInfo) {
+	if returnsConnection {
+		if !embedsArgs {
+			return nil, fmt.Errorf("if a *Connection is returned then pagination args must be embedded")
+		}
+		if m.NodeType == nil {
+			return nil, fmt.Errorf("if a *Connection is returned then the node type must be configured with Paginated.WithNodeType")
+		}
+	} else if funcCtx.returnsEdgeSource {
+		if !embedsArgs {
+			return nil, fmt.Errorf("if an EdgeSource is returned then pagination args must be embedded")
+		}
+		if m.NodeType == nil {
+			return nil, fmt.Errorf("if an EdgeSource is returned then the node type must be configured with Paginated.WithNodeType")
+		}
+		if len(m.SortFields) > 0 || m.Overfetch || m.StableSort || m.RelativePages {
+			return nil, fmt.Errorf("an EdgeSource can't be sorted, overfetched or paginated relatively")
+		}
+	} else if funcCtx.returnsEdges {
+		if !embedsArgs || !returnsPageInfo {
+			return nil, fmt.Errorf("if a []Edge is returned then pagination args must be embedded and pagination info must be included as a return value")
+		}
+		if m.NodeType == nil {
+			return nil, fmt.Errorf("if a []Edge is returned then the node type must be configured with Paginated.WithNodeType")
+		}
+		if len(m.SortFields) > 0 || m.Overfetch || m.StableSort || m.RelativePages {
+			return nil, fmt.Errorf("a []Edge can't be sorted, overfetched or paginated relatively")
+		}
+	} else if m.Overfetch {
+		if !embedsArgs {
+			return nil, fmt.Errorf("if nodes are overfetched then pagination args must be embedded")
+		}
+	} else if (embedsArgs || returnsPageInfo) && !(embedsArgs && returnsPageInfo) {
 		return nil, fmt.Errorf("if pagination args are embedded then pagination info must be included as a return value")
 	}
+	if returnsScores {
+		if m.NodeType == nil {
+			return nil, fmt.Errorf("if ScoredNodes are returned then the node type must be configured with Paginated.WithNodeType")
+		}
+		if len(m.SortFields) > 0 {
+			return nil, fmt.Errorf("ScoredNodes are ordered by score and can't be combined with sort fields")
+		}
+	}
+	if edgeStructType != nil && m.NodeType == nil {
+		return nil, fmt.Errorf("if edge structs are returned then the node type must be configured with Paginated.WithNodeType")
+	}
 
 	// It's safe to assume that there's a return type since the method is marked as non-nullable
 	// when calling parseReturnSignature above.
-	if funcCtx.funcType.Out(0).Kind() != reflect.Slice {
+	if retSliceType.Kind() != reflect.Slice {
 		return nil, fmt.Errorf("paginated field func must return a slice type")
 	}
-	nodeType := funcCtx.funcType.Out(0).Elem()
-	retType, err := funcCtx.constructConnType(sb, nodeType, returnsPageInfo)
+	nodeType := retSliceType.Elem()
+	retType, err := funcCtx.constructConnType(sb, nodeType, returnsPageInfo, returnsScores, edgeStructType, m)
 	if err != nil {
 		return nil, err
 	}
 
-	nodeKey, err := sb.getKeyFieldOnStruct(nodeType)
+	// The cursors of a returned *Connection, EdgeSource or []Edge are built by the resolver, so no
+	// key is needed.
+	resolverCursors := returnsConnection || funcCtx.returnsEdgeSource || funcCtx.returnsEdges
+	var nodeKey string
+	if !resolverCursors {
+		nodeKey, err = sb.getKeyFieldOnStruct(nodeType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The node type may still be incomplete, e.g. if one of its own fields returns this
+	// connection, so its id is only checked once all types are built.
+	if m.RequiresNodeID {
+		sb.nodeIDChecks = append(sb.nodeIDChecks, nodeIDCheck{nodeType: nodeType, path: strings.Join(sb.path, ".")})
+	}
+
+	args, err := funcCtx.argsTypeMap(argType)
 	if err != nil {
 		return nil, err
 	}
+	var argDefaultValues, argDescriptions map[string]string
+	if inputObject, ok := argType.(*graphql.InputObject); ok {
+		argDefaultValues = inputObject.DefaultValues
+		argDescriptions = inputObject.Descriptions
+	}
 
-	args, err := funcCtx.argsTypeMap(argType)
+	maxLimit := m.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = sb.maxPaginationLimit
+	}
+	// effectiveMaxLimit returns the max limit of the field in ctx, which may
+	// be lowered per request with graphql.WithMaxPageSize.
+	effectiveMaxLimit := func(ctx context.Context) int64 {
+		if size := graphql.MaxPageSize(ctx); size > 0 && (maxLimit == 0 || size < maxLimit) {
+			return size
+		}
+		return maxLimit
+	}
+	unboundedPageCost := sb.unboundedPageCost
+	if unboundedPageCost == 0 {
+		unboundedPageCost = defaultUnboundedPageCost
+	}
 
 	ret := &graphql.Field{
 		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			paginationArgs, err := applyPaginationLimit(getPaginationArgs(args, embedsArgs), effectiveMaxLimit(ctx), m.DefaultLimit)
+			if err != nil {
+				return nil, err
+			}
+			if funcCtx.returnsEdgeSource && (paginationArgs.Last != nil || paginationArgs.Before != nil) {
+				return nil, graphql.NewClientError("last and before are not supported")
+			}
+			if !resolverCursors {
+				if paginationArgs.OrderBy != nil {
+					paginationArgs.sortField = sortFields[*paginationArgs.OrderBy]
+				}
+				paginationArgs.cursorType = getCursorType(nodeType, nodeKey, paginationArgs.sortField)
+				paginationArgs.scoredCursor = returnsScores
+				paginationArgs.stableSort = m.StableSort
+				paginationArgs.overfetch = m.Overfetch
+				paginationArgs.relativePages = m.RelativePages
+			}
+
 			argsVal := args
-			if !embedsArgs {
+			if embedsArgs {
+				argsVal = setPaginationArgs(args, paginationArgs)
+			} else {
 				val, ok := args.(ConnectionArgs)
 				if !ok {
 					return nil, fmt.Errorf("arguments should implement ConnectionArgs")
@@ -512,49 +1327,142 @@ func (sb *schemaBuilder) buildPaginatedField(typ reflect.Type, m *method) (*grap
 				}
 			}
 
-			in := funcCtx.prepareResolveArgs(source, argsVal, ctx)
+			in := funcCtx.prepareResolveArgs(source, argsVal, selectionSet, ctx)
 
 			// Call the function.
 			out := fun.Call(in)
 
-			return funcCtx.extractPaginatedRetAndErr(nodeKey, out, args, retType, embedsArgs, returnsPageInfo)
+			var result interface{}
+			if returnsConnection {
+				result, err = funcCtx.extractConnectionRetAndErr(out)
+			} else if funcCtx.returnsEdgeSource {
+				result, err = funcCtx.extractEdgeSourceRetAndErr(out, paginationArgs, returnsPageInfo)
+			} else if funcCtx.returnsEdges {
+				result, err = funcCtx.extractEdgesRetAndErr(out)
+			} else {
+				result, err = funcCtx.extractPaginatedRetAndErr(nodeKey, out, paginationArgs, returnsPageInfo)
+			}
+			if err != nil {
+				return nil, err
+			}
 
+			if m.EdgeHighlighter != nil {
+				query := getSearchQuery(argsVal, searchQueryIndex)
+				conn := result.(Connection)
+				for i := range conn.Edges {
+					highlight := m.EdgeHighlighter(ctx, conn.Edges[i].Node, query)
+					conn.Edges[i].Highlight = &highlight
+				}
+				return conn, nil
+			}
+			return result, nil
+		},
+		Args:             args,
+		ArgDefaultValues: argDefaultValues,
+		ArgDescriptions:  argDescriptions,
+		Type:             retType,
+		ParseArguments:   argParser.Parse,
+		Expensive:        funcCtx.hasContext,
+
+		// The selections of a connection are resolved for every node of the page.
+		// Without a limit, all nodes are returned, which costs
+		// unboundedPageCost.
+		CostMultiplier: func(ctx context.Context, args interface{}) int {
+			paginationArgs, _ := applyPaginationLimit(getPaginationArgs(args, embedsArgs), effectiveMaxLimit(ctx), m.DefaultLimit)
+			if paginationArgs.First != nil && (paginationArgs.Last == nil || *paginationArgs.First < *paginationArgs.Last) {
+				return int(*paginationArgs.First)
+			}
+			if paginationArgs.Last != nil {
+				return int(*paginationArgs.Last)
+			}
+			return unboundedPageCost
 		},
-		Args:           args,
-		Type:           retType,
-		ParseArguments: argParser.Parse,
-		Expensive:      funcCtx.hasContext,
 	}
 
 	return ret, nil
 }
 
-func (funcCtx *funcContext) extractPaginatedRetAndErr(nodeKey string, out []reflect.Value, args interface{}, retType graphql.Type, embedsArgs bool, returnsPageInfo bool) (interface{}, error) {
-	var result interface{}
-	var paginationArgs PaginationArgs
-
+// getPaginationArgs returns the pagination args out of the parsed args of a paginated field.
+func getPaginationArgs(args interface{}, embedsArgs bool) PaginationArgs {
 	// If the pagination args are not embedded then they need to be extracted out of ConnectionArgs
 	// struct and setup for the slicing functions.
 	if !embedsArgs {
 		connectionArgs, _ := args.(ConnectionArgs)
-		paginationArgs = PaginationArgs{
-			First:  connectionArgs.First,
-			Last:   connectionArgs.Last,
-			After:  connectionArgs.After,
-			Before: connectionArgs.Before,
+		return PaginationArgs{
+			First:   connectionArgs.First,
+			Last:    connectionArgs.Last,
+			After:   connectionArgs.After,
+			Before:  connectionArgs.Before,
+			OrderBy: connectionArgs.OrderBy,
 		}
-	} else {
-		pagTyp := reflect.TypeOf(args)
-		fieldInd := -1
-		for i := 0; i < pagTyp.NumField(); i++ {
-			field := pagTyp.Field(i)
-			if field.Type == reflect.TypeOf(PaginationArgs{}) {
-				fieldInd = i
-			}
+	}
+
+	return reflect.ValueOf(args).Field(getPaginationArgsIndex(reflect.TypeOf(args))).Interface().(PaginationArgs)
+}
+
+// setPaginationArgs returns a copy of the embedded args with the pagination args replaced.
+func setPaginationArgs(args interface{}, paginationArgs PaginationArgs) interface{} {
+	argsValue := reflect.New(reflect.TypeOf(args)).Elem()
+	argsValue.Set(reflect.ValueOf(args))
+	argsValue.Field(getPaginationArgsIndex(argsValue.Type())).Set(reflect.ValueOf(paginationArgs))
+	return argsValue.Interface()
+}
+
+// getCursorType returns the type of the node field that cursors are built from, the sort field if
+// there is one, or else the key.
+func getCursorType(nodeType reflect.Type, nodeKey string, sortField string) reflect.Type {
+	if nodeType.Kind() == reflect.Ptr {
+		nodeType = nodeType.Elem()
+	}
+	key := nodeKey
+	if sortField != "" {
+		key = sortField
+	}
+	field, _ := nodeType.FieldByName(key)
+	return field.Type
+}
+
+// getPaginationArgsIndex returns the index of the embedded PaginationArgs in the args struct.
+func getPaginationArgsIndex(argType reflect.Type) int {
+	fieldInd := -1
+	for i := 0; i < argType.NumField(); i++ {
+		field := argType.Field(i)
+		if field.Type == reflect.TypeOf(PaginationArgs{}) {
+			fieldInd = i
 		}
+	}
+	return fieldInd
+}
 
-		paginationArgs = reflect.ValueOf(args).Field(fieldInd).Interface().(PaginationArgs)
+// applyPaginationLimit checks that first and last don't exceed maxLimit. If neither first nor last
+// are specified, first is set to defaultLimit, or else to maxLimit, so that the entire set isn't
+// returned. The default is clamped to maxLimit, which may be lowered per request with
+// graphql.WithMaxPageSize. A maxLimit of 0 means that the field is unlimited.
+func applyPaginationLimit(args PaginationArgs, maxLimit int64, defaultLimit int64) (PaginationArgs, error) {
+	if args.First == nil && args.Last == nil {
+		limit := defaultLimit
+		if maxLimit > 0 && (limit <= 0 || limit > maxLimit) {
+			limit = maxLimit
+		}
+		if limit > 0 {
+			args.First = &limit
+		}
+		return args, nil
+	}
+	if maxLimit <= 0 {
+		return args, nil
+	}
+	if args.First != nil && *args.First > maxLimit {
+		return args, graphql.NewClientError("first should not be greater than %d", maxLimit)
+	}
+	if args.Last != nil && *args.Last > maxLimit {
+		return args, graphql.NewClientError("last should not be greater than %d", maxLimit)
 	}
+	return args, nil
+}
+
+func (funcCtx *funcContext) extractPaginatedRetAndErr(nodeKey string, out []reflect.Value, paginationArgs PaginationArgs, returnsPageInfo bool) (interface{}, error) {
+	var result interface{}
 
 	result, err := getConnection(nodeKey, out, paginationArgs, returnsPageInfo)
 	if err != nil {
@@ -573,6 +1481,251 @@ func (funcCtx *funcContext) extractPaginatedRetAndErr(nodeKey string, out []refl
 	return result, nil
 }
 
+// extractConnectionRetAndErr returns the *Connection returned by a resolver that has already
+// sliced its nodes. A nil *Connection is returned as an empty connection.
+func (funcCtx *funcContext) extractConnectionRetAndErr(out []reflect.Value) (interface{}, error) {
+	if funcCtx.hasError {
+		if err := out[1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+	}
+
+	conn, _ := out[0].Interface().(*Connection)
+	if conn == nil {
+		return Connection{}, nil
+	}
+	return *conn, nil
+}
+
+// extractEdgeSourceRetAndErr pulls the page of nodes from a returned EdgeSource, and an extra node
+// to tell if there is a next page.
+func (funcCtx *funcContext) extractEdgeSourceRetAndErr(out []reflect.Value, args PaginationArgs, returnsPageInfo bool) (interface{}, error) {
+	if funcCtx.hasError {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+	}
+
+	source, _ := out[0].Interface().(EdgeSource)
+	if source == nil {
+		return Connection{}, nil
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var edges []Edge
+	for args.First == nil || int64(len(edges)) <= *args.First {
+		node, cursor, ok := source.Next()
+		if !ok {
+			break
+		}
+		edges = append(edges, Edge{Node: node, Cursor: cursor})
+	}
+	return getOverfetchedConnection("edge source", edges, out, args, returnsPageInfo)
+}
+
+// extractEdgesRetAndErr returns the page of edges returned by an externally managed resolver, with
+// their cursors as returned.
+func (funcCtx *funcContext) extractEdgesRetAndErr(out []reflect.Value) (interface{}, error) {
+	if funcCtx.hasError {
+		if err := out[2]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+	}
+
+	edges := out[0].Interface().([]Edge)
+	connInfo := out[1].Interface().(PaginationInfo)
+	pageInfo := PageInfo{HasNextPage: connInfo.HasNextPage, HasPrevPage: connInfo.HasPrevPage}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = edges[0].Cursor
+		pageInfo.EndCursor = edges[len(edges)-1].Cursor
+	}
+	conn := Connection{
+		Edges:                edges,
+		PageInfo:             pageInfo,
+		totalCountFunc:       connInfo.TotalCount,
+		totalCountStringFunc: connInfo.TotalCountString,
+	}
+	if connInfo.EstimatedTotalCount != nil {
+		estimate := connInfo.EstimatedTotalCount()
+		conn.TotalCountEstimate = &estimate
+	}
+	return conn, nil
+}
+
+// addOrderByArg adds the orderBy enum arg of a paginated field configured with sort fields to
+// argType, and wraps argParser to parse it into the OrderBy pagination arg. It also returns the
+// names of the Go fields of the sort fields.
+func (sb *schemaBuilder) addOrderByArg(parser *argParser, argType graphql.Type, retType reflect.Type, m *method, embedsArgs bool) (*argParser, map[string]string, error) {
+	sortFields := m.SortFields
+	if retType == nil || retType.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("paginated field func must return a slice type")
+	}
+	nodeType := retType.Elem()
+	nodeStruct := nodeType
+	if nodeStruct.Kind() == reflect.Ptr {
+		nodeStruct = nodeStruct.Elem()
+	}
+	if nodeStruct.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s must be a struct to be sorted", nodeType)
+	}
+
+	reverseMap := make(map[interface{}]string)
+	goNames := make(map[string]string, len(sortFields))
+	for _, sortField := range sortFields {
+		goName := sb.goFieldName(nodeStruct, sortField)
+		if goName == "" {
+			return nil, nil, fmt.Errorf("sort field %s doesn't exist on %s", sortField, nodeStruct)
+		}
+		reverseMap[sortField] = sortField
+		goNames[sortField] = goName
+	}
+
+	inputObject := argType.(*graphql.InputObject)
+	if _, ok := inputObject.InputFields["orderBy"]; ok {
+		return nil, nil, fmt.Errorf("the orderBy arg name is restricted on sorted paginated fields")
+	}
+	inputObject.InputFields["orderBy"] = &graphql.Enum{
+		Type:       getConnectionTypeName(nodeType, nil, m) + "OrderBy",
+		Values:     sortFields,
+		ReverseMap: reverseMap,
+	}
+
+	return &argParser{
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			asMap, ok := value.(map[string]interface{})
+			if !ok {
+				return errors.New("not an object")
+			}
+
+			// The remaining args are parsed by the original parser.
+			rest := make(map[string]interface{}, len(asMap))
+			for name, value := range asMap {
+				if name != "orderBy" {
+					rest[name] = value
+				}
+			}
+			if err := parser.FromJSON(rest, dest); err != nil {
+				return err
+			}
+
+			orderBy, ok := asMap["orderBy"]
+			if !ok || orderBy == nil {
+				return nil
+			}
+			asString, ok := orderBy.(string)
+			if !ok {
+				return errors.New("orderBy: not a string")
+			}
+			if _, ok := reverseMap[asString]; !ok {
+				return fmt.Errorf("orderBy: unknown enum value %v", asString)
+			}
+
+			orderByDest := dest.FieldByName("OrderBy")
+			if embedsArgs {
+				orderByDest = dest.Field(getPaginationArgsIndex(dest.Type())).FieldByName("OrderBy")
+			}
+			orderByDest.Set(reflect.ValueOf(&asString))
+			return nil
+		},
+		Type: parser.Type,
+	}, goNames, nil
+}
+
+// sortEdges stably sorts the edges by the values of the given struct fields of their nodes, ordered
+// by the first field, with ties broken by the following fields.
+func sortEdges(edges []Edge, fields ...string) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		for _, field := range fields {
+			a, b := nodeFieldValue(edges[i].Node, field), nodeFieldValue(edges[j].Node, field)
+			if lessFieldValues(a, b) {
+				return true
+			}
+			if lessFieldValues(b, a) {
+				return false
+			}
+		}
+		return false
+	})
+}
+
+// sortScoredNodes orders scored nodes by descending score, breaking ties by key so that the order,
+// and hence the cursors, are stable across requests. It returns the nodes and their scores.
+func sortScoredNodes(scored []ScoredNode, key string) ([]interface{}, []float64) {
+	scored = append([]ScoredNode(nil), scored...)
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return lessFieldValues(nodeFieldValue(scored[i].Node, key), nodeFieldValue(scored[j].Node, key))
+	})
+
+	nodes := make([]interface{}, len(scored))
+	scores := make([]float64, len(scored))
+	for i, node := range scored {
+		nodes[i] = node.Node
+		scores[i] = node.Score
+	}
+	return nodes, scores
+}
+
+// nodeFieldValue returns the value of the struct field of node, which may be a pointer.
+func nodeFieldValue(node interface{}, field string) reflect.Value {
+	value := reflect.ValueOf(node)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	return value.FieldByName(field)
+}
+
+// lessFieldValues compares two values of the same struct field.
+func lessFieldValues(a, b reflect.Value) bool {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Before(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+// getSearchQueryFieldIndex returns the index of the field exposed as the query arg on the args of
+// a paginated field configured with an EdgeHighlighter. Args are named like in makeStructParser.
+func (sb *schemaBuilder) getSearchQueryFieldIndex(argType reflect.Type) ([]int, error) {
+	if argType != nil && argType.Kind() == reflect.Struct {
+		field, ok := argType.FieldByName(sb.goFieldName(argType, "query"))
+		if ok && (field.Type.Kind() == reflect.String || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.String)) {
+			return field.Index, nil
+		}
+	}
+	return nil, fmt.Errorf("paginated fields with an edge highlighter must take a string query arg")
+}
+
+// getSearchQuery returns the value of the query arg, or "" if it wasn't specified.
+func getSearchQuery(args interface{}, index []int) string {
+	argsValue := reflect.ValueOf(args)
+	if !argsValue.IsValid() {
+		return ""
+	}
+	queryValue := argsValue.FieldByIndex(index)
+	if queryValue.Kind() == reflect.Ptr {
+		if queryValue.IsNil() {
+			return ""
+		}
+		queryValue = queryValue.Elem()
+	}
+	return queryValue.String()
+}
+
 func castSlice(slice interface{}) []interface{} {
 	s := reflect.ValueOf(slice)
 	if s.Kind() != reflect.Slice {
@@ -610,21 +1763,56 @@ func (sb *schemaBuilder) buildEmbeddedPaginatedArgParser(typ reflect.Type) (*arg
 			continue
 		}
 
-		name := makeGraphql(field.Name)
+		tag, options, err := parseTagOptions(field.Tag.Get("graphql"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad arg type %s: %s", typ, err)
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = sb.fieldName(field.Name)
+		}
+		if name == "-" {
+			continue
+		}
+		for option := range options {
+			if option != "description" && option != "default" {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s has unexpected tag option %s", typ, name, option)
+			}
+		}
 
 		var parser *argParser
 		var fieldArgTyp graphql.Type
 
-		parser, fieldArgTyp, err := sb.makeArgParser(field.Type)
+		parser, fieldArgTyp, err = sb.makeArgParser(field.Type)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		argType.InputFields[name] = fieldArgTyp
-		fields[name] = argField{
+		argField := argField{
 			field:  field,
 			parser: parser,
 		}
+		if value, ok := options["default"]; ok {
+			defaultValue, literal, err := sb.parseDefaultValue(field.Type, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s has bad default: %s", typ, name, err)
+			}
+			argField.hasDefault = true
+			argField.defaultValue = defaultValue
+			if argType.DefaultValues == nil {
+				argType.DefaultValues = make(map[string]string)
+			}
+			argType.DefaultValues[name] = literal
+		}
+		if description := options["description"]; description != "" {
+			if argType.Descriptions == nil {
+				argType.Descriptions = make(map[string]string)
+			}
+			argType.Descriptions[name] = description
+		}
+
+		argType.InputFields[name] = fieldArgTyp
+		fields[name] = argField
 	}
 
 	pagArgParser, pagArgType, err := sb.makeStructParser(reflect.TypeOf(PaginationArgs{}))
@@ -649,7 +1837,10 @@ func (sb *schemaBuilder) buildEmbeddedPaginatedArgParser(typ reflect.Type) (*arg
 			}
 
 			for name, field := range fields {
-				value := asMap[name]
+				value, ok := asMap[name]
+				if !ok && field.hasDefault {
+					value = field.defaultValue
+				}
 				fieldDest := dest.FieldByIndex(field.field.Index)
 				if err := field.parser.FromJSON(value, fieldDest); err != nil {
 					return fmt.Errorf("%s: %s", name, err)
@@ -670,13 +1861,29 @@ func (sb *schemaBuilder) buildEmbeddedPaginatedArgParser(typ reflect.Type) (*arg
 				return err
 			}
 
-			return nil
+			paginationArgs := fieldDest.Interface().(PaginationArgs)
+			if err := validatePaginationArgs(paginationArgs.First, paginationArgs.Last); err != nil {
+				return err
+			}
+			return validateArgs(dest)
 		},
 		Type: typ,
 	}, argType, nil
 
 }
 
+// validatePaginationArgs rejects invalid first and last args while the args are parsed, so that
+// the resolver isn't called. EdgesToReturn checks them again when slicing.
+func validatePaginationArgs(first *int64, last *int64) error {
+	if first != nil && *first < 0 {
+		return errors.New("first: should be a non-negative integer")
+	}
+	if last != nil && *last < 0 {
+		return errors.New("last: should be a non-negative integer")
+	}
+	return nil
+}
+
 // buildPaginatedArgParser corresponds to buildArgParser for arguments used on a paginated
 // fieldFunc. The args are nested as the Args field in the ConnectionArgs.
 func (sb *schemaBuilder) buildPaginatedArgParser(originalArgType reflect.Type) (*argParser, graphql.Type, error) {
@@ -702,7 +1909,11 @@ func (sb *schemaBuilder) buildPaginatedArgParser(originalArgType reflect.Type) (
 		if field.Type.Kind() == reflect.Interface {
 			continue
 		}
+		if field.Tag.Get("graphql") == "-" {
+			continue
+		}
 
+		// The args of the Relay spec aren't renamed by the field name mapper.
 		name := makeGraphql(field.Name)
 
 		var parser *argParser
@@ -737,6 +1948,8 @@ func (sb *schemaBuilder) buildPaginatedArgParser(originalArgType reflect.Type) (
 		for name, typ := range userInputObject.InputFields {
 			argType.InputFields[name] = typ
 		}
+		argType.DefaultValues = userInputObject.DefaultValues
+		argType.Descriptions = userInputObject.Descriptions
 	}
 
 	return &argParser{
@@ -754,6 +1967,11 @@ func (sb *schemaBuilder) buildPaginatedArgParser(originalArgType reflect.Type) (
 				}
 			}
 
+			connectionArgs := dest.Interface().(ConnectionArgs)
+			if err := validatePaginationArgs(connectionArgs.First, connectionArgs.Last); err != nil {
+				return err
+			}
+
 			// nestedArgFields is the map used to parse the remaining fields: any field which isn't
 			// part of ConnectionArgs should be a field of the args used for the paginated field.
 			nestedArgFields := make(map[string]interface{})
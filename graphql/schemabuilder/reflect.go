@@ -3,11 +3,15 @@ package schemabuilder
 import (
 	"bytes"
 	"context"
+	"encoding"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -203,8 +207,48 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 			return nil
 		},
 	},
+	reflect.TypeOf(json.RawMessage{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			dest.Set(reflect.ValueOf(json.RawMessage(raw)).Convert(dest.Type()))
+			return nil
+		},
+	},
+	reflect.TypeOf(map[string]interface{}{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			asMap, ok := value.(map[string]interface{})
+			if !ok {
+				return errors.New("not an object")
+			}
+			dest.Set(reflect.ValueOf(asMap).Convert(dest.Type()))
+			return nil
+		},
+	},
+	reflect.TypeOf(graphql.Upload{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			// Uploads are set by the HTTP handler, and can't be sent as JSON.
+			asUpload, ok := value.(*graphql.Upload)
+			if !ok {
+				return errors.New("not an upload")
+			}
+			dest.Set(reflect.ValueOf(*asUpload).Convert(dest.Type()))
+			return nil
+		},
+	},
 	reflect.TypeOf(time.Time{}): {
 		FromJSON: func(value interface{}, dest reflect.Value) error {
+			// Times are RFC3339 strings, or integer Unix timestamps in seconds.
+			if asFloat, ok := value.(float64); ok {
+				if asFloat != math.Trunc(asFloat) {
+					return errors.New("not an integer unix timestamp")
+				}
+				dest.Set(reflect.ValueOf(time.Unix(int64(asFloat), 0).UTC()).Convert(dest.Type()))
+				return nil
+			}
+
 			asString, ok := value.(string)
 			if !ok {
 				return errors.New("not a string")
@@ -242,6 +286,54 @@ func getScalarArgParser(typ reflect.Type) (*argParser, graphql.Type, bool) {
 	return nil, nil, false
 }
 
+// coerceScalarArgParser wraps the parser of a number or bool scalar. With
+// WithLenientInputCoercion, numeric strings are parsed as numbers and the
+// numbers 0 and 1 as bools; otherwise they are rejected with an error telling
+// what the value was instead.
+func (sb *schemaBuilder) coerceScalarArgParser(parser *argParser) *argParser {
+	var kind string
+	switch parser.Type.Kind() {
+	case reflect.Bool:
+		kind = "bool"
+	case reflect.Float64, reflect.Float32,
+		reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		kind = "number"
+	default:
+		return parser
+	}
+
+	inner := parser.FromJSON
+	lenient := sb.lenientInputCoercion
+	newParser := *parser
+	newParser.FromJSON = func(value interface{}, dest reflect.Value) error {
+		switch v := value.(type) {
+		case string:
+			if kind != "number" {
+				break
+			}
+			if !lenient {
+				return fmt.Errorf("not a number, got string %q", v)
+			}
+			asFloat, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("not a number, got string %q", v)
+			}
+			value = asFloat
+		case float64:
+			if kind != "bool" {
+				break
+			}
+			if !lenient || (v != 0 && v != 1) {
+				return fmt.Errorf("not a bool, got number %v", v)
+			}
+			value = v == 1
+		}
+		return inner(value, dest)
+	}
+	return &newParser
+}
+
 func (sb *schemaBuilder) getEnumArgParser(typ reflect.Type) (*argParser, graphql.Type) {
 	var values []string
 	for mapping := range sb.enumMappings[typ].Map {
@@ -258,7 +350,7 @@ func (sb *schemaBuilder) getEnumArgParser(typ reflect.Type) (*argParser, graphql
 		}
 		dest.Set(reflect.ValueOf(val).Convert(dest.Type()))
 		return nil
-	}, Type: typ}, &graphql.Enum{Type: typ.Name(), Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap}
+	}, Type: typ}, sb.enumMappings[typ].graphqlType(typ, values)
 
 }
 
@@ -268,10 +360,186 @@ func init() {
 	}
 }
 
+// idScalar returns the ID scalar. IDs are backed by Go strings or integers, and
+// are serialized as strings.
+func idScalar() *graphql.Scalar {
+	return &graphql.Scalar{
+		Type: "ID",
+		Serialize: func(value interface{}) interface{} {
+			return fmt.Sprint(value)
+		},
+	}
+}
+
+// isIDKind returns if values of kind can back an ID.
+func isIDKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// getIDType returns the type of a struct field tagged with the type option,
+// e.g. `graphql:"id;type=ID"`. ID is the only supported type.
+// Slices of IDs are lists of IDs.
+func getIDType(typ reflect.Type, typeName string) (graphql.Type, error) {
+	if typeName != "ID" {
+		return nil, fmt.Errorf("has unknown type %s", typeName)
+	}
+	if typ.Kind() == reflect.Slice {
+		elemType, err := getIDType(typ.Elem(), typeName)
+		if err != nil {
+			return nil, err
+		}
+		return &graphql.NonNull{Type: &graphql.List{Type: elemType}}, nil
+	}
+	if typ.Kind() == reflect.Ptr {
+		if !isIDKind(typ.Elem().Kind()) {
+			return nil, fmt.Errorf("of type %s can't be an ID", typ)
+		}
+		return idScalar(), nil
+	}
+	if !isIDKind(typ.Kind()) {
+		return nil, fmt.Errorf("of type %s can't be an ID", typ)
+	}
+	return &graphql.NonNull{Type: idScalar()}, nil
+}
+
+// makeIDArgParser returns the parser of an arg tagged with the type option.
+// IDs are accepted as strings or integers, and are coerced to the Go type of
+// the arg.
+func makeIDArgParser(typ reflect.Type, typeName string) (*argParser, graphql.Type, error) {
+	argType, err := getIDType(typ, typeName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if typ.Kind() == reflect.Slice {
+		elemParser, _, err := makeIDArgParser(typ.Elem(), typeName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return makeListParser(typ, elemParser), argType, nil
+	}
+
+	inner := typ
+	if inner.Kind() == reflect.Ptr {
+		inner = inner.Elem()
+	}
+	parser := &argParser{
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			var asString string
+			switch value := value.(type) {
+			case string:
+				asString = value
+			case float64:
+				if value != math.Trunc(value) {
+					return errors.New("not an ID")
+				}
+				asString = strconv.FormatFloat(value, 'f', -1, 64)
+			default:
+				return errors.New("not an ID")
+			}
+
+			switch inner.Kind() {
+			case reflect.String:
+				dest.SetString(asString)
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				asInt, err := strconv.ParseInt(asString, 10, inner.Bits())
+				if err != nil {
+					return fmt.Errorf("ID %s is not an integer", asString)
+				}
+				dest.SetInt(asInt)
+			default:
+				asUint, err := strconv.ParseUint(asString, 10, inner.Bits())
+				if err != nil {
+					return fmt.Errorf("ID %s is not an unsigned integer", asString)
+				}
+				dest.SetUint(asUint)
+			}
+			return nil
+		},
+		Type: inner,
+	}
+	if typ.Kind() == reflect.Ptr {
+		parser = wrapPtrParser(parser)
+	}
+	return parser, argType, nil
+}
+
+// flagTagOptions are the tag options that take no value.
+var flagTagOptions = map[string]bool{
+	"nonnull":  true,
+	"nullable": true,
+	"external": true,
+}
+
+// parseTagOptions splits a graphql struct tag into its name and flags, e.g.
+// "name,key", and its options, which follow separated by semicolons, e.g.
+//   `graphql:"name;description=The user's display name"`
+// Options are of the form key=value, except for the flagTagOptions.
+func parseTagOptions(tag string) (string, map[string]string, error) {
+	parts := strings.Split(tag, ";")
+	options := make(map[string]string)
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 && flagTagOptions[part] {
+			kv = []string{part, ""}
+		}
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("tag option %s should be of the form key=value", part)
+		}
+		if _, ok := options[kv[0]]; ok {
+			return "", nil, fmt.Errorf("duplicate tag option %s", kv[0])
+		}
+		options[kv[0]] = kv[1]
+	}
+	return parts[0], options, nil
+}
+
+// blankFieldDescription returns the description in the graphql tag of the
+// blank field of typ, which documents the type itself, e.g.
+//   _ struct{} `graphql:";description=A user of the app."`
+func blankFieldDescription(typ reflect.Type) (string, error) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("graphql")
+		if field.Name != "_" || !ok {
+			continue
+		}
+		name, options, err := parseTagOptions(tag)
+		if err != nil {
+			return "", err
+		}
+		if name != "" {
+			return "", fmt.Errorf("blank field has unexpected name %s", name)
+		}
+		for option := range options {
+			if option != "description" {
+				return "", fmt.Errorf("blank field has unexpected tag option %s", option)
+			}
+		}
+		return options["description"], nil
+	}
+	return "", nil
+}
+
+// inputObject is the parser and type of a struct built by makeStructParser.
+type inputObject struct {
+	parser *argParser
+	typ    *graphql.InputObject
+}
+
 type argField struct {
 	field    reflect.StructField
 	parser   *argParser
 	optional bool
+
+	hasDefault   bool
+	defaultValue interface{}
 }
 
 func (sb *schemaBuilder) makeArgParser(typ reflect.Type) (*argParser, graphql.Type, error) {
@@ -291,13 +559,21 @@ func (sb *schemaBuilder) makeArgParser(typ reflect.Type) (*argParser, graphql.Ty
 }
 
 func (sb *schemaBuilder) makeArgParserInner(typ reflect.Type) (*argParser, graphql.Type, error) {
+	if scalar, ok := sb.scalars[typ]; ok {
+		return scalar.argParser(typ), scalar.graphqlType(), nil
+	}
+
 	if sb.enumMappings[typ] != nil {
 		parser, argType := sb.getEnumArgParser(typ)
 		return parser, argType, nil
 	}
 
+	if scalar, ok := getTextScalar(typ); ok {
+		return scalar.argParser(typ), scalar.graphqlType(), nil
+	}
+
 	if parser, argType, ok := getScalarArgParser(typ); ok {
-		return parser, argType, nil
+		return sb.coerceScalarArgParser(parser), argType, nil
 	}
 
 	switch typ.Kind() {
@@ -349,6 +625,28 @@ func (sb *schemaBuilder) makeStructParser(typ reflect.Type) (*argParser, graphql
 	if typ.Kind() != reflect.Struct {
 		return nil, nil, fmt.Errorf("expected struct but received type %s", typ.Name())
 	}
+	description, err := blankFieldDescription(typ)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad arg type %s: %s", typ, err)
+	}
+	argType.Description = description
+
+	// Structs are cached before their fields are built, so that input objects
+	// can refer to themselves, e.g. a filter with a list of alternative filters.
+	if cached, ok := sb.inputObjects[typ]; ok {
+		return cached.parser, cached.typ, nil
+	}
+	if sb.inputObjects == nil {
+		sb.inputObjects = make(map[reflect.Type]*inputObject)
+	}
+	structParser := &argParser{Type: typ}
+	sb.inputObjects[typ] = &inputObject{parser: structParser, typ: argType}
+	built := false
+	defer func() {
+		if !built {
+			delete(sb.inputObjects, typ)
+		}
+	}()
 
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
@@ -358,13 +656,17 @@ func (sb *schemaBuilder) makeStructParser(typ reflect.Type) (*argParser, graphql
 		if field.Anonymous {
 			return nil, nil, fmt.Errorf("bad arg type %s: anonymous fields not supported", typ)
 		}
-		tags := strings.Split(field.Tag.Get("graphql"), ",")
+		tag, options, err := parseTagOptions(field.Tag.Get("graphql"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad arg type %s: %s", typ, err)
+		}
+		tags := strings.Split(tag, ",")
 		var name string
 		if len(tags) > 0 {
 			name = tags[0]
 		}
 		if name == "" {
-			name = makeGraphql(field.Name)
+			name = sb.fieldName(field.Name)
 		}
 		if name == "-" {
 			continue
@@ -380,46 +682,191 @@ func (sb *schemaBuilder) makeStructParser(typ reflect.Type) (*argParser, graphql
 				key = true
 			}
 		}
+		for option := range options {
+			if option != "description" && option != "default" && option != "type" {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s has unexpected tag option %s", typ, name, option)
+			}
+		}
 
 		if _, ok := fields[name]; ok {
 			return nil, nil, fmt.Errorf("bad arg type %s: duplicate field %s", typ, name)
 		}
-		parser, fieldArgTyp, err := sb.makeArgParser(field.Type)
-		if err != nil {
-			return nil, nil, err
+		// Optional fields are parsed into their Value field.
+		parsedType := field.Type
+		valueField, optional := optionalValueField(field.Type)
+		if optional {
+			parsedType = valueField.Type
+		}
+		var parser *argParser
+		var fieldArgTyp graphql.Type
+		if typeName, ok := options["type"]; ok {
+			parser, fieldArgTyp, err = makeIDArgParser(parsedType, typeName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s %s", typ, name, err)
+			}
+		} else {
+			parser, fieldArgTyp, err = sb.makeArgParser(parsedType)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Optional fields can always be omitted.
+		if nonNull, ok := fieldArgTyp.(*graphql.NonNull); ok && optional {
+			fieldArgTyp = nonNull.Type
 		}
 
-		fields[name] = argField{
-			field:  field,
-			parser: parser,
+		argField := argField{
+			field:    field,
+			parser:   parser,
+			optional: optional,
+		}
+		if value, ok := options["default"]; ok {
+			defaultValue, literal, err := sb.parseDefaultValue(field.Type, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s has bad default: %s", typ, name, err)
+			}
+			argField.hasDefault = true
+			argField.defaultValue = defaultValue
+			if argType.DefaultValues == nil {
+				argType.DefaultValues = make(map[string]string)
+			}
+			argType.DefaultValues[name] = literal
+		}
+		if description := options["description"]; description != "" {
+			if argType.Descriptions == nil {
+				argType.Descriptions = make(map[string]string)
+			}
+			argType.Descriptions[name] = description
 		}
+
+		fields[name] = argField
 		argType.InputFields[name] = fieldArgTyp
 	}
 
-	return &argParser{
-		FromJSON: func(value interface{}, dest reflect.Value) error {
-			asMap, ok := value.(map[string]interface{})
-			if !ok {
-				return errors.New("not an object")
-			}
+	structParser.FromJSON = func(value interface{}, dest reflect.Value) error {
+		asMap, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("not an object")
+		}
 
-			for name, field := range fields {
-				value := asMap[name]
-				fieldDest := dest.FieldByIndex(field.field.Index)
-				if err := field.parser.FromJSON(value, fieldDest); err != nil {
-					return fmt.Errorf("%s: %s", name, err)
-				}
+		for name, field := range fields {
+			value, ok := asMap[name]
+			if !ok && field.hasDefault {
+				value, ok = field.defaultValue, true
 			}
-			for name := range asMap {
-				if _, ok := fields[name]; !ok {
-					return fmt.Errorf("unknown arg %s", name)
+			fieldDest := dest.FieldByIndex(field.field.Index)
+			if field.optional {
+				// Omitted optional fields are left unset, unlike null ones.
+				if !ok {
+					continue
 				}
+				fieldDest.FieldByName("Set").SetBool(true)
+				fieldDest = fieldDest.FieldByName("Value")
+			}
+			if err := field.parser.FromJSON(value, fieldDest); err != nil {
+				return fmt.Errorf("%s: %s", name, err)
 			}
+		}
+		for name := range asMap {
+			if _, ok := fields[name]; !ok {
+				return fmt.Errorf("unknown arg %s", name)
+			}
+		}
 
-			return nil
-		},
-		Type: typ,
-	}, argType, nil
+		return validateArgs(dest)
+	}
+	built = true
+	return structParser, argType, nil
+}
+
+// Optional is embedded in the fields of arg structs and input objects that
+// tell omitted args from args that are explicitly null, e.g. for mutations
+// that only update the given fields. The embedding struct holds the arg in a
+// field named Value, whose type is the arg's type, e.g.
+//   type OptionalString struct {
+//     schemabuilder.Optional
+//     Value *string
+//   }
+// Set is true if the arg is given, even if it is null.
+type Optional struct {
+	Set bool
+}
+
+var optionalType = reflect.TypeOf(Optional{})
+
+// optionalValueField returns the Value field of typ if it is a struct embedding Optional.
+func optionalValueField(typ reflect.Type) (reflect.StructField, bool) {
+	if typ.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	embedded, ok := typ.FieldByName("Optional")
+	if !ok || !embedded.Anonymous || embedded.Type != optionalType {
+		return reflect.StructField{}, false
+	}
+	return typ.FieldByName("Value")
+}
+
+// ArgsValidator can be implemented by arg structs and input objects to
+// validate their values once they are parsed, e.g. to check that an email is
+// well-formed. Validate's error is returned to the client.
+type ArgsValidator interface {
+	Validate() error
+}
+
+// validateArgs calls Validate on a parsed arg struct if it implements
+// ArgsValidator.
+func validateArgs(dest reflect.Value) error {
+	if dest.CanAddr() {
+		dest = dest.Addr()
+	}
+	validator, ok := dest.Interface().(ArgsValidator)
+	if !ok {
+		return nil
+	}
+	return validator.Validate()
+}
+
+// parseDefaultValue parses the default tag option of an arg field of type typ
+// into its JSON value, as passed to the field's parser, and its GraphQL literal.
+// Defaults are supported for ints, floats, strings, bools and enums.
+func (sb *schemaBuilder) parseDefaultValue(typ reflect.Type, value string) (interface{}, string, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if mapping, ok := sb.enumMappings[typ]; ok {
+		if _, ok := mapping.Map[value]; !ok {
+			return nil, "", fmt.Errorf("unknown enum value %s", value)
+		}
+		return value, value, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		asInt, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, "", errors.New("not an int")
+		}
+		return float64(asInt), value, nil
+	case reflect.Float32, reflect.Float64:
+		asFloat, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, "", errors.New("not a number")
+		}
+		return asFloat, value, nil
+	case reflect.String:
+		return value, strconv.Quote(value), nil
+	case reflect.Bool:
+		asBool, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, "", errors.New("not a bool")
+		}
+		return asBool, strconv.FormatBool(asBool), nil
+	default:
+		return nil, "", fmt.Errorf("defaults are not supported for type %s", typ)
+	}
 }
 
 func (sb *schemaBuilder) makeSliceParser(typ reflect.Type) (*argParser, graphql.Type, error) {
@@ -428,36 +875,179 @@ func (sb *schemaBuilder) makeSliceParser(typ reflect.Type) (*argParser, graphql.
 		return nil, nil, err
 	}
 
+	return makeListParser(typ, inner), &graphql.List{Type: argType}, nil
+}
+
+// makeListParser returns the parser of a list into a slice of type typ, which
+// parses every item with inner. Items of slices of pointers are nullable, all
+// other items are non-null. As in the GraphQL spec, a single value is coerced
+// into a list of one item.
+func makeListParser(typ reflect.Type, inner *argParser) *argParser {
+	nullable := typ.Elem().Kind() == reflect.Ptr
 	return &argParser{
 		FromJSON: func(value interface{}, dest reflect.Value) error {
+			if value == nil {
+				return errors.New("not a list")
+			}
 			asSlice, ok := value.([]interface{})
 			if !ok {
-				return errors.New("not a list")
+				asSlice = []interface{}{value}
 			}
 
 			dest.Set(reflect.MakeSlice(typ, len(asSlice), len(asSlice)))
 
 			for i, value := range asSlice {
+				if value == nil && !nullable {
+					return fmt.Errorf("item %d: should not be null", i)
+				}
 				if err := inner.FromJSON(value, dest.Index(i)); err != nil {
-					return err
+					return fmt.Errorf("item %d: %s", i, err)
 				}
 			}
 
 			return nil
 		},
 		Type: typ,
-	}, &graphql.List{Type: argType}, nil
+	}
 }
 
 type schemaBuilder struct {
 	types        map[reflect.Type]graphql.Type
 	objects      map[reflect.Type]*Object
 	enumMappings map[reflect.Type]*EnumMapping
+	scalars      map[reflect.Type]*scalarMapping
+	interfaces   map[reflect.Type]*interfaceMapping
+	unions       map[string]*unionMapping
+	keyFields    map[*graphql.Object]string
+
+	builtInterfaces []*graphql.Interface
+	builtUnions     map[string]*graphql.Union
+
+	maxPaginationLimit int64
+	unboundedPageCost  int
+	fieldNameMapper    func(goName string) string
+
+	// lenientInputCoercion is set by WithLenientInputCoercion.
+	lenientInputCoercion bool
+
+	// nodeIDChecks are run once all types are built.
+	nodeIDChecks []nodeIDCheck
+
+	// pageInfoWithoutPages is the PageInfo type of connections without pages.
+	pageInfoWithoutPages *graphql.Object
+
+	// federation is set if the schema is a federation subgraph, see EnableFederation.
+	federation bool
+
+	// inputObjects caches the input objects built from structs by type.
+	inputObjects map[reflect.Type]*inputObject
+
+	// If collectErrors is set, fields that fail to build are skipped and their errors are
+	// collected in errors instead, see Schema.Validate. path holds the names of the object and
+	// fields being built, starting at the object the build started from.
+	collectErrors bool
+	errors        []error
+	path          []string
+}
+
+// collectError records the error of a field that failed to build with its path, and returns if
+// the field should be skipped, which is only the case while collecting errors.
+func (sb *schemaBuilder) collectError(name string, err error) bool {
+	if !sb.collectErrors {
+		return false
+	}
+	path := append(append([]string(nil), sb.path...), name)
+	sb.errors = append(sb.errors, fmt.Errorf("%s: %s", strings.Join(path, "."), err))
+	return true
+}
+
+// fieldName returns the GraphQL name of a Go struct field without an explicit
+// name.
+func (sb *schemaBuilder) fieldName(goName string) string {
+	if sb.fieldNameMapper != nil {
+		return sb.fieldNameMapper(goName)
+	}
+	return makeGraphql(goName)
+}
+
+// goFieldName returns the name of the Go field of the struct typ that is
+// exposed as the GraphQL field name, or "" if there is none.
+func (sb *schemaBuilder) goFieldName(typ reflect.Type, name string) string {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, _, err := parseTagOptions(field.Tag.Get("graphql"))
+		if err != nil {
+			continue
+		}
+		fieldName := strings.Split(tag, ",")[0]
+		if fieldName == "" {
+			fieldName = sb.fieldName(field.Name)
+		}
+		if fieldName == name {
+			return field.Name
+		}
+	}
+	return ""
 }
 
 type EnumMapping struct {
+	Name       string // Optional, defaults to the Go type's name.
 	Map        map[string]interface{}
 	ReverseMap map[interface{}]string
+
+	// DeprecationReasons maps the labels of deprecated values to the reason.
+	DeprecationReasons map[string]string
+}
+
+// graphqlType returns the enum of Go type typ with the given values.
+func (m *EnumMapping) graphqlType(typ reflect.Type, values []string) *graphql.Enum {
+	return &graphql.Enum{
+		Type:               m.typeName(typ),
+		Values:             values,
+		ReverseMap:         m.ReverseMap,
+		DeprecationReasons: m.DeprecationReasons,
+	}
+}
+
+// typeName returns the name of the enum of Go type typ.
+func (m *EnumMapping) typeName(typ reflect.Type) string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return typ.Name()
+}
+
+// scalarMapping is a custom scalar registered with RegisterScalar.
+type scalarMapping struct {
+	name      string
+	parse     func(interface{}) (interface{}, error)
+	serialize func(interface{}) interface{}
+}
+
+func (s *scalarMapping) graphqlType() *graphql.Scalar {
+	return &graphql.Scalar{Type: s.name, Serialize: s.serialize}
+}
+
+// argParser returns a parser which parses args of the scalar into values of typ.
+func (s *scalarMapping) argParser(typ reflect.Type) *argParser {
+	return &argParser{
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			parsed, err := s.parse(value)
+			if err != nil {
+				return err
+			}
+			parsedValue := reflect.ValueOf(parsed)
+			if !parsedValue.IsValid() || !parsedValue.Type().ConvertibleTo(typ) {
+				return fmt.Errorf("parsed %T, expected %s", parsed, typ)
+			}
+			dest.Set(parsedValue.Convert(dest.Type()))
+			return nil
+		},
+		Type: typ,
+	}
 }
 
 var errType reflect.Type
@@ -502,6 +1092,18 @@ func (funcCtx *funcContext) consumeContextAndSource(in []reflect.Type) []reflect
 	return in
 }
 
+// checkSource fails if the resolver takes a source of another type than the
+// object, e.g. of another object. It is called with the input types remaining
+// after consumeContextAndSource. Args are never pointers, so a pointer to a
+// struct that wasn't consumed as the source, other than the selection set, is a
+// source of the wrong type.
+func (funcCtx *funcContext) checkSource(in []reflect.Type) error {
+	if !funcCtx.hasSource && len(in) > 0 && in[0] != selectionSetType && in[0].Kind() == reflect.Ptr && in[0].Elem().Kind() == reflect.Struct {
+		return fmt.Errorf("%s source should be %s or *%s, not %s", funcCtx.funcType, funcCtx.typ, funcCtx.typ, in[0])
+	}
+	return nil
+}
+
 func (funcCtx *funcContext) parseReturnSignature(m *method) (err error) {
 
 	out := make([]reflect.Type, 0, funcCtx.funcType.NumOut())
@@ -585,7 +1187,16 @@ func (funcCtx *funcContext) getReturnType(sb *schemaBuilder, m *method) (graphql
 	var retType graphql.Type
 	if funcCtx.hasRet {
 		var err error
-		retType, err = sb.getType(funcCtx.funcType.Out(0))
+		out := funcCtx.funcType.Out(0)
+		// Streams are typed by the values they send.
+		if funcCtx.isStream {
+			out = out.Elem()
+		}
+		if m.UnionName != "" {
+			retType, err = sb.getUnionType(m.UnionName, out)
+		} else {
+			retType, err = sb.getType(out)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -639,14 +1250,19 @@ type funcContext struct {
 	hasSelectionSet bool
 	hasRet          bool
 	hasError        bool
+	isStream        bool
+
+	// returnsEdgeSource is set for paginated fields returning an EdgeSource.
+	returnsEdgeSource bool
+	// returnsEdges is set for paginated fields returning a []Edge.
+	returnsEdges bool
 
-	funcType     reflect.Type
-	isPtrFunc    bool
-	typ          reflect.Type
-	selectionSet *graphql.SelectionSet
+	funcType  reflect.Type
+	isPtrFunc bool
+	typ       reflect.Type
 }
 
-func (funcCtx *funcContext) prepareResolveArgs(source interface{}, args interface{}, ctx context.Context) []reflect.Value {
+func (funcCtx *funcContext) prepareResolveArgs(source interface{}, args interface{}, selectionSet *graphql.SelectionSet, ctx context.Context) []reflect.Value {
 
 	in := make([]reflect.Value, 0, funcCtx.funcType.NumIn())
 	if funcCtx.hasContext {
@@ -674,7 +1290,7 @@ func (funcCtx *funcContext) prepareResolveArgs(source interface{}, args interfac
 		in = append(in, reflect.ValueOf(args))
 	}
 	if funcCtx.hasSelectionSet {
-		in = append(in, reflect.ValueOf(funcCtx.selectionSet))
+		in = append(in, reflect.ValueOf(selectionSet))
 	}
 
 	return in
@@ -692,6 +1308,10 @@ func (sb *schemaBuilder) buildFunction(typ reflect.Type, m *method) (*graphql.Fi
 	in := funcCtx.getFuncInputTypes()
 	in = funcCtx.consumeContextAndSource(in)
 
+	if err := funcCtx.checkSource(in); err != nil {
+		return nil, err
+	}
+
 	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, in)
 	if err != nil {
 		return nil, err
@@ -712,6 +1332,16 @@ func (sb *schemaBuilder) buildFunction(typ reflect.Type, m *method) (*graphql.Fi
 		return nil, err
 	}
 
+	// Fields of the subscription root may return a stream of values.
+	if funcCtx.hasRet && typ == reflect.TypeOf(subscription{}) {
+		if out := funcCtx.funcType.Out(0); out.Kind() == reflect.Chan {
+			if out.ChanDir()&reflect.RecvDir == 0 {
+				return nil, fmt.Errorf("%s should return a receive channel", funcCtx.funcType)
+			}
+			funcCtx.isStream = true
+		}
+	}
+
 	retType, err := funcCtx.getReturnType(sb, m)
 	if err != nil {
 		return nil, err
@@ -722,21 +1352,30 @@ func (sb *schemaBuilder) buildFunction(typ reflect.Type, m *method) (*graphql.Fi
 		return nil, err
 	}
 
+	var argDefaultValues, argDescriptions map[string]string
+	if inputObject, ok := argType.(*graphql.InputObject); ok {
+		argDefaultValues = inputObject.DefaultValues
+		argDescriptions = inputObject.Descriptions
+	}
+
 	return &graphql.Field{
 		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
 			// Set up function arguments.
 
-			in := funcCtx.prepareResolveArgs(source, args, ctx)
+			in := funcCtx.prepareResolveArgs(source, args, selectionSet, ctx)
 			// Call the function.
 			out := fun.Call(in)
 
 			return funcCtx.extractResultAndErr(out, retType)
 
 		},
-		Args:           args,
-		Type:           retType,
-		ParseArguments: argParser.Parse,
-		Expensive:      funcCtx.hasContext,
+		Args:             args,
+		ArgDefaultValues: argDefaultValues,
+		ArgDescriptions:  argDescriptions,
+		Type:             retType,
+		ParseArguments:   argParser.Parse,
+		Expensive:        funcCtx.hasContext,
+		Stream:           funcCtx.isStream,
 	}, nil
 }
 
@@ -759,6 +1398,113 @@ func (sb *schemaBuilder) buildField(field reflect.StructField) (*graphql.Field,
 	}, nil
 }
 
+// buildPromotedField builds a field of typ promoted from an embedded struct. If a struct on the
+// way is embedded by pointer, the field is nullable and resolves to null while the pointer is nil.
+func (sb *schemaBuilder) buildPromotedField(typ reflect.Type, field reflect.StructField) (*graphql.Field, error) {
+	retType, err := sb.getType(field.Type)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range field.Index[:len(field.Index)-1] {
+		embed := typ.Field(i)
+		typ = embed.Type
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+			if nonNull, ok := retType.(*graphql.NonNull); ok {
+				retType = nonNull.Type
+			}
+		}
+	}
+
+	return &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			value := reflect.ValueOf(source)
+			for _, i := range field.Index {
+				if value.Kind() == reflect.Ptr {
+					if value.IsNil() {
+						return nil, nil
+					}
+					value = value.Elem()
+				}
+				value = value.Field(i)
+			}
+			return value.Interface(), nil
+		},
+		Type:           retType,
+		ParseArguments: nilParseArguments,
+	}, nil
+}
+
+// objectFields returns the exported fields of an object struct, followed by the fields promoted
+// from its anonymous embedded structs, e.g. the fields of User in
+//   type Admin struct {
+//     User
+//     Level int
+//   }
+// Embedded structs that are tagged with a name, or that aren't objects, such as custom scalars,
+// remain fields of their own. The Index of promoted fields is relative to typ.
+func (sb *schemaBuilder) objectFields(typ reflect.Type) ([]reflect.StructField, error) {
+	var fields, embedded []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		promote, err := sb.isPromotedEmbed(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad type %s: field %s: %s", typ, field.Name, err)
+		}
+		if promote {
+			embedded = append(embedded, field)
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	for _, embed := range embedded {
+		embedType := embed.Type
+		if embedType.Kind() == reflect.Ptr {
+			embedType = embedType.Elem()
+		}
+		promoted, err := sb.objectFields(embedType)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range promoted {
+			field.Index = append(append([]int(nil), embed.Index...), field.Index...)
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// isPromotedEmbed returns if the fields of an anonymous embedded struct are promoted onto the
+// embedding object.
+func (sb *schemaBuilder) isPromotedEmbed(field reflect.StructField) (bool, error) {
+	if !field.Anonymous || field.Type == unionType || field.Type == edgeType {
+		return false, nil
+	}
+	if tag, _, _ := parseTagOptions(field.Tag.Get("graphql")); tag != "" {
+		return false, nil
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false, nil
+	}
+	built, err := sb.getType(typ)
+	if err != nil {
+		return false, err
+	}
+	if nonNull, ok := built.(*graphql.NonNull); ok {
+		built = nonNull.Type
+	}
+	_, ok := built.(*graphql.Object)
+	return ok, nil
+}
+
 func (sb *schemaBuilder) buildUnionStruct(typ reflect.Type) error {
 	var name string
 	var description string
@@ -823,11 +1569,13 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	var description string
 	var methods Methods
 	var objectKey string
+	var duplicateFields []string
 	if object, ok := sb.objects[typ]; ok {
 		name = object.Name
 		description = object.Description
 		methods = object.Methods
 		objectKey = object.key
+		duplicateFields = object.duplicateFields
 	}
 
 	if name == "" {
@@ -836,6 +1584,20 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 			return fmt.Errorf("bad type %s: should have a name", typ)
 		}
 	}
+	if description == "" {
+		tagDescription, err := blankFieldDescription(typ)
+		if err != nil {
+			return fmt.Errorf("bad type %s: %s", typ, err)
+		}
+		description = tagDescription
+	}
+	if len(sb.path) == 0 {
+		sb.path = []string{name}
+		defer func() { sb.path = nil }()
+	}
+	if len(duplicateFields) > 0 {
+		return fmt.Errorf("bad type %s: object %s has multiple FieldFuncs named %s", typ, name, duplicateFields[0])
+	}
 
 	isScalarType := func(typ graphql.Type) bool {
 		if nonNull, ok := typ.(*graphql.NonNull); ok {
@@ -852,21 +1614,27 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		Description: description,
 		Fields:      make(map[string]*graphql.Field),
 	}
+	// Register the object before building its fields, so that self-referential
+	// and mutually recursive types resolve to this object instead of recursing.
 	sb.types[typ] = object
 
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if field.PkgPath != "" {
-			continue
+	fields, err := sb.objectFields(typ)
+	if err != nil {
+		return err
+	}
+	promoted := make(map[string]bool)
+	for _, field := range fields {
+		tag, options, err := parseTagOptions(field.Tag.Get("graphql"))
+		if err != nil {
+			return fmt.Errorf("bad type %s: %s", typ, err)
 		}
-
-		tags := strings.Split(field.Tag.Get("graphql"), ",")
+		tags := strings.Split(tag, ",")
 		var name string
 		if len(tags) > 0 {
 			name = tags[0]
 		}
 		if name == "" {
-			name = makeGraphql(field.Name)
+			name = sb.fieldName(field.Name)
 		}
 		if name == "-" {
 			continue
@@ -882,16 +1650,72 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 				key = true
 			}
 		}
+		for option := range options {
+			if option != "description" && option != "deprecated" && option != "type" && !flagTagOptions[option] {
+				return fmt.Errorf("bad type %s: field %s has unexpected tag option %s", typ, name, option)
+			}
+		}
+		_, nonNull := options["nonnull"]
+		_, nullable := options["nullable"]
+		if nonNull && nullable {
+			return fmt.Errorf("bad type %s: field %s can't be both nonnull and nullable", typ, name)
+		}
+		_, external := options["external"]
+		if external && !sb.federation {
+			return fmt.Errorf("bad type %s: field %s can only be external if federation is enabled", typ, name)
+		}
 
 		if _, ok := object.Fields[name]; ok {
+			// Fields of the outer struct, and of shallower embedded structs, win over the
+			// fields promoted from embedded structs.
+			if len(field.Index) > 1 {
+				continue
+			}
 			return fmt.Errorf("bad type %s: two fields named %s", typ, name)
 		}
+		if name == "__typename" {
+			return fmt.Errorf("bad type %s: field %s is reserved", typ, name)
+		}
 
-		built, err := sb.buildField(field)
+		sb.path = append(sb.path, name)
+		var built *graphql.Field
+		if len(field.Index) > 1 {
+			built, err = sb.buildPromotedField(typ, field)
+		} else {
+			built, err = sb.buildField(field)
+		}
+		sb.path = sb.path[:len(sb.path)-1]
 		if err != nil {
+			if sb.collectError(name, err) {
+				continue
+			}
 			return fmt.Errorf("bad field %s on type %s: %s", name, typ, err)
 		}
+		built.Description = options["description"]
+		built.DeprecationReason = options["deprecated"]
+		if external {
+			built.Directives = append(built.Directives, "@external")
+		}
+		if typeName, ok := options["type"]; ok {
+			built.Type, err = getIDType(field.Type, typeName)
+			if err != nil {
+				return fmt.Errorf("bad type %s: field %s %s", typ, name, err)
+			}
+		}
+		if nonNull {
+			if _, ok := built.Type.(*graphql.NonNull); !ok {
+				built.Type = &graphql.NonNull{Type: built.Type}
+			}
+		}
+		if nullable {
+			if nonNullType, ok := built.Type.(*graphql.NonNull); ok {
+				built.Type = nonNullType.Type
+			}
+		}
 		object.Fields[name] = built
+		if len(field.Index) > 1 {
+			promoted[name] = true
+		}
 		if key {
 			if object.Key != nil {
 				return fmt.Errorf("bad type %s: multiple key fields", typ)
@@ -900,6 +1724,7 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 				return fmt.Errorf("bad type %s: key type must be scalar, got %T", typ, built.Type)
 			}
 			object.Key = built.Resolve
+			sb.keyFields[object] = name
 		}
 	}
 
@@ -911,20 +1736,57 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 
 	for _, name := range names {
 		method := methods[name]
+		if _, ok := object.Fields[name]; ok && !promoted[name] {
+			return fmt.Errorf("bad type %s: object %s has both a struct field and a FieldFunc named %s", typ, object.Name, name)
+		}
+		if name == "__typename" {
+			return fmt.Errorf("bad type %s: FieldFunc %s is reserved", typ, name)
+		}
 
+		sb.path = append(sb.path, name)
 		if method.Paginated {
 			typedField, err := sb.buildPaginatedField(typ, method)
+			sb.path = sb.path[:len(sb.path)-1]
 			if err != nil {
+				if sb.collectError(name, err) {
+					continue
+				}
 				return err
 			}
+			typedField.DeprecationReason = method.DeprecationReason
+			typedField.Cost = method.Cost
+			typedField.Timeout = method.Timeout
+			typedField.Authorize = method.Authorize
+			typedField.Memoize = method.Memoize
+			typedField.BestEffort = method.BestEffort
+			typedField.CacheTTL = method.CacheTTL
+			if nonNullType, ok := typedField.Type.(*graphql.NonNull); ok && (method.Authorize != nil || method.BestEffort) {
+				typedField.Type = nonNullType.Type
+			}
 			object.Fields[name] = typedField
 			continue
 		}
 
 		built, err := sb.buildFunction(typ, method)
+		sb.path = sb.path[:len(sb.path)-1]
 		if err != nil {
+			if sb.collectError(name, err) {
+				continue
+			}
 			return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
 		}
+		built.DeprecationReason = method.DeprecationReason
+		built.Cost = method.Cost
+		built.Timeout = method.Timeout
+		built.Authorize = method.Authorize
+		built.Memoize = method.Memoize
+		built.BestEffort = method.BestEffort
+		built.CacheTTL = method.CacheTTL
+		// Authorized fields resolve to null if their check fails, and best
+		// effort fields if their resolver does.
+		if nonNullType, ok := built.Type.(*graphql.NonNull); ok && (method.Authorize != nil || method.BestEffort) {
+			built.Type = nonNullType.Type
+		}
 		object.Fields[name] = built
 	}
 
@@ -938,6 +1800,7 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 			return fmt.Errorf("bad type %s: key type must be scalar, got %s", typ, keyPtr.Type.String())
 		}
 		object.Key = keyPtr.Resolve
+		sb.keyFields[object] = objectKey
 	}
 
 	return nil
@@ -960,6 +1823,13 @@ var scalars = map[reflect.Type]string{
 	reflect.TypeOf(string("")):  "string",
 	reflect.TypeOf(time.Time{}): "Time",
 	reflect.TypeOf([]byte{}):    "bytes",
+
+	// Uploads are files sent with multipart requests, see graphql.WithHTTPUploads.
+	reflect.TypeOf(graphql.Upload{}): "Upload",
+
+	// JSON values are passed through as-is.
+	reflect.TypeOf(json.RawMessage{}):        "JSON",
+	reflect.TypeOf(map[string]interface{}{}): "JSON",
 }
 
 func getScalar(typ reflect.Type) (string, bool) {
@@ -971,13 +1841,60 @@ func getScalar(typ reflect.Type) (string, bool) {
 	return "", false
 }
 
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// getTextScalar returns a scalar named after typ if typ, or a pointer to it,
+// implements encoding.TextMarshaler. Fields of the scalar are serialized as
+// their text, and args are parsed from a string with encoding.TextUnmarshaler.
+// Built-in scalars like time.Time and structs, which are exposed as objects,
+// aren't text scalars.
+func getTextScalar(typ reflect.Type) (*scalarMapping, bool) {
+	if _, ok := scalars[typ]; ok {
+		return nil, false
+	}
+	if typ.Name() == "" || typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface || typ.Kind() == reflect.Struct {
+		return nil, false
+	}
+	if !typ.Implements(textMarshalerType) && !reflect.PtrTo(typ).Implements(textMarshalerType) {
+		return nil, false
+	}
+
+	return &scalarMapping{
+		name: typ.Name(),
+		parse: func(value interface{}) (interface{}, error) {
+			asString, ok := value.(string)
+			if !ok {
+				return nil, errors.New("not a string")
+			}
+			ptr := reflect.New(typ)
+			unmarshaler, ok := ptr.Interface().(encoding.TextUnmarshaler)
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't implement encoding.TextUnmarshaler", typ)
+			}
+			if err := unmarshaler.UnmarshalText([]byte(asString)); err != nil {
+				return nil, err
+			}
+			return ptr.Elem().Interface(), nil
+		},
+		serialize: func(value interface{}) interface{} {
+			ptr := reflect.New(typ)
+			ptr.Elem().Set(reflect.ValueOf(value))
+			text, err := ptr.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil
+			}
+			return string(text)
+		},
+	}, true
+}
+
 func (sb *schemaBuilder) getEnum(typ reflect.Type) (string, []string, bool) {
 	if sb.enumMappings[typ] != nil {
 		var values []string
 		for mapping := range sb.enumMappings[typ].Map {
 			values = append(values, mapping)
 		}
-		return typ.Name(), values, true
+		return sb.enumMappings[typ].typeName(typ), values, true
 	}
 	return "", nil, false
 }
@@ -993,10 +1910,32 @@ func hasUnionMarkerEmbedded(typ reflect.Type) bool {
 }
 
 func (sb *schemaBuilder) getType(t reflect.Type) (graphql.Type, error) {
+	// Custom scalars have precedence over everything else, so that they can be
+	// registered for aliases of built-in scalars and for structs.
+	if scalar, ok := sb.scalars[t]; ok {
+		return &graphql.NonNull{Type: scalar.graphqlType()}, nil
+	}
+	if t.Kind() == reflect.Ptr {
+		if scalar, ok := sb.scalars[t.Elem()]; ok {
+			return scalar.graphqlType(), nil
+		}
+	}
+
 	// Support scalars and optional scalars. Scalars have precedence over structs
 	// to have eg. time.Time function as a scalar.
-	if typ, values, ok := sb.getEnum(t); ok {
-		return &graphql.NonNull{Type: &graphql.Enum{Type: typ, Values: values, ReverseMap: sb.enumMappings[t].ReverseMap}}, nil
+	if _, values, ok := sb.getEnum(t); ok {
+		return &graphql.NonNull{Type: sb.enumMappings[t].graphqlType(t, values)}, nil
+	}
+
+	// Types implementing encoding.TextMarshaler are serialized as their text,
+	// even if they are aliases of built-in scalars.
+	if scalar, ok := getTextScalar(t); ok {
+		return &graphql.NonNull{Type: scalar.graphqlType()}, nil
+	}
+	if t.Kind() == reflect.Ptr {
+		if scalar, ok := getTextScalar(t.Elem()); ok {
+			return scalar.graphqlType(), nil
+		}
 	}
 
 	if typ, ok := getScalar(t); ok {
@@ -1022,6 +1961,16 @@ func (sb *schemaBuilder) getType(t reflect.Type) (graphql.Type, error) {
 		return sb.types[t.Elem()], nil
 	}
 
+	// Interfaces
+	if t.Kind() == reflect.Interface {
+		if _, ok := sb.interfaces[t]; ok {
+			if err := sb.buildInterface(t); err != nil {
+				return nil, err
+			}
+			return sb.types[t], nil
+		}
+	}
+
 	switch t.Kind() {
 	case reflect.Slice:
 		typ, err := sb.getType(t.Elem())
@@ -1036,20 +1985,141 @@ func (sb *schemaBuilder) getType(t reflect.Type) (graphql.Type, error) {
 
 		return &graphql.NonNull{Type: &graphql.List{Type: typ}}, nil
 
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("bad type %s: map keys should be strings", t)
+		}
+		if err := sb.buildMapEntry(t); err != nil {
+			return nil, err
+		}
+		return &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: sb.types[t]}}}, nil
+
 	default:
-		return nil, fmt.Errorf("bad type %s: should be a scalar, slice, or struct type", t)
+		return nil, fmt.Errorf("bad type %s: should be a scalar, slice, map, or struct type", t)
+	}
+}
+
+// buildMapEntry builds the object of the entries of a map type, which have a
+// key and a value field. Map types with the same key and value types share
+// their entry object, e.g. StringToInt64Entry for map[string]int64.
+func (sb *schemaBuilder) buildMapEntry(t reflect.Type) error {
+	if sb.types[t] != nil {
+		return nil
+	}
+
+	keyType, err := sb.getType(t.Key())
+	if err != nil {
+		return err
+	}
+	valueType, err := sb.getType(t.Elem())
+	if err != nil {
+		return err
+	}
+
+	object := &graphql.Object{
+		Name:   fmt.Sprintf("%sTo%sEntry", entryTypeName(keyType), entryTypeName(valueType)),
+		Fields: make(map[string]*graphql.Field),
+	}
+	sb.types[t] = object
+
+	object.Fields["key"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			return source.(graphql.MapEntry).Key, nil
+		},
+		Type:           keyType,
+		ParseArguments: nilParseArguments,
+	}
+	object.Fields["value"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			return source.(graphql.MapEntry).Value, nil
+		},
+		Type:           valueType,
+		ParseArguments: nilParseArguments,
+	}
+	return nil
+}
+
+// entryTypeName returns the name of typ used in the name of map entry objects,
+// e.g. Int64 for int64! and UserList for [User!]!.
+func entryTypeName(typ graphql.Type) string {
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		return entryTypeName(typ.Type)
+	case *graphql.List:
+		return entryTypeName(typ.Type) + "List"
+	default:
+		name := typ.String()
+		return strings.ToUpper(name[:1]) + name[1:]
 	}
 }
 
 type Schema struct {
-	objects   map[string]*Object
-	enumTypes map[reflect.Type]*EnumMapping
+	objects    map[string]*Object
+	enumTypes  map[reflect.Type]*EnumMapping
+	scalars    map[reflect.Type]*scalarMapping
+	interfaces map[reflect.Type]*interfaceMapping
+	unions     map[string]*unionMapping
+	directives map[string]*graphql.Directive
+
+	maxPaginationLimit   int64
+	unboundedPageCost    int
+	lenientInputCoercion bool
+	federation           bool
+	relayNode            bool
+	fieldNameMapper      func(goName string) string
+	middlewares          []FieldMiddleware
+}
+
+// A SchemaOption configures a Schema created by NewSchema.
+type SchemaOption func(*Schema)
+
+// WithMaxPaginationLimit sets the default maximum first and last args on all
+// paginated fields of the schema. It can be overridden per field with
+// Paginated.WithMaxLimit.
+func WithMaxPaginationLimit(limit int64) SchemaOption {
+	return func(s *Schema) {
+		s.maxPaginationLimit = limit
+	}
+}
+
+// defaultUnboundedPageCost is the page size that graphql.QueryCost assumes for
+// paginated fields without a first, last, default or max limit.
+const defaultUnboundedPageCost = 100
+
+// WithUnboundedPageCost sets the page size that graphql.QueryCost assumes for
+// paginated fields that return all their nodes because neither the query nor
+// the field limits their page size. It defaults to 100.
+func WithUnboundedPageCost(size int) SchemaOption {
+	return func(s *Schema) {
+		s.unboundedPageCost = size
+	}
+}
+
+// WithLenientInputCoercion makes args and input fields accept numeric strings,
+// e.g. "5", for Int and Float, and the numbers 0 and 1 for Boolean, for
+// clients that can't send properly typed values. By default they are rejected.
+func WithLenientInputCoercion() SchemaOption {
+	return func(s *Schema) {
+		s.lenientInputCoercion = true
+	}
 }
 
-func NewSchema() *Schema {
-	return &Schema{
+// SetFieldNameMapper sets the function converting the names of Go struct
+// fields into the names of object fields, input fields and args, e.g. to use
+// snake_case instead of the default lowerCamelCase. Names given to FieldFunc or
+// in graphql tags are used as is.
+func (s *Schema) SetFieldNameMapper(mapper func(goName string) string) {
+	s.fieldNameMapper = mapper
+}
+
+func NewSchema(opts ...SchemaOption) *Schema {
+	s := &Schema{
 		objects: make(map[string]*Object),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Enum registers an enumType in the schema. The val should be any arbitrary value
@@ -1080,6 +2150,112 @@ func (s *Schema) Enum(val interface{}, enumMap interface{}) {
 	s.enumTypes[typ] = &EnumMapping{Map: eMap, ReverseMap: rMap}
 }
 
+// RegisterScalar registers a custom scalar named name for the Go type of
+// zeroValue, so that the type isn't exposed as an object. Args of the scalar are
+// parsed from their JSON value with parse, which must return a value of the Go
+// type, and fields are serialized with serialize.
+//
+// For example a Decimal type could be registered as:
+//   s.RegisterScalar("Decimal", Decimal{}, func(value interface{}) (interface{}, error) {
+//     asString, ok := value.(string)
+//     if !ok {
+//       return nil, errors.New("not a string")
+//     }
+//     return ParseDecimal(asString)
+//   }, func(value interface{}) interface{} {
+//     return value.(Decimal).String()
+//   })
+func (s *Schema) RegisterScalar(name string, zeroValue interface{}, parse func(interface{}) (interface{}, error), serialize func(interface{}) interface{}) {
+	if s.scalars == nil {
+		s.scalars = make(map[reflect.Type]*scalarMapping)
+	}
+	s.scalars[reflect.TypeOf(zeroValue)] = &scalarMapping{
+		name:      name,
+		parse:     parse,
+		serialize: serialize,
+	}
+}
+
+// RegisterDirective registers a custom directive named name that can be used
+// at the given locations of a query. The executor calls apply with the
+// resolved value of every field the directive is used on and the directive's
+// args, and uses the returned value instead.
+//
+// For example an @uppercase directive could be registered as:
+//   s.RegisterDirective("uppercase", []graphql.DirectiveLocation{graphql.DirectiveLocationField},
+//     func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error) {
+//       return strings.ToUpper(value.(string)), nil
+//     })
+func (s *Schema) RegisterDirective(name string, locations []graphql.DirectiveLocation, apply func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error)) {
+	if s.directives == nil {
+		s.directives = make(map[string]*graphql.Directive)
+	}
+	s.directives[name] = &graphql.Directive{
+		Name:      name,
+		Locations: locations,
+		Apply:     apply,
+	}
+}
+
+// RegisterEnum registers an enum named name. The keys of valueMap are the
+// values of the enum's Go type, and the values of valueMap are their labels.
+// Args of the enum type only accept the labels.
+//
+// For example a enum could be declared as follows:
+//   type Role int32
+//   const (
+//     Admin  Role = 1
+//     Member Role = 2
+//   )
+//
+// Then the enum can be registered as:
+//   s.RegisterEnum("Role", map[interface{}]string{
+//     Admin:  "ADMIN",
+//     Member: "MEMBER",
+//   })
+func (s *Schema) RegisterEnum(name string, valueMap map[interface{}]string) {
+	var typ reflect.Type
+	eMap := make(map[string]interface{})
+	rMap := make(map[interface{}]string)
+	for value, label := range valueMap {
+		if typ == nil {
+			typ = reflect.TypeOf(value)
+		} else if reflect.TypeOf(value) != typ {
+			panic("enum values have different types")
+		}
+		if _, ok := eMap[label]; ok {
+			panic("duplicate enum label")
+		}
+		eMap[label] = value
+		rMap[value] = label
+	}
+	if typ == nil {
+		panic("enum has no values")
+	}
+
+	if s.enumTypes == nil {
+		s.enumTypes = make(map[reflect.Type]*EnumMapping)
+	}
+	s.enumTypes[typ] = &EnumMapping{Name: name, Map: eMap, ReverseMap: rMap}
+}
+
+// DeprecateEnumValue marks a value of a registered enum as deprecated with the
+// given reason. The value can still be used.
+func (s *Schema) DeprecateEnumValue(value interface{}, reason string) {
+	mapping, ok := s.enumTypes[reflect.TypeOf(value)]
+	if !ok {
+		panic("enum is not registered")
+	}
+	label, ok := mapping.ReverseMap[value]
+	if !ok {
+		panic("unknown enum value")
+	}
+	if mapping.DeprecationReasons == nil {
+		mapping.DeprecationReasons = make(map[string]string)
+	}
+	mapping.DeprecationReasons[label] = reason
+}
+
 func getEnumMap(enumMap interface{}, typ reflect.Type) (map[string]interface{}, map[interface{}]string) {
 	rMap := make(map[interface{}]string)
 	eMap := make(map[string]interface{})
@@ -1136,38 +2312,103 @@ func (s *Schema) Mutation() *Object {
 	return s.Object("Mutation", mutation{})
 }
 
+type subscription struct{}
+
+// Subscription returns the root object of subscriptions. Unlike the query and
+// mutation, the schema only has a subscription root if Subscription is called.
+//
+// Fields of the subscription root may return a receive channel, e.g.
+// (<-chan *Event, error), to send a result for every value received from it,
+// see graphql.Field.Stream. They are typed by the channel's element type.
+func (s *Schema) Subscription() *Object {
+	return s.Object("Subscription", subscription{})
+}
+
 func (s *Schema) Build() (*graphql.Schema, error) {
+	schema, _, err := s.build(false)
+	return schema, err
+}
+
+// build builds the schema, collecting the errors of fields that fail to build if collectErrors
+// is set. It also returns the schemaBuilder, which holds the built types.
+func (s *Schema) build(collectErrors bool) (*graphql.Schema, *schemaBuilder, error) {
 	sb := &schemaBuilder{
 		types:        make(map[reflect.Type]graphql.Type),
 		objects:      make(map[reflect.Type]*Object),
 		enumMappings: s.enumTypes,
+		scalars:      s.scalars,
+		interfaces:   s.interfaces,
+		unions:       s.unions,
+		builtUnions:  make(map[string]*graphql.Union),
+		keyFields:    make(map[*graphql.Object]string),
+
+		maxPaginationLimit:   s.maxPaginationLimit,
+		unboundedPageCost:    s.unboundedPageCost,
+		lenientInputCoercion: s.lenientInputCoercion,
+		fieldNameMapper:      s.fieldNameMapper,
+		federation:           s.federation,
+		collectErrors:        collectErrors,
 	}
 
 	for _, object := range s.objects {
 		typ := reflect.TypeOf(object.Type)
 		if typ.Kind() != reflect.Struct {
-			return nil, fmt.Errorf("object.Type should be a struct, not %s", typ.String())
+			return nil, nil, fmt.Errorf("object.Type should be a struct, not %s", typ.String())
 		}
 
 		if _, ok := sb.objects[typ]; ok {
-			return nil, fmt.Errorf("duplicate object for %s", typ.String())
+			return nil, nil, fmt.Errorf("duplicate object for %s", typ.String())
 		}
 
 		sb.objects[typ] = object
 	}
 
+	if err := sb.buildUnions(); err != nil {
+		return nil, nil, err
+	}
+
 	queryTyp, err := sb.getType(reflect.TypeOf(&query{}))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	mutationTyp, err := sb.getType(reflect.TypeOf(&mutation{}))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return &graphql.Schema{
-		Query:    queryTyp,
-		Mutation: mutationTyp,
-	}, nil
+	var subscriptionTyp graphql.Type
+	if _, ok := s.objects["Subscription"]; ok {
+		subscriptionTyp, err = sb.getType(reflect.TypeOf(&subscription{}))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if s.relayNode {
+		if err := sb.addRelayNode(queryTyp); err != nil {
+			return nil, nil, err
+		}
+	}
+	sb.addInterfaceFields()
+	if err := sb.checkNodeIDs(); err != nil {
+		return nil, nil, err
+	}
+
+	schema := &graphql.Schema{
+		Query:        queryTyp,
+		Mutation:     mutationTyp,
+		Subscription: subscriptionTyp,
+		Directives:   s.directives,
+	}
+
+	if s.federation {
+		if err := sb.addFederation(schema); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkTypeConflicts(schema); err != nil {
+		return nil, nil, err
+	}
+	applyMiddlewares(schema, s.middlewares)
+	return schema, sb, nil
 }
 
 // MustBuildSchema builds a schema and panics if an error occurs
@@ -2,6 +2,7 @@ package schemabuilder
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
 	"strings"
@@ -139,7 +140,7 @@ func TestExecuteGood(t *testing.T) {
 	ctx := context.WithValue(context.Background(), "foo", "hello there")
 
 	q := graphql.MustParse(`
-		{
+		query ($var: int64) {
 			users {
 				name
 				foo: age
@@ -196,6 +197,152 @@ func TestExecuteGood(t *testing.T) {
 	}
 }
 
+func TestTimeRoundTrip(t *testing.T) {
+	schema := NewSchema()
+	query := schema.Query()
+	query.FieldFunc("echo", func(args struct{ Time *time.Time }) *time.Time {
+		return args.Time
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			offset: echo(time: "2016-08-31T02:30:00+02:00")
+			utc: echo(time: "2016-08-31T00:30:00Z")
+			unix: echo(time: 1472603400)
+			null: echo
+		}
+	`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`
+		{
+			"offset": "2016-08-31T02:30:00+02:00",
+			"utc": "2016-08-31T00:30:00Z",
+			"unix": "2016-08-31T00:30:00Z",
+			"null": null
+		}`), internal.AsJSON(result))
+
+	q = graphql.MustParse(`{ echo(time: "2016-08-31") }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil {
+		t.Error("expected error parsing a time without a time of day")
+	}
+}
+
+func TestJSONScalar(t *testing.T) {
+	type Event struct {
+		Payload  json.RawMessage
+		Metadata map[string]interface{}
+	}
+
+	schema := NewSchema()
+	query := schema.Query()
+	query.FieldFunc("event", func(args struct {
+		Payload  json.RawMessage
+		Metadata map[string]interface{}
+	}) Event {
+		return Event{Payload: args.Payload, Metadata: args.Metadata}
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{
+			event(payload: [1, {a: "b"}], metadata: {nested: {list: [true, "x"]}}) {
+				payload
+				metadata
+			}
+		}
+	`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`
+		{
+			"event": {
+				"payload": [1, {"a": "b"}],
+				"metadata": {"nested": {"list": [true, "x"]}}
+			}
+		}`), internal.AsJSON(result))
+
+	q = graphql.MustParse(`{ event(payload: 1, metadata: {}) { metadata { nested } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil {
+		t.Error("expected error selecting fields of a JSON scalar")
+	}
+}
+
+type Email string
+
+func (e Email) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(string(e))), nil
+}
+
+func (e *Email) UnmarshalText(text []byte) error {
+	if !strings.Contains(string(text), "@") {
+		return errors.New("not an email")
+	}
+	*e = Email(text)
+	return nil
+}
+
+func TestTextMarshalerScalar(t *testing.T) {
+	type Contact struct {
+		Email   Email
+		Backup  *Email
+		Aliases []Email
+	}
+
+	schema := NewSchema()
+	query := schema.Query()
+	query.FieldFunc("contact", func(args struct {
+		Email  Email
+		Backup *Email
+	}) Contact {
+		return Contact{Email: args.Email, Backup: args.Backup, Aliases: []Email{args.Email + "-Alias"}}
+	})
+	builtSchema := schema.MustBuild()
+
+	contact := builtSchema.Query.(*graphql.Object).Fields["contact"]
+	assert.Equal(t, "Email!", contact.Args["email"].String())
+	assert.Equal(t, "Email", contact.Args["backup"].String())
+
+	q := graphql.MustParse(`{ contact(email: "Jane@Example.com") { email backup aliases } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`
+		{
+			"contact": {
+				"email": "jane@example.com",
+				"backup": null,
+				"aliases": ["jane@example.com-alias"]
+			}
+		}`), internal.AsJSON(result))
+
+	q = graphql.MustParse(`{ contact(email: "jane") { email } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err == nil || err.Error() != `error parsing args for "contact": email: not an email` {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
 func TestEnumMapKeys(t *testing.T) {
 	schema := NewSchema()
 	defer func() {
@@ -674,3 +821,274 @@ func TestObjectKeyMustBeScalar(t *testing.T) {
 	})
 
 }
+
+func TestNullabilityTags(t *testing.T) {
+	type object struct {
+		Name     string
+		Nickname string  `graphql:"nickname;nullable"`
+		Email    *string `graphql:"email;nonnull"`
+		Phone    *string
+	}
+	builder := NewSchema()
+	builder.Query().FieldFunc("object", func() object { return object{} })
+	schema, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := schema.Query.(*graphql.Object).Fields["object"].Type.(*graphql.NonNull).Type.(*graphql.Object).Fields
+	assert.Equal(t, "string!", fields["name"].Type.String())
+	assert.Equal(t, "string", fields["nickname"].Type.String())
+	assert.Equal(t, "string!", fields["email"].Type.String())
+	assert.Equal(t, "string", fields["phone"].Type.String())
+
+	type conflicting struct {
+		Name string `graphql:"name;nonnull;nullable"`
+	}
+	builder = NewSchema()
+	builder.Query().FieldFunc("conflicting", func() conflicting { return conflicting{} })
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "can't be both nonnull and nullable") {
+		t.Errorf("expected conflicting tags error, got %v", err)
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	type Category struct {
+		Name     string
+		Parent   *Category
+		Children []*Category
+	}
+
+	root := &Category{Name: "root"}
+	child := &Category{Name: "child", Parent: root}
+	grandchild := &Category{Name: "grandchild", Parent: child}
+	root.Children = []*Category{child}
+	child.Children = []*Category{grandchild}
+
+	builder := NewSchema()
+	builder.Query().FieldFunc("category", func() *Category { return root })
+	schema := builder.MustBuild()
+
+	category := schema.Query.(*graphql.Object).Fields["category"].Type.(*graphql.Object)
+	assert.Equal(t, category, category.Fields["parent"].Type)
+	assert.Equal(t, category, category.Fields["children"].Type.(*graphql.NonNull).Type.(*graphql.List).Type.(*graphql.NonNull).Type)
+
+	q := graphql.MustParse(`{
+		category {
+			name
+			children {
+				name
+				parent { name }
+				children { name parent { name } }
+			}
+		}
+	}`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), schema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"category": {
+			"name": "root",
+			"children": [{
+				"name": "child",
+				"parent": {"name": "root"},
+				"children": [{"name": "grandchild", "parent": {"name": "child"}}]
+			}]
+		}
+	}`), internal.AsJSON(result))
+}
+
+func TestMapEntries(t *testing.T) {
+	type Stats struct {
+		Counts map[string]int64
+		Totals map[string]int64
+		Users  map[string]*User
+	}
+
+	builder := NewSchema()
+	builder.Query().FieldFunc("stats", func() Stats {
+		return Stats{
+			Counts: map[string]int64{"b": 2, "a": 1, "c": 3},
+			Users:  map[string]*User{"bob": {Name: "bob"}},
+		}
+	})
+	schema := builder.MustBuild()
+
+	stats := schema.Query.(*graphql.Object).Fields["stats"].Type.(*graphql.NonNull).Type.(*graphql.Object)
+	assert.Equal(t, "[StringToInt64Entry!]!", stats.Fields["counts"].Type.String())
+	assert.Equal(t, stats.Fields["counts"].Type, stats.Fields["totals"].Type)
+	assert.Equal(t, "[StringToUserEntry!]!", stats.Fields["users"].Type.String())
+
+	q := graphql.MustParse(`{ stats { counts { key value } totals { key } users { key value { name } } } }`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), schema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"stats": {
+			"counts": [{"key": "a", "value": 1}, {"key": "b", "value": 2}, {"key": "c", "value": 3}],
+			"totals": [],
+			"users": [{"key": "bob", "value": {"__key": "bob", "name": "bob"}}]
+		}
+	}`), internal.AsJSON(result))
+}
+
+func TestFieldNameMapper(t *testing.T) {
+	type Filter struct {
+		MinAge int64
+	}
+	type Person struct {
+		FirstName string
+		LastName  string `graphql:"surname"`
+	}
+
+	snakeCase := func(goName string) string {
+		var b strings.Builder
+		for i, c := range goName {
+			if i > 0 && c >= 'A' && c <= 'Z' {
+				b.WriteByte('_')
+			}
+			b.WriteString(strings.ToLower(string(c)))
+		}
+		return b.String()
+	}
+
+	builder := NewSchema()
+	builder.SetFieldNameMapper(snakeCase)
+	query := builder.Query()
+	query.FieldFunc("people", func(args struct {
+		NameFilter string
+		AgeFilter  *Filter
+	}) []Person {
+		return []Person{{FirstName: args.NameFilter, LastName: "smith"}}
+	})
+	query.FieldFunc("peopleConnection", func(args struct {
+		NamePrefix string
+	}) []Person {
+		return nil
+	}, Paginated)
+	person := builder.Object("Person", Person{})
+	person.Key("surname")
+	person.FieldFunc("fullName", func(p Person) string {
+		return p.FirstName + " " + p.LastName
+	})
+	schema, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := graphql.MustParse(`{
+		people(name_filter: "jane", age_filter: {min_age: 3}) { first_name surname fullName }
+		peopleConnection(name_prefix: "j", first: 1) { totalCount }
+	}`, nil)
+	if err := graphql.PrepareQuery(schema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), schema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"people": [{"__key": "smith", "first_name": "jane", "surname": "smith", "fullName": "jane smith"}],
+		"peopleConnection": {"totalCount": 0}
+	}`), internal.AsJSON(result))
+}
+
+type validatePet interface {
+	Name() string
+}
+
+func TestValidate(t *testing.T) {
+	type User struct {
+		Name string
+		Pet  validatePet
+	}
+	type Group struct {
+		Name string
+	}
+	type Orphan struct {
+		Name string
+	}
+
+	builder := NewSchema()
+	query := builder.Query()
+	query.FieldFunc("user", func() *User { return nil })
+	query.FieldFunc("groups", func() []Group { return nil })
+	builder.Object("Orphan", Orphan{})
+	group := builder.Object("Group", Group{})
+	group.FieldFunc("owner", func() validatePet { return nil })
+
+	err := builder.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	assert.Equal(t, []string{
+		"Query.groups.owner: bad type schemabuilder.validatePet: should be a scalar, slice, map, or struct type",
+		"Query.user.pet: bad type schemabuilder.validatePet: should be a scalar, slice, map, or struct type",
+		"Orphan: object is unreachable from the root types",
+	}, strings.Split(errs.Error(), "\n"))
+
+	// Build still fails on the first error.
+	if _, err := builder.Build(); err == nil || strings.Contains(err.Error(), "\n") {
+		t.Errorf("expected a single build error, got %v", err)
+	}
+
+	builder = NewSchema()
+	builder.Query().FieldFunc("groups", func() []Group { return nil })
+	builder.Object("Group", Group{})
+	assert.Nil(t, builder.Validate())
+}
+
+func TestDuplicateFields(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+
+	builder := NewSchema()
+	builder.Query().FieldFunc("user", func() User { return User{} })
+	user := builder.Object("User", User{})
+	user.FieldFunc("name", func(u User) string { return u.Name })
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "object User has both a struct field and a FieldFunc named name") {
+		t.Errorf("expected struct field collision error, got %v", err)
+	}
+
+	builder = NewSchema()
+	builder.Query().FieldFunc("user", func() User { return User{} })
+	builder.Query().FieldFunc("user", func() User { return User{} })
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "object Query has multiple FieldFuncs named user") {
+		t.Errorf("expected duplicate FieldFunc error, got %v", err)
+	}
+
+	builder = NewSchema()
+	builder.Query().FieldFunc("__typename", func() string { return "" })
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "FieldFunc __typename is reserved") {
+		t.Errorf("expected reserved field error, got %v", err)
+	}
+
+	type Item struct {
+		Uuid string
+		Id   int64
+	}
+	builder = NewSchema()
+	builder.EnableRelayNode()
+	builder.Query().FieldFunc("item", func() Item { return Item{} })
+	builder.Object("Item", Item{}).Key("uuid")
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "bad field id on type Item: conflicts with the global ID of EnableRelayNode") {
+		t.Errorf("expected relay id collision error, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package schemabuilder
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// EnableRelayNode adds Relay's global object identification to the schema. All
+// objects with a key implement a Node interface, and their id field is replaced
+// by an opaque global ID encoding the object's type name and key. The built
+// query object gets a node field fetching an object by its global ID with the
+// function registered with NodeResolver.
+func (s *Schema) EnableRelayNode() {
+	s.relayNode = true
+}
+
+// encodeGlobalID returns the global ID of the object of type typeName with the
+// given key.
+func encodeGlobalID(typeName string, key interface{}) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%v", typeName, key)))
+}
+
+// decodeGlobalID returns the type name and key encoded in a global ID.
+func decodeGlobalID(id string) (string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(id)
+	if err != nil {
+		return "", "", errors.New("malformed id")
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("malformed id")
+	}
+	return parts[0], parts[1], nil
+}
+
+// addRelayNode adds the Node interface and the node field to the built query
+// type. It runs before addInterfaceFields, so that other interfaces see the
+// replaced id fields.
+func (sb *schemaBuilder) addRelayNode(queryTyp graphql.Type) error {
+	query, ok := queryTyp.(*graphql.Object)
+	if !ok {
+		return fmt.Errorf("bad query type %s: should be an object", queryTyp)
+	}
+	for _, iface := range sb.builtInterfaces {
+		if iface.Name == "Node" {
+			return errors.New("bad interface Node: conflicts with EnableRelayNode")
+		}
+	}
+	if _, ok := query.Fields["node"]; ok {
+		return errors.New("bad field node on query: conflicts with EnableRelayNode")
+	}
+
+	idType := &graphql.NonNull{Type: idScalar()}
+	resolvers := make(map[string]NodeResolver)
+	objectNames := make(map[reflect.Type]string)
+	node := &graphql.Interface{
+		Name:   "Node",
+		Fields: make(map[string]*graphql.Field),
+		Types:  make(map[string]*graphql.Object),
+		ResolveType: func(value interface{}) string {
+			typ := reflect.TypeOf(value)
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+			return objectNames[typ]
+		},
+	}
+
+	// Build the objects in a stable order, so that errors are deterministic.
+	var typs []reflect.Type
+	for typ := range sb.objects {
+		typs = append(typs, typ)
+	}
+	sort.Slice(typs, func(i, j int) bool { return typs[i].String() < typs[j].String() })
+
+	for _, typ := range typs {
+		if err := sb.buildStruct(typ); err != nil {
+			return err
+		}
+		built, ok := sb.types[typ].(*graphql.Object)
+		if !ok || built.Key == nil {
+			continue
+		}
+
+		// Only the key field may be replaced by the global ID, other id fields would be lost.
+		if _, ok := built.Fields["id"]; ok && sb.keyFields[built] != "id" {
+			return fmt.Errorf("bad field id on type %s: conflicts with the global ID of EnableRelayNode", built.Name)
+		}
+
+		key := built.Key
+		name := built.Name
+		built.Fields["id"] = &graphql.Field{
+			Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				value, err := key(ctx, source, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+				return encodeGlobalID(name, value), nil
+			},
+			Type:           idType,
+			ParseArguments: nilParseArguments,
+		}
+		if built.Interfaces == nil {
+			built.Interfaces = make(map[string]*graphql.Interface)
+		}
+		built.Interfaces[node.Name] = node
+		node.Types[name] = built
+		objectNames[typ] = name
+		if resolver := sb.objects[typ].nodeResolver; resolver != nil {
+			resolvers[name] = resolver
+		}
+	}
+
+	node.Fields["id"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			return node.Types[node.ResolveType(source)].Fields["id"].Resolve(ctx, source, args, selectionSet)
+		},
+		Type:           idType,
+		ParseArguments: nilParseArguments,
+	}
+
+	query.Fields["node"] = &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			typeName, key, err := decodeGlobalID(args.(string))
+			if err != nil {
+				return nil, err
+			}
+			resolver, ok := resolvers[typeName]
+			if !ok {
+				return nil, fmt.Errorf("unknown node type %s", typeName)
+			}
+			value, err := resolver(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil && node.ResolveType(value) != typeName {
+				return nil, fmt.Errorf("node resolver for %s returned %T", typeName, value)
+			}
+			return value, nil
+		},
+		Type: node,
+		Args: map[string]graphql.Type{
+			"id": idType,
+		},
+		ParseArguments: func(json interface{}) (interface{}, error) {
+			args, ok := json.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("not an object")
+			}
+			for name := range args {
+				if name != "id" {
+					return nil, fmt.Errorf("unknown arg %s", name)
+				}
+			}
+			id, ok := args["id"].(string)
+			if !ok {
+				return nil, errors.New("id: not a string")
+			}
+			return id, nil
+		},
+		Expensive: true,
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package schemabuilder
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+	"time"
+)
 
 // A Object represents a Go type and set of methods to be converted into an
 // Object in a GraphQL schema.
@@ -10,7 +14,13 @@ type Object struct {
 	Type        interface{}
 	Methods     Methods // Deprecated, use FieldFunc instead.
 
-	key string
+	key               string
+	referenceResolver ReferenceResolver
+	nodeResolver      NodeResolver
+
+	// duplicateFields are the names passed to FieldFunc more than once, which
+	// fail the build.
+	duplicateFields []string
 }
 
 type paginationObject struct {
@@ -29,6 +39,73 @@ type fieldFuncOptionFunc func(*method)
 
 func (f fieldFuncOptionFunc) apply(m *method) { f(m) }
 
+// Deprecated is an option that can be passed to a FieldFunc to mark the field as
+// deprecated with the given reason. Deprecated fields still resolve normally.
+func Deprecated(reason string) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.DeprecationReason = reason
+	})
+}
+
+// Cost is an option that can be passed to a FieldFunc to set the cost of
+// resolving the field, which defaults to 1, for graphql.QueryCost.
+func Cost(cost int) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.Cost = cost
+	})
+}
+
+// WithTimeout is an option that can be passed to a FieldFunc to make the field
+// fail if its resolver doesn't return within timeout. The resolver's context is
+// canceled once the timeout expires.
+func WithTimeout(timeout time.Duration) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.Timeout = timeout
+	})
+}
+
+// Authorize is an option that can be passed to a FieldFunc to check if the
+// field may be resolved, e.g. for the user in ctx. The check runs before the
+// resolver with the object the field is resolved on. If it returns an error the
+// field resolves to null and the error is recorded as a field error, returned
+// by graphql.Executor.ExecuteResult. Authorized fields are therefore nullable.
+func Authorize(authorize func(ctx context.Context, source interface{}) error) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.Authorize = authorize
+	})
+}
+
+// Memoize is an option that can be passed to a FieldFunc to resolve the field
+// only once per object and args during an execution, even if a query selects it
+// several times, e.g. under different aliases or in several fragments.
+func Memoize() FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.Memoize = true
+	})
+}
+
+// BestEffort is an option that can be passed to a FieldFunc for fields that
+// may be missing from the response, e.g. data enriched from an unreliable
+// service. If the resolver fails, the field resolves to null without failing
+// the query or adding to its errors, and the error is passed to
+// graphql.Executor.OnBestEffortError. BestEffort fields are therefore nullable.
+func BestEffort() FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.BestEffort = true
+	})
+}
+
+// CacheResponse is an option that can be passed to a FieldFunc to make the
+// responses of queries that only select cacheable top-level fields cacheable
+// for ttl, e.g. by graphql.WithHTTPResponseCache. Only fields of the
+// query object whose value doesn't depend on the user, or only on the scope
+// set with graphql.WithResponseCacheScope, should be cacheable.
+func CacheResponse(ttl time.Duration) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.CacheTTL = ttl
+	})
+}
+
 // NonNullable is an option that can be passed to a FieldFunc to indicate that
 // its return value is required, even if the return value is a pointer type.
 var NonNullable fieldFuncOptionFunc = func(m *method) {
@@ -37,10 +114,166 @@ var NonNullable fieldFuncOptionFunc = func(m *method) {
 
 // Paginated is an option that can be passed to a FieldFunc to indicate that
 // its return value should be paginated.
-var Paginated fieldFuncOptionFunc = func(m *method) {
+var Paginated paginatedOption = func(m *method) {
 	m.Paginated = true
 }
 
+// paginatedOption is a FieldFuncOption which marks a field as paginated. It can
+// be further configured with its With* methods, e.g.
+//    Paginated.WithEdgeHighlighter(highlight)
+type paginatedOption func(*method)
+
+func (f paginatedOption) apply(m *method) { f(m) }
+
+// WithEdgeHighlighter configures the paginated field to compute a highlight
+// field on every returned edge. The paginated field's args must include a
+// string field named query, which is passed to fn along with each node.
+func (f paginatedOption) WithEdgeHighlighter(fn EdgeHighlighter) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.EdgeHighlighter = fn
+	}
+}
+
+// WithMaxLimit configures the paginated field to reject first and last args
+// greater than limit. If neither first nor last are specified, the field
+// returns at most limit items. It overrides the schema's WithMaxPaginationLimit.
+func (f paginatedOption) WithMaxLimit(limit int64) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.MaxLimit = limit
+	}
+}
+
+// WithDefaultLimit configures the paginated field to return at most limit
+// items if neither first nor last are specified.
+func (f paginatedOption) WithDefaultLimit(limit int64) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.DefaultLimit = limit
+	}
+}
+
+// WithSortFields configures the paginated field to take an orderBy enum arg
+// whose values are the given fields of the node type. Cursors are built from
+// the chosen sort field instead of the node's key. Resolvers returning
+// PaginationInfo receive the choice in PaginationArgs.OrderBy and must sort the
+// nodes themselves; otherwise the nodes are sorted before they are paginated.
+func (f paginatedOption) WithSortFields(fields ...string) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.SortFields = fields
+	}
+}
+
+// WithStableSort configures the paginated field to stably sort the nodes by their key before
+// the cursors are built, so that pages are resumed deterministically even if the resolver returns
+// the nodes unordered. Nodes with equal keys keep the resolver's order. It has no effect on
+// resolvers returning PaginationInfo, which sort the nodes themselves, nor on nodes ordered by
+// WithSortFields or by score.
+func (f paginatedOption) WithStableSort() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.StableSort = true
+	}
+}
+
+// WithOverfetch configures the paginated field to let its resolver apply the after and before
+// cursors while thunder trims the page to first and last. The resolver's args must embed
+// PaginationArgs, and it returns the nodes following After and preceding Before, in order. To
+// indicate that more nodes exist it returns one more node than First, or one more node than Last
+// preceding the page. Thunder drops the extra node and sets hasNextPage or hasPreviousPage,
+// which are also set if Before or After are given. Returning PaginationInfo is optional; it only
+// provides the totalCount field, which is omitted otherwise, e.g.
+//    inner.FieldFunc("users", func(args struct{ schemabuilder.PaginationArgs }) []*User {
+//        return db.UsersAfter(args.After, *args.First+1)
+//    }, schemabuilder.Paginated.WithOverfetch())
+func (f paginatedOption) WithOverfetch() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.Overfetch = true
+	}
+}
+
+// WithRelativePages configures the paginated field to compute pageInfo.pages relative to the
+// current page instead of the start (or, paginating backward, the end) of the list. By default
+// the pages are absolute, so when nodes are added before the current page every page cursor
+// shifts, and a page-number UI jumps. Relative pages are anchored to the after (or before) cursor
+// of the current page, and only shift if nodes are added between the anchor and the page. The
+// tradeoff is that the first (or last) page may be shorter than the page size, and overlaps the
+// following page, because it is requested with an empty cursor.
+func (f paginatedOption) WithRelativePages() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.RelativePages = true
+	}
+}
+
+// WithoutPages configures the paginated field to not expose pageInfo.pages, e.g. so that clients
+// don't rely on page cursors that shift as nodes are added.
+func (f paginatedOption) WithoutPages() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.WithoutPages = true
+	}
+}
+
+// WithRequiredNodeID configures the paginated field to require that its node
+// type exposes an id field, so that clients can normalize the nodes in their
+// store. Building the schema fails if the node type has no id field.
+func (f paginatedOption) WithRequiredNodeID() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.RequiresNodeID = true
+	}
+}
+
+// WithTotalCountEstimate configures the paginated field to expose a nullable
+// totalCountEstimate field on its connection. Resolvers returning
+// PaginationInfo populate it with EstimatedTotalCount; otherwise it is the
+// exact count.
+func (f paginatedOption) WithTotalCountEstimate() paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.TotalCountEstimate = true
+	}
+}
+
+// WithTotalCountType configures the type of the totalCount field of the paginated field's
+// connection, e.g. for counts beyond 2^53, which JavaScript clients can't represent exactly.
+// convert is a func(int64) T converting the count to the field's type T, such as a string or a
+// custom scalar, e.g.
+//    Paginated.WithTotalCountType(func(count int64) string { return strconv.FormatInt(count, 10) })
+// Resolvers returning PaginationInfo can return the count of string types with TotalCountString.
+func (f paginatedOption) WithTotalCountType(convert interface{}) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.TotalCountConverter = convert
+	}
+}
+
+// WithNodeType configures the node type of a paginated field whose resolver returns a
+// *Connection, ScoredNodes or edge structs, which it can't be inferred from. node is a value of
+// the node type, e.g.
+//    Paginated.WithNodeType(&User{})
+func (f paginatedOption) WithNodeType(node interface{}) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.NodeType = reflect.TypeOf(node)
+	}
+}
+
+// WithConnectionName configures the name that the connection, edge and orderBy types of the
+// paginated field are derived from, e.g. UserSearch for UserSearchConnection and UserSearchEdge.
+// By default they are named after the node type, so paginated fields of the same node type whose
+// connections differ, e.g. in their edge fields, must set distinct names.
+func (f paginatedOption) WithConnectionName(name string) paginatedOption {
+	return func(m *method) {
+		f(m)
+		m.ConnectionName = name
+	}
+}
+
 // FieldFunc exposes a field on an object. The function f can take a number of
 // optional arguments:
 // func([ctx context.Context], [o *Type], [args struct {}]) ([Result], [error])
@@ -70,7 +303,8 @@ func (s *Object) FieldFunc(name string, f interface{}, options ...FieldFuncOptio
 	}
 
 	if _, ok := s.Methods[name]; ok {
-		panic("duplicate method")
+		s.duplicateFields = append(s.duplicateFields, name)
+		return
 	}
 	s.Methods[name] = m
 }
@@ -87,12 +321,61 @@ func (s *Object) Key(f string) {
 	s.key = f
 }
 
+// A ReferenceResolver fetches an entity from its federation representation. The
+// representation holds the entity's __typename and its key field, e.g.
+//   {"__typename": "User", "id": 1}
+// Key values are passed as decoded from the request, so numbers are float64.
+// The returned value must be of the object's type or a pointer to it.
+type ReferenceResolver func(ctx context.Context, representation map[string]interface{}) (interface{}, error)
+
+// ResolveReference registers the object as a federation entity, resolved by f
+// in the schema's _entities field. The object must have a key. It has no effect
+// unless federation is enabled with EnableFederation.
+func (s *Object) ResolveReference(f ReferenceResolver) {
+	s.referenceResolver = f
+}
+
+// A NodeResolver fetches an object by its key for the Relay node field. The key
+// is decoded from a global ID as a string, e.g. "1" for a numeric key. The
+// returned value must be of the object's type or a pointer to it.
+type NodeResolver func(ctx context.Context, key string) (interface{}, error)
+
+// NodeResolver registers f to fetch the object in the schema's node field. The
+// object must have a key. It has no effect unless the node field is enabled
+// with EnableRelayNode.
+func (s *Object) NodeResolver(f NodeResolver) {
+	s.nodeResolver = f
+}
+
 type method struct {
 	MarkedNonNullable bool
+	DeprecationReason string
+	UnionName         string
+	Cost              int
+	Timeout           time.Duration
+	Authorize         func(ctx context.Context, source interface{}) error
+	Memoize           bool
+	BestEffort        bool
+	CacheTTL          time.Duration
 	Fn                interface{}
 
 	// Connection configuration
-	Paginated bool
+	Paginated          bool
+	EdgeHighlighter    EdgeHighlighter
+	MaxLimit           int64
+	DefaultLimit       int64
+	SortFields         []string
+	StableSort         bool
+	Overfetch          bool
+	RelativePages      bool
+	WithoutPages       bool
+	RequiresNodeID     bool
+	TotalCountEstimate bool
+	NodeType           reflect.Type
+	ConnectionName     string
+
+	// TotalCountConverter is the func(int64) T converting the total count to its field's type.
+	TotalCountConverter interface{}
 }
 
 // A Methods map represents the set of methods exposed on a Object.
@@ -0,0 +1,123 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// unionMapping is a union registered with RegisterUnion.
+type unionMapping struct {
+	resolveType func(value interface{}) string
+	members     []reflect.Type
+}
+
+// RegisterUnion registers a union named name whose member types are the Go
+// types of members, which must be registered objects. Fields return the union
+// when they are passed the ReturnsUnion option, and resolveType picks the name
+// of the member type of their values. If resolveType is nil, the member type is
+// picked by the value's Go type.
+//
+// For example a search result union could be registered as:
+//   s.RegisterUnion("SearchResult", nil, User{}, Item{})
+//
+//   query.FieldFunc("search", func() []interface{} {
+//     return []interface{}{&User{}, &Item{}}
+//   }, schemabuilder.ReturnsUnion("SearchResult"))
+func (s *Schema) RegisterUnion(name string, resolveType func(interface{}) string, members ...interface{}) {
+	if len(members) == 0 {
+		panic("union has no members")
+	}
+
+	mapping := &unionMapping{resolveType: resolveType}
+	for _, member := range members {
+		typ := reflect.TypeOf(member)
+		if typ != nil && typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		mapping.members = append(mapping.members, typ)
+	}
+
+	if s.unions == nil {
+		s.unions = make(map[string]*unionMapping)
+	}
+	s.unions[name] = mapping
+}
+
+// ReturnsUnion is an option that can be passed to a FieldFunc to indicate that
+// it returns the union registered with RegisterUnion as name. The FieldFunc
+// must return an interface{}, or a slice of interface{}.
+func ReturnsUnion(name string) FieldFuncOption {
+	return fieldFuncOptionFunc(func(m *method) {
+		m.UnionName = name
+	})
+}
+
+// buildUnions builds all unions registered with RegisterUnion, checking that
+// their members are registered objects.
+func (sb *schemaBuilder) buildUnions() error {
+	var names []string
+	for name := range sb.unions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mapping := sb.unions[name]
+
+		memberNames := make(map[reflect.Type]string)
+		union := &graphql.Union{
+			Name:  name,
+			Types: make(map[string]*graphql.Object),
+			ResolveType: func(value interface{}) string {
+				if mapping.resolveType != nil {
+					return mapping.resolveType(value)
+				}
+				typ := reflect.TypeOf(value)
+				if typ.Kind() == reflect.Ptr {
+					typ = typ.Elem()
+				}
+				return memberNames[typ]
+			},
+		}
+
+		for _, typ := range mapping.members {
+			if _, ok := sb.objects[typ]; !ok {
+				return fmt.Errorf("bad union %s: member %v should be a registered object", name, typ)
+			}
+			if err := sb.buildStruct(typ); err != nil {
+				return err
+			}
+			object, ok := sb.types[typ].(*graphql.Object)
+			if !ok {
+				return fmt.Errorf("bad union %s: member %v should be an object", name, typ)
+			}
+			union.Types[object.Name] = object
+			memberNames[typ] = object.Name
+		}
+		sb.builtUnions[name] = union
+	}
+	return nil
+}
+
+// getUnionType returns the type of a field returning the union registered as
+// name, whose Go type is typ.
+func (sb *schemaBuilder) getUnionType(name string, typ reflect.Type) (graphql.Type, error) {
+	union, ok := sb.builtUnions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown union %s", name)
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		return union, nil
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Interface {
+			break
+		}
+		return &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: union}}}, nil
+	}
+	return nil, fmt.Errorf("bad type %s: union %s should be returned as an interface or slice of interfaces", typ, name)
+}
@@ -0,0 +1,176 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// ValidationErrors are the errors found by Schema.Validate.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Validate checks the schema for mistakes that Build either fails on one at a
+// time, or doesn't catch at all. It reports every field whose type can't be
+// built, e.g. a Go interface that wasn't registered with RegisterInterface,
+// with its path from the object the build started from:
+//   Query.user.pet: bad type main.Pet: should be a scalar, slice, map, or struct type
+// It also reports every registered object that can't be reached from the
+// query, mutation or subscription root. Validate returns nil or
+// ValidationErrors.
+func (s *Schema) Validate() error {
+	schema, sb, err := s.build(true)
+	if err != nil {
+		return ValidationErrors{err}
+	}
+
+	// Interface implementations are built in map order, so the errors are sorted to be stable.
+	errs := append([]error(nil), sb.errors...)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+
+	reachable := make(map[graphql.Type]bool)
+	for _, root := range []graphql.Type{schema.Query, schema.Mutation, schema.Subscription} {
+		if root != nil {
+			markReachable(root, reachable)
+		}
+	}
+
+	var names []string
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "Query" || name == "Mutation" || name == "Subscription" {
+			continue
+		}
+		if built := sb.types[reflect.TypeOf(s.objects[name].Type)]; built == nil || !reachable[built] {
+			errs = append(errs, fmt.Errorf("%s: object is unreachable from the root types", name))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return ValidationErrors(errs)
+}
+
+// markReachable marks typ and all types reachable from it through fields,
+// interface implementations and union members.
+func markReachable(typ graphql.Type, reachable map[graphql.Type]bool) {
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		markReachable(typ.Type, reachable)
+	case *graphql.List:
+		markReachable(typ.Type, reachable)
+	case *graphql.Object:
+		if reachable[typ] {
+			return
+		}
+		reachable[typ] = true
+		for _, field := range typ.Fields {
+			markReachable(field.Type, reachable)
+		}
+	case *graphql.Interface:
+		if reachable[typ] {
+			return
+		}
+		reachable[typ] = true
+		for _, object := range typ.Types {
+			markReachable(object, reachable)
+		}
+	case *graphql.Union:
+		if reachable[typ] {
+			return
+		}
+		reachable[typ] = true
+		for _, object := range typ.Types {
+			markReachable(object, reachable)
+		}
+	}
+}
+
+// checkTypeConflicts returns an error if two different types reachable from the roots of schema
+// have the same name, e.g. the connection types of two paginated fields with the same node type
+// but different options. Types are compared by their fields or values.
+func checkTypeConflicts(schema *graphql.Schema) error {
+	definitions := make(map[string]string)
+	seen := make(map[graphql.Type]bool)
+	var check func(typ graphql.Type) error
+	check = func(typ graphql.Type) error {
+		if typ == nil || seen[typ] {
+			return nil
+		}
+		seen[typ] = true
+
+		var name, definition string
+		var children []graphql.Type
+		switch typ := typ.(type) {
+		case *graphql.NonNull:
+			return check(typ.Type)
+		case *graphql.List:
+			return check(typ.Type)
+		case *graphql.Enum:
+			values := append([]string(nil), typ.Values...)
+			sort.Strings(values)
+			name, definition = typ.Type, strings.Join(values, ", ")
+		case *graphql.InputObject:
+			fields := make([]string, 0, len(typ.InputFields))
+			for fieldName, fieldType := range typ.InputFields {
+				fields = append(fields, fieldName+": "+fieldType.String())
+				children = append(children, fieldType)
+			}
+			sort.Strings(fields)
+			name, definition = typ.Name, strings.Join(fields, ", ")
+		case *graphql.Object:
+			fields := make([]string, 0, len(typ.Fields))
+			for fieldName, field := range typ.Fields {
+				fields = append(fields, fieldName+": "+field.Type.String())
+				children = append(children, field.Type)
+				for _, arg := range field.Args {
+					children = append(children, arg)
+				}
+			}
+			sort.Strings(fields)
+			name, definition = typ.Name, strings.Join(fields, ", ")
+		case *graphql.Interface:
+			for _, object := range typ.Types {
+				children = append(children, object)
+			}
+		case *graphql.Union:
+			for _, object := range typ.Types {
+				children = append(children, object)
+			}
+		}
+
+		if name != "" {
+			if existing, ok := definitions[name]; ok && existing != definition {
+				return fmt.Errorf("conflicting definitions of type %s: {%s} and {%s}; paginated fields can set distinct names with Paginated.WithConnectionName", name, existing, definition)
+			}
+			definitions[name] = definition
+		}
+		for _, child := range children {
+			if err := check(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, root := range []graphql.Type{schema.Query, schema.Mutation, schema.Subscription} {
+		if err := check(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// builtinScalars are the scalars every GraphQL schema defines implicitly, and
+// which are therefore not declared in SDL.
+var builtinScalars = map[string]bool{
+	"Int":     true,
+	"Float":   true,
+	"String":  true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// SDL prints the schema in the GraphQL schema definition language. Types,
+// fields, args and enum values are sorted by name so that the output is stable
+// and diffs cleanly. Meta fields such as __schema are omitted.
+func (s *Schema) SDL() string {
+	types := make(map[string]Type)
+	collectSDLTypes(s.Query, types)
+	collectSDLTypes(s.Mutation, types)
+	collectSDLTypes(s.Subscription, types)
+
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var defs []string
+	for _, name := range names {
+		if def := printSDLType(types[name]); def != "" {
+			defs = append(defs, def)
+		}
+	}
+	return strings.Join(defs, "\n")
+}
+
+// collectSDLTypes adds typ and all types reachable from it to types, keyed by
+// name.
+func collectSDLTypes(typ Type, types map[string]Type) {
+	switch typ := typ.(type) {
+	case *Object:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+
+		for name, field := range typ.Fields {
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			collectSDLTypes(field.Type, types)
+			for _, arg := range field.Args {
+				collectSDLTypes(arg, types)
+			}
+		}
+
+	case *Union:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+		for _, member := range typ.Types {
+			collectSDLTypes(member, types)
+		}
+
+	case *Interface:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+		for _, field := range typ.Fields {
+			collectSDLTypes(field.Type, types)
+			for _, arg := range field.Args {
+				collectSDLTypes(arg, types)
+			}
+		}
+		for _, implementation := range typ.Types {
+			collectSDLTypes(implementation, types)
+		}
+
+	case *InputObject:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+		for _, field := range typ.InputFields {
+			collectSDLTypes(field, types)
+		}
+
+	case *Scalar:
+		types[typ.Type] = typ
+
+	case *Enum:
+		types[typ.Type] = typ
+
+	case *List:
+		collectSDLTypes(typ.Type, types)
+
+	case *NonNull:
+		collectSDLTypes(typ.Type, types)
+	}
+}
+
+// printSDLType prints a single type definition, or "" if the type needs none.
+func printSDLType(typ Type) string {
+	var buf bytes.Buffer
+
+	switch typ := typ.(type) {
+	case *Object:
+		var names []string
+		for name := range typ.Fields {
+			if !strings.HasPrefix(name, "__") {
+				names = append(names, name)
+			}
+		}
+		// Objects without fields, such as an unused Mutation, are not valid SDL.
+		if len(names) == 0 {
+			return ""
+		}
+		sort.Strings(names)
+
+		var interfaces []string
+		for name := range typ.Interfaces {
+			interfaces = append(interfaces, name)
+		}
+		sort.Strings(interfaces)
+
+		printSDLDescription(&buf, "", typ.Description)
+		fmt.Fprintf(&buf, "type %s", typ.Name)
+		if len(interfaces) > 0 {
+			fmt.Fprintf(&buf, " implements %s", strings.Join(interfaces, " & "))
+		}
+		for _, directive := range typ.Directives {
+			fmt.Fprintf(&buf, " %s", directive)
+		}
+		buf.WriteString(" {\n")
+		printSDLFields(&buf, typ.Fields, names)
+		buf.WriteString("}\n")
+
+	case *Interface:
+		var names []string
+		for name := range typ.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		printSDLDescription(&buf, "", typ.Description)
+		fmt.Fprintf(&buf, "interface %s {\n", typ.Name)
+		printSDLFields(&buf, typ.Fields, names)
+		buf.WriteString("}\n")
+
+	case *Union:
+		var names []string
+		for name := range typ.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		printSDLDescription(&buf, "", typ.Description)
+		fmt.Fprintf(&buf, "union %s = %s\n", typ.Name, strings.Join(names, " | "))
+
+	case *InputObject:
+		var names []string
+		for name := range typ.InputFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		printSDLDescription(&buf, "", typ.Description)
+		fmt.Fprintf(&buf, "input %s {\n", typ.Name)
+		for _, name := range names {
+			printSDLDescription(&buf, "  ", typ.Descriptions[name])
+			fmt.Fprintf(&buf, "  %s: %s%s\n", name, typ.InputFields[name], printSDLDefault(typ.DefaultValues, name))
+		}
+		buf.WriteString("}\n")
+
+	case *Enum:
+		values := append([]string(nil), typ.Values...)
+		sort.Strings(values)
+
+		fmt.Fprintf(&buf, "enum %s {\n", typ.Type)
+		for _, value := range values {
+			fmt.Fprintf(&buf, "  %s", value)
+			if reason := typ.DeprecationReasons[value]; reason != "" {
+				fmt.Fprintf(&buf, " @deprecated(reason: %s)", quoteSDLString(reason))
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}\n")
+
+	case *Scalar:
+		if builtinScalars[typ.Type] {
+			return ""
+		}
+		fmt.Fprintf(&buf, "scalar %s\n", typ.Type)
+	}
+
+	return buf.String()
+}
+
+// printSDLFields prints the named fields of an object or interface.
+func printSDLFields(buf *bytes.Buffer, fields map[string]*Field, names []string) {
+	for _, name := range names {
+		field := fields[name]
+		printSDLDescription(buf, "  ", field.Description)
+		fmt.Fprintf(buf, "  %s%s: %s", name, printSDLArgs(field.Args, field.ArgDefaultValues, field.ArgDescriptions), field.Type)
+		if field.DeprecationReason != "" {
+			fmt.Fprintf(buf, " @deprecated(reason: %s)", quoteSDLString(field.DeprecationReason))
+		}
+		for _, directive := range field.Directives {
+			fmt.Fprintf(buf, " %s", directive)
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// printSDLDescription prints a description as a block string, if it is set.
+func printSDLDescription(buf *bytes.Buffer, indent string, description string) {
+	if description == "" {
+		return
+	}
+	description = strings.Replace(description, `"""`, `\"""`, -1)
+	if !strings.Contains(description, "\n") {
+		fmt.Fprintf(buf, "%s\"\"\"%s\"\"\"\n", indent, description)
+		return
+	}
+	fmt.Fprintf(buf, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(buf, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(buf, "%s\"\"\"\n", indent)
+}
+
+// printSDLArgs prints a field's argument list, or "" if it takes no args.
+// Documented args are preceded by their description.
+func printSDLArgs(args map[string]Type, defaultValues map[string]string, descriptions map[string]string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	var names []string
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		var description string
+		if descriptions[name] != "" {
+			description = quoteSDLString(descriptions[name]) + " "
+		}
+		parts = append(parts, fmt.Sprintf("%s%s: %s%s", description, name, args[name], printSDLDefault(defaultValues, name)))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// printSDLDefault prints the default of an arg or input field, or "" if it has
+// none.
+func printSDLDefault(defaultValues map[string]string, name string) string {
+	if literal, ok := defaultValues[name]; ok {
+		return " = " + literal
+	}
+	return ""
+}
+
+var sdlStringReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// quoteSDLString quotes s as a GraphQL string value.
+func quoteSDLString(s string) string {
+	return `"` + sdlStringReplacer.Replace(s) + `"`
+}
@@ -0,0 +1,133 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type sdlRole int32
+
+func TestSDL(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string `graphql:"name;description=The user's display name."`
+		Role sdlRole
+	}
+	type Filter struct {
+		_    struct{} `graphql:";description=Filters users."`
+		Name *string  `graphql:"name;description=Matches the name."`
+		Ids  []int64
+	}
+	type Pet struct {
+		_    struct{} `graphql:";description=A pet of a user."`
+		Name string
+	}
+	type Owner struct {
+		schemabuilder.Union
+		*User
+		*Pet
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Enum(sdlRole(0), map[string]interface{}{
+		"admin":  sdlRole(0),
+		"member": sdlRole(1),
+	})
+
+	query := schema.Query()
+	query.FieldFunc("user", func(args struct {
+		Id int64 `graphql:"id;description=The id of the user."`
+	}) *User {
+		return nil
+	})
+	query.FieldFunc("users", func(args struct {
+		Filter Filter `graphql:"filter;description=Only returns matching users."`
+	}) []User {
+		return nil
+	}, schemabuilder.Paginated)
+	query.FieldFunc("owner", func() Owner {
+		return Owner{}
+	})
+
+	user := schema.Object("User", User{})
+	user.Description = "A user of the app."
+	user.Key("id")
+
+	mutation := schema.Mutation()
+	mutation.FieldFunc("rename", func(args struct {
+		Id   int64
+		Name string
+	}) *User {
+		return nil
+	})
+
+	builtSchema := schema.MustBuild()
+	builtSchema.Query.(*graphql.Object).Fields["owner"].DeprecationReason = `use "user"`
+
+	assert.Equal(t, `"""Filters users."""
+input Filter_InputObject {
+  ids: [int64!]!
+  """Matches the name."""
+  name: string
+}
+
+type Mutation {
+  rename(id: int64!, name: string!): User
+}
+
+union Owner = Pet | User
+
+type PageInfo {
+  endCursor: string!
+  hasNextPage: bool!
+  hasPrevPage: bool!
+  pages: [string!]!
+  startCursor: string!
+}
+
+"""A pet of a user."""
+type Pet {
+  name: string!
+}
+
+type Query {
+  owner: Owner! @deprecated(reason: "use \"user\"")
+  user("The id of the user." id: int64!): User
+  users(after: string, before: string, "Only returns matching users." filter: Filter_InputObject!, first: int64, last: int64): UserConnection!
+}
+
+"""A user of the app."""
+type User {
+  id: int64!
+  """The user's display name."""
+  name: string!
+  role: sdlRole!
+}
+
+type UserConnection {
+  edges: [UserEdge!]!
+  nodes: [User!]
+  pageInfo: PageInfo!
+  totalCount: int64!
+}
+
+type UserEdge {
+  cursor: string!
+  node: User!
+}
+
+scalar bool
+
+scalar int64
+
+enum sdlRole {
+  admin
+  member
+}
+
+scalar string
+`, builtSchema.SDL())
+}
@@ -71,6 +71,9 @@ type conn struct {
 
 	minRerunIntervalFunc RerunIntervalFunc
 	maxSubscriptions     int
+
+	persistedQueries PersistedQueryStore
+	queryCache       *QueryCache
 }
 
 type inEnvelope struct {
@@ -139,6 +142,73 @@ func sanitizeError(err error) string {
 	return "Internal server error"
 }
 
+// An ExtendedError is an error with extensions, e.g. a machine-readable error
+// code, that are sent to clients along with its message. The extensions of
+// errors that don't implement SanitizedError are masked like their message.
+type ExtendedError interface {
+	error
+	Extensions() map[string]interface{}
+}
+
+type extendedError struct {
+	error
+	extensions map[string]interface{}
+}
+
+func (e extendedError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+type sanitizedExtendedError struct {
+	extendedError
+}
+
+func (e sanitizedExtendedError) SanitizedError() string {
+	return e.error.(SanitizedError).SanitizedError()
+}
+
+// WithExtensions attaches extensions to err, which are sent to clients if err
+// implements SanitizedError.
+func WithExtensions(err error, extensions map[string]interface{}) error {
+	extended := extendedError{error: err, extensions: extensions}
+	if _, ok := err.(SanitizedError); ok {
+		return sanitizedExtendedError{extended}
+	}
+	return extended
+}
+
+// NewCodedError returns an error whose message is sent to clients along with
+// code in its extensions, e.g.
+//   graphql.NewCodedError("NOT_FOUND", "user not found")
+// is sent as {"message": "user not found", "extensions": {"code": "NOT_FOUND"}}.
+func NewCodedError(code string, message string) error {
+	return WithExtensions(SafeError{message: message}, map[string]interface{}{"code": code})
+}
+
+// sanitizeExtensions returns the extensions of err, or nil if err has none or
+// is masked.
+func sanitizeExtensions(err error) map[string]interface{} {
+	if _, ok := err.(SanitizedError); !ok {
+		return nil
+	}
+	if extended, ok := err.(ExtendedError); ok {
+		return extended.Extensions()
+	}
+	return nil
+}
+
+// errorMessage is an error as sent to clients, with the message and extensions
+// of errors that implement SanitizedError and masked ones otherwise, e.g.
+//   {"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}
+type errorMessage struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func newErrorMessage(err error) *errorMessage {
+	return &errorMessage{Message: sanitizeError(err), Extensions: sanitizeExtensions(err)}
+}
+
 func isCloseError(err error) bool {
 	_, ok := err.(*websocket.CloseError)
 	return ok || err == websocket.ErrCloseSent
@@ -164,6 +234,15 @@ func mustMarshalJson(v interface{}) string {
 	return string(bytes)
 }
 
+// prepareQuery parses and prepares a query, using the connection's query cache
+// if it has one.
+func (c *conn) prepareQuery(typ Type, source string, vars map[string]interface{}) (*Query, error) {
+	if c.queryCache != nil {
+		return c.queryCache.Prepare(typ, source, vars)
+	}
+	return prepareQuery(typ, source, vars)
+}
+
 func (c *conn) handleSubscribe(in *inEnvelope) error {
 	id := in.ID
 	var subscribe subscribeMessage
@@ -182,9 +261,15 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 		return NewSafeError("too many subscriptions")
 	}
 
+	queryText, err := resolvePersistedQuery(c.persistedQueries, subscribe.Query, in.Extensions)
+	if err != nil {
+		return err
+	}
+	subscribe.Query = queryText
+
 	tags := map[string]string{"url": c.url, "query": subscribe.Query, "queryVariables": mustMarshalJson(subscribe.Variables), "id": id}
 
-	query, err := Parse(subscribe.Query, subscribe.Variables)
+	query, err := c.prepareQuery(c.schema.Query, subscribe.Query, subscribe.Variables)
 	if query != nil {
 		tags["queryType"] = query.Kind
 		tags["queryName"] = query.Name
@@ -193,14 +278,10 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 		c.logger.Error(c.ctx, err, tags)
 		return err
 	}
-	if err := PrepareQuery(c.schema.Query, query.SelectionSet); err != nil {
-		c.logger.Error(c.ctx, err, tags)
-		return err
-	}
 
 	var previous interface{}
 
-	e := Executor{}
+	e := Executor{Directives: c.schema.Directives}
 
 	initial := true
 	c.subscriptionLogger.Subscribe(c.ctx, id, tags)
@@ -216,7 +297,10 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 		middlewares = append(middlewares, c.middlewares...)
 		middlewares = append(middlewares, func(input *ComputationInput, next MiddlewareNextFunc) *ComputationOutput {
 			output := next(input)
-			output.Current, output.Error = e.Execute(input.Ctx, c.schema.Query, nil, input.ParsedQuery)
+			output.cost = lazyQueryCost(input.Ctx, c.schema.Query, input.ParsedQuery.SelectionSet)
+			x := e.newExecution()
+			x.cost = output.cost
+			output.Current, output.Error = x.run(input.Ctx, c.schema.Query, nil, input.ParsedQuery)
 			return output
 		})
 
@@ -261,7 +345,7 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 			c.writeOrClose(outEnvelope{
 				ID:       id,
 				Type:     "error",
-				Message:  sanitizeError(err),
+				Message:  newErrorMessage(err),
 				Metadata: output.Metadata,
 			})
 			go c.closeSubscription(id)
@@ -310,9 +394,15 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	queryText, err := resolvePersistedQuery(c.persistedQueries, mutate.Query, in.Extensions)
+	if err != nil {
+		return err
+	}
+	mutate.Query = queryText
+
 	tags := map[string]string{"url": c.url, "query": mutate.Query, "queryVariables": mustMarshalJson(mutate.Variables), "id": id}
 
-	query, err := Parse(mutate.Query, mutate.Variables)
+	query, err := c.prepareQuery(c.mutationSchema.Mutation, mutate.Query, mutate.Variables)
 	if query != nil {
 		tags["queryType"] = query.Kind
 		tags["queryName"] = query.Name
@@ -321,13 +411,9 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 		c.logger.Error(c.ctx, err, tags)
 		return err
 	}
-	if err := PrepareQuery(c.mutationSchema.Mutation, query.SelectionSet); err != nil {
-		c.logger.Error(c.ctx, err, tags)
-		return err
-	}
 
 	initial := true
-	e := Executor{}
+	e := Executor{Directives: c.mutationSchema.Directives}
 	c.subscriptions[id] = reactive.NewRerunner(c.ctx, func(ctx context.Context) (interface{}, error) {
 		// Serialize all mutates for a given connection.
 		c.mutateMu.Lock()
@@ -343,7 +429,10 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 		middlewares = append(middlewares, c.middlewares...)
 		middlewares = append(middlewares, func(input *ComputationInput, next MiddlewareNextFunc) *ComputationOutput {
 			output := next(input)
-			output.Current, output.Error = e.Execute(input.Ctx, c.mutationSchema.Mutation, c.mutationSchema.Mutation, query)
+			output.cost = lazyQueryCost(input.Ctx, c.mutationSchema.Mutation, query.SelectionSet)
+			x := e.newExecution()
+			x.cost = output.cost
+			output.Current, output.Error = x.run(input.Ctx, c.mutationSchema.Mutation, c.mutationSchema.Mutation, query)
 			return output
 		})
 
@@ -367,7 +456,7 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 			c.writeOrClose(outEnvelope{
 				ID:       id,
 				Type:     "error",
-				Message:  sanitizeError(err),
+				Message:  newErrorMessage(err),
 				Metadata: output.Metadata,
 			})
 
@@ -592,6 +681,23 @@ func WithSubscriptionLogger(logger SubscriptionLogger) ConnectionOption {
 	}
 }
 
+// WithPersistedQueryStore enables automatic persisted queries, saving queries
+// sent along with their hash in store. Subscribe and mutate messages may then
+// send only the hash in the envelope's extensions.
+func WithPersistedQueryStore(store PersistedQueryStore) ConnectionOption {
+	return func(c *conn) {
+		c.persistedQueries = store
+	}
+}
+
+// WithQueryCache reuses parsed and prepared queries from cache. A cache may be
+// shared between connections.
+func WithQueryCache(cache *QueryCache) ConnectionOption {
+	return func(c *conn) {
+		c.queryCache = cache
+	}
+}
+
 // WithMinRerunIntervalFunc is deprecated.
 func WithMinRerunIntervalFunc(fn RerunIntervalFunc) ConnectionOption {
 	return func(c *conn) {
@@ -616,7 +722,7 @@ func (c *conn) ServeJSONSocket() {
 			c.writeOrClose(outEnvelope{
 				ID:       envelope.ID,
 				Type:     "error",
-				Message:  sanitizeError(err),
+				Message:  newErrorMessage(err),
 				Metadata: nil,
 			})
 		}
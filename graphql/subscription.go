@@ -0,0 +1,189 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/samsarahq/thunder/batch"
+	"github.com/samsarahq/thunder/diff"
+	"github.com/samsarahq/thunder/reactive"
+)
+
+// A Result is a value of a subscription sent by Executor.Subscribe.
+type Result struct {
+	// Data is the full result of the subscription's query.
+	Data interface{}
+
+	// Delta is the diff from the previous result's Data, in the format of the
+	// diff package. The first result's Delta is the diff from nil.
+	Delta interface{}
+
+	// Err is the error of a failed execution. It is sent as the last result of
+	// the subscription.
+	Err error
+}
+
+// Subscribe executes query on typ, typically the schema's Subscription root,
+// and sends the result on the returned channel. Whenever a reactive.Resource
+// used by the query's resolvers is invalidated the query is executed again,
+// and a new result is sent if the data changed.
+//
+// If the query selects a Stream field, its resolver is called once, and the
+// query is executed again for every value received from the returned channel,
+// with the value as the field's value. The subscription ends once the channel
+// is closed. The resolver's context is canceled when the subscription ends, so
+// that it can stop sending values.
+//
+// The channel is closed when ctx is canceled or after an execution fails. The
+// caller must keep receiving from the channel until it is closed.
+func (e *Executor) Subscribe(ctx context.Context, typ Type, query *Query) (<-chan *Result, error) {
+	if err := PrepareQuery(typ, query.SelectionSet); err != nil {
+		return nil, err
+	}
+
+	selection, field, err := streamSelection(typ, query.SelectionSet)
+	if err != nil {
+		return nil, err
+	}
+	if field != nil {
+		return e.subscribeStream(ctx, typ.(*Object), field, selection, query)
+	}
+
+	minRerunInterval := e.MinRerunInterval
+	if minRerunInterval == 0 {
+		minRerunInterval = DefaultMinRerunInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan *Result)
+	send := func(result *Result) {
+		select {
+		case results <- result:
+		case <-ctx.Done():
+		}
+	}
+
+	var previous interface{}
+	runner := reactive.NewRerunner(ctx, func(ctx context.Context) (interface{}, error) {
+		current, err := e.Execute(batch.WithBatching(ctx), typ, nil, query)
+		if err != nil {
+			if ErrorCause(err) != context.Canceled {
+				send(&Result{Err: err})
+			}
+			cancel()
+			return nil, err
+		}
+
+		initial := previous == nil
+		delta := diff.Diff(previous, current)
+		previous = current
+		if delta != nil || initial {
+			send(&Result{Data: current, Delta: delta})
+		}
+		return nil, nil
+	}, minRerunInterval)
+
+	go func() {
+		<-ctx.Done()
+		runner.Stop()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// streamSelection returns the selection of a Stream field of typ in
+// selectionSet, or nil if there is none. A Stream field must be the only
+// selected field.
+func streamSelection(typ Type, selectionSet *SelectionSet) (*Selection, *Field, error) {
+	object, ok := typ.(*Object)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	selections := Flatten(selectionSet)
+	for _, selection := range selections {
+		if field := object.Fields[selection.Name]; field != nil && field.Stream {
+			if len(selections) != 1 {
+				return nil, nil, NewClientError("stream field %s must be the only selected field", selection.Name)
+			}
+			return selection, field, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// subscribeStream resolves a Stream field and sends the result of query for
+// every value received from the resolved channel.
+func (e *Executor) subscribeStream(ctx context.Context, typ *Object, field *Field, selection *Selection, query *Query) (<-chan *Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var value interface{}
+	var err error
+	if e.Panics == PanicsPropagate {
+		value, err = field.Resolve(ctx, nil, selection.Args, selection.SelectionSet)
+	} else {
+		value, err = safeResolve(ctx, field, nil, selection.Args, selection.SelectionSet)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stream := reflect.ValueOf(value)
+	if stream.Kind() != reflect.Chan || stream.IsNil() {
+		cancel()
+		return nil, fmt.Errorf("stream field %s should return a channel", selection.Name)
+	}
+
+	// The query is executed for every value against an object whose field
+	// resolves to the value.
+	object := &Object{
+		Name: typ.Name,
+		Fields: map[string]*Field{
+			selection.Name: {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error) {
+					return source, nil
+				},
+				Type: field.Type,
+			},
+		},
+	}
+
+	results := make(chan *Result)
+	send := func(result *Result) {
+		select {
+		case results <- result:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(results)
+		defer cancel()
+
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: stream},
+		}
+		var previous interface{}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				return
+			}
+
+			current, err := e.Execute(batch.WithBatching(ctx), object, item.Interface(), query)
+			if err != nil {
+				if ErrorCause(err) != context.Canceled {
+					send(&Result{Err: err})
+				}
+				return
+			}
+			send(&Result{Data: current, Delta: diff.Diff(previous, current)})
+			previous = current
+		}
+	}()
+
+	return results, nil
+}
@@ -0,0 +1,257 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/internal"
+	"github.com/samsarahq/thunder/reactive"
+)
+
+func TestSubscribe(t *testing.T) {
+	var mu sync.Mutex
+	count := int64(0)
+	resource := reactive.NewResource()
+
+	increment := func() {
+		mu.Lock()
+		count++
+		old := resource
+		resource = reactive.NewResource()
+		mu.Unlock()
+		old.Invalidate()
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	schema.Mutation()
+	subscription := schema.Subscription()
+	subscription.FieldFunc("count", func(ctx context.Context) int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		reactive.AddDependency(ctx, resource, nil)
+		return count
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`subscription { count }`, nil)
+	if q.Kind != "subscription" {
+		t.Errorf("expected subscription kind, got %s", q.Kind)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := graphql.Executor{MinRerunInterval: time.Millisecond}
+	results, err := e.Subscribe(ctx, builtSchema.Subscription, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func() *graphql.Result {
+		select {
+		case result := <-results:
+			return result
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for result")
+			return nil
+		}
+	}
+
+	result := receive()
+	assert.NoError(t, result.Err)
+	assert.Equal(t, map[string]interface{}{"count": float64(0)}, internal.AsJSON(result.Data))
+
+	increment()
+	result = receive()
+	assert.NoError(t, result.Err)
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, internal.AsJSON(result.Data))
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, internal.AsJSON(result.Delta))
+
+	cancel()
+	if _, ok := <-results; ok {
+		t.Error("expected results to be closed")
+	}
+}
+
+func TestSubscribeInvalidateKey(t *testing.T) {
+	var mu sync.Mutex
+	names := map[int64]string{1: "alice", 2: "bob"}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	schema.Mutation().FieldFunc("rename", func(args struct {
+		Id   int64
+		Name string
+	}) bool {
+		mu.Lock()
+		names[args.Id] = args.Name
+		mu.Unlock()
+		reactive.Invalidate(fmt.Sprint("user:", args.Id))
+		return true
+	})
+	schema.Subscription().FieldFunc("name", func(ctx context.Context, args struct{ Id int64 }) string {
+		reactive.AddKeyDependency(ctx, fmt.Sprint("user:", args.Id))
+		mu.Lock()
+		defer mu.Unlock()
+		return names[args.Id]
+	})
+	builtSchema := schema.MustBuild()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := graphql.Executor{MinRerunInterval: time.Millisecond}
+	results, err := e.Subscribe(ctx, builtSchema.Subscription, graphql.MustParse(`subscription { name(id: 1) }`, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func() *graphql.Result {
+		select {
+		case result := <-results:
+			return result
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for result")
+			return nil
+		}
+	}
+	rename := func(id int64, name string) {
+		q := graphql.MustParse(fmt.Sprintf(`mutation { rename(id: %d, name: %q) }`, id, name), nil)
+		if err := graphql.PrepareQuery(builtSchema.Mutation, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		mutationExecutor := graphql.Executor{}
+		if _, err := mutationExecutor.Execute(context.Background(), builtSchema.Mutation, nil, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, map[string]interface{}{"name": "alice"}, internal.AsJSON(receive().Data))
+
+	// Invalidating another key doesn't rerun the subscription.
+	rename(2, "carol")
+	select {
+	case result := <-results:
+		t.Errorf("unexpected result %v", result)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rename(1, "alicia")
+	assert.Equal(t, map[string]interface{}{"name": "alicia"}, internal.AsJSON(receive().Data))
+
+	// The subscription depends on the key again after its rerun.
+	rename(1, "ali")
+	assert.Equal(t, map[string]interface{}{"name": "ali"}, internal.AsJSON(receive().Data))
+}
+
+func TestSubscribeUnknownField(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Subscription().FieldFunc("count", func() int64 { return 0 })
+	builtSchema := schema.MustBuild()
+
+	e := graphql.Executor{}
+	_, err := e.Subscribe(context.Background(), builtSchema.Subscription, graphql.MustParse(`subscription { missing }`, nil))
+	if err == nil || err.Error() != `unknown field "missing"` {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+type streamTick struct {
+	Count int64
+}
+
+func TestSubscribeStream(t *testing.T) {
+	stopped := make(chan struct{})
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+	schema.Mutation()
+	subscription := schema.Subscription()
+	subscription.FieldFunc("ticks", func(ctx context.Context, args struct{ Limit int64 }) (<-chan *streamTick, error) {
+		ticks := make(chan *streamTick)
+		go func() {
+			defer func() { stopped <- struct{}{} }()
+			defer close(ticks)
+
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for count := int64(1); args.Limit == 0 || count <= args.Limit; count++ {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case ticks <- &streamTick{Count: count}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ticks, nil
+	})
+	schema.Object("Tick", streamTick{})
+	builtSchema := schema.MustBuild()
+
+	assert.Contains(t, builtSchema.SDL(), "ticks(limit: int64!): Tick\n")
+
+	subscribe := func(ctx context.Context, query string) <-chan *graphql.Result {
+		e := graphql.Executor{}
+		results, err := e.Subscribe(ctx, builtSchema.Subscription, graphql.MustParse(query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	receive := func(results <-chan *graphql.Result) (*graphql.Result, bool) {
+		select {
+		case result, ok := <-results:
+			return result, ok
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for result")
+			return nil, false
+		}
+	}
+
+	// Every value of the stream is sent, and the results are closed with the stream.
+	results := subscribe(context.Background(), `subscription { ticks(limit: 3) { count } }`)
+	for count := 1; count <= 3; count++ {
+		result, ok := receive(results)
+		if !ok {
+			t.Fatal("expected result")
+		}
+		assert.NoError(t, result.Err)
+		assert.Equal(t, map[string]interface{}{"ticks": map[string]interface{}{"count": float64(count)}}, internal.AsJSON(result.Data))
+	}
+	if _, ok := receive(results); ok {
+		t.Error("expected results to be closed")
+	}
+	<-stopped
+
+	// Canceling the subscription stops the stream.
+	ctx, cancel := context.WithCancel(context.Background())
+	results = subscribe(ctx, `subscription { ticks(limit: 0) { count } }`)
+	receive(results)
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected stream to stop")
+	}
+	for range results {
+	}
+
+	e := graphql.Executor{}
+	_, err := e.Subscribe(context.Background(), builtSchema.Subscription, graphql.MustParse(`subscription { ticks(limit: 1) { count } other: ticks(limit: 2) { count } }`, nil))
+	if err == nil || err.Error() != "stream field ticks must be the only selected field" {
+		t.Errorf("expected single field error, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracing is the timing of an execution in the Apollo Tracing format, which
+// is meant to be returned under the extensions.tracing key of a response.
+type Tracing struct {
+	Version   int              `json:"version"`
+	StartTime time.Time        `json:"startTime"`
+	EndTime   time.Time        `json:"endTime"`
+	Duration  time.Duration    `json:"duration"`
+	Execution TracingExecution `json:"execution"`
+
+	mu sync.Mutex
+}
+
+// TracingExecution holds the timing of every resolver called during an
+// execution.
+type TracingExecution struct {
+	Resolvers []*ResolverTrace `json:"resolvers"`
+}
+
+// ResolverTrace is the timing of a single resolver. Its path consists of the
+// aliases of the fields and the indices of the list elements leading to the
+// field. StartOffset is relative to the start of the execution.
+type ResolverTrace struct {
+	Path        []interface{} `json:"path"`
+	ParentType  string        `json:"parentType"`
+	FieldName   string        `json:"fieldName"`
+	ReturnType  string        `json:"returnType"`
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// traceContext describes the field, or list element, being executed.
+type traceContext struct {
+	path       []interface{}
+	parentType string
+	fieldName  string
+	field      *Field
+}
+
+type traceContextKey struct{}
+
+// withTracePath returns a context for executing the field or list element at
+// key. field is nil for list elements.
+func withTracePath(ctx context.Context, key interface{}, parentType string, fieldName string, field *Field) context.Context {
+	var path []interface{}
+	if parent, ok := ctx.Value(traceContextKey{}).(*traceContext); ok {
+		path = append(path, parent.path...)
+	}
+	path = append(path, key)
+
+	return context.WithValue(ctx, traceContextKey{}, &traceContext{
+		path:       path,
+		parentType: parentType,
+		fieldName:  fieldName,
+		field:      field,
+	})
+}
+
+// record adds the trace of field's resolver, if ctx describes field.
+func (t *Tracing) record(ctx context.Context, field *Field, start time.Time, end time.Time) {
+	traceCtx, ok := ctx.Value(traceContextKey{}).(*traceContext)
+	if !ok || traceCtx.field != field {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Execution.Resolvers = append(t.Execution.Resolvers, &ResolverTrace{
+		Path:        traceCtx.path,
+		ParentType:  traceCtx.parentType,
+		FieldName:   traceCtx.fieldName,
+		ReturnType:  field.Type.String(),
+		StartOffset: start.Sub(t.StartTime),
+		Duration:    end.Sub(start),
+	})
+}
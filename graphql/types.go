@@ -3,6 +3,7 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Type represents a GraphQL type, and should be either an Object, a Scalar,
@@ -18,6 +19,9 @@ type Type interface {
 // Scalar is a leaf value
 type Scalar struct {
 	Type string
+
+	// Serialize optionally converts a value into its JSON representation.
+	Serialize func(value interface{}) interface{}
 }
 
 func (s *Scalar) isType() {}
@@ -31,6 +35,9 @@ type Enum struct {
 	Type       string
 	Values     []string
 	ReverseMap map[interface{}]string
+
+	// DeprecationReasons maps the deprecated values to the reason.
+	DeprecationReasons map[string]string
 }
 
 func (e *Enum) isType() {}
@@ -49,6 +56,13 @@ type Object struct {
 	Description string
 	Key         Resolver
 	Fields      map[string]*Field
+
+	// Directives are printed after the object's name in SDL, e.g.
+	// `@key(fields: "id")`.
+	Directives []string
+
+	// Interfaces are the interfaces implemented by the object.
+	Interfaces map[string]*Interface
 }
 
 func (o *Object) isType() {}
@@ -70,7 +84,15 @@ func (l *List) String() string {
 
 type InputObject struct {
 	Name        string
+	Description string
 	InputFields map[string]Type
+
+	// DefaultValues maps the fields with a default to the default's GraphQL
+	// literal, e.g. `25` or `"name"`.
+	DefaultValues map[string]string
+
+	// Descriptions maps the documented fields to their description.
+	Descriptions map[string]string
 }
 
 func (io *InputObject) isType() {}
@@ -95,6 +117,11 @@ type Union struct {
 	Name        string
 	Description string
 	Types       map[string]*Object
+
+	// ResolveType optionally returns the name of the member type of a value.
+	// If it is nil, values are expected to be one-hot structs with a field
+	// named after each member type.
+	ResolveType func(value interface{}) string
 }
 
 func (*Union) isType() {}
@@ -103,6 +130,23 @@ func (u *Union) String() string {
 	return u.Name
 }
 
+// Interface is an abstract type with fields shared by several object types
+type Interface struct {
+	Name        string
+	Description string
+	Fields      map[string]*Field
+	Types       map[string]*Object
+
+	// ResolveType returns the name of the object type of a value.
+	ResolveType func(value interface{}) string
+}
+
+func (*Interface) isType() {}
+
+func (i *Interface) String() string {
+	return i.Name
+}
+
 // Verify *Scalar, *Object, *List, *InputObject, and *NonNull implement Type
 var _ Type = &Scalar{}
 var _ Type = &Object{}
@@ -111,6 +155,7 @@ var _ Type = &InputObject{}
 var _ Type = &NonNull{}
 var _ Type = &Enum{}
 var _ Type = &Union{}
+var _ Type = &Interface{}
 
 // A Resolver calculates the value of a field of an object
 type Resolver func(ctx context.Context, source, args interface{}, selectionSet *SelectionSet) (interface{}, error)
@@ -124,12 +169,99 @@ type Field struct {
 	Args           map[string]Type
 	ParseArguments func(json interface{}) (interface{}, error)
 
+	// ArgDefaultValues maps the args with a default to the default's GraphQL
+	// literal, e.g. `25` or `"name"`.
+	ArgDefaultValues map[string]string
+
+	// ArgDescriptions maps the documented args to their description.
+	ArgDescriptions map[string]string
+
 	Expensive bool
+
+	// Stream marks a field of the subscription root whose resolver returns a
+	// receive channel instead of a value of Type. Executor.Subscribe sends a
+	// result for every value received from the channel, and ends the
+	// subscription once it is closed.
+	Stream bool
+
+	// Timeout optionally limits how long the field's resolver may run.
+	Timeout time.Duration
+
+	// Authorize optionally checks if the field may be resolved on source
+	// before its resolver runs. If it returns an error, the field resolves to
+	// null and the error is returned by Executor.ExecuteResult.
+	Authorize func(ctx context.Context, source interface{}) error
+
+	// Memoize makes the executor resolve the field only once per source and
+	// args during an execution, and reuse the value for every other selection
+	// of the field.
+	Memoize bool
+
+	// BestEffort makes the field resolve to null if its resolver fails,
+	// without failing the query or recording the error. The error is passed
+	// to Executor.OnBestEffortError instead. BestEffort fields should be
+	// nullable.
+	BestEffort bool
+
+	// Cost is the cost of resolving the field, excluding its selections. If it
+	// is 0, the field costs 1. CostMultiplier optionally multiplies the cost of
+	// the field's selections based on the context of the query and the field's
+	// parsed args, e.g. by the page size of a connection.
+	Cost           int
+	CostMultiplier func(ctx context.Context, args interface{}) int
+
+	// CacheTTL makes the responses of queries that only select cacheable
+	// top-level fields cacheable for the shortest CacheTTL of their fields, by
+	// HTTP handlers with a ResponseCache.
+	CacheTTL time.Duration
+
+	Description       string
+	DeprecationReason string
+
+	// Directives are printed after the field's type in SDL, e.g. `@external`.
+	Directives []string
 }
 
 type Schema struct {
 	Query    Type
 	Mutation Type
+
+	// Subscription is the root type of subscriptions, or nil if the schema
+	// has none.
+	Subscription Type
+
+	// Directives are the custom directives of the schema, keyed by name. An
+	// Executor only applies them if its Directives are set to these.
+	Directives map[string]*Directive
+}
+
+// A DirectiveLocation is a location in a query where a directive can be used.
+type DirectiveLocation string
+
+const (
+	// DirectiveLocationField is the location of directives used on fields.
+	DirectiveLocationField DirectiveLocation = "FIELD"
+)
+
+// A Directive is a custom directive that post-processes the resolved values of
+// the fields it is used on.
+type Directive struct {
+	Name      string
+	Locations []DirectiveLocation
+
+	// Apply is called with the resolved value of a field and the directive's
+	// args, and returns the field's new value.
+	Apply func(ctx context.Context, value interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+// allowedOn returns whether the directive can be used at location.
+func (d *Directive) allowedOn(location DirectiveLocation) bool {
+	for _, allowed := range d.Locations {
+		if allowed == location {
+			return true
+		}
+	}
+	return false
 }
 
 // SelectionSet represents a core GraphQL query
@@ -172,11 +304,24 @@ type Selection struct {
 	Args         interface{}
 	SelectionSet *SelectionSet
 
+	// Directives are the custom directives used on the selection, applied by
+	// the executor to its resolved value.
+	Directives []*SelectionDirective
+
 	// The parsed flag is used to make sure the args for this Selection are only
 	// parsed once.
 	parsed bool
 }
 
+// A SelectionDirective is a custom directive used on a Selection, such as
+// @uppercase in the query
+//
+//   { name @uppercase }
+type SelectionDirective struct {
+	Name string
+	Args map[string]interface{}
+}
+
 // A Fragment represents a reusable part of a GraphQL query
 //
 // The On part of a Fragment represents the type of source object for which
@@ -185,4 +330,8 @@ type Selection struct {
 type Fragment struct {
 	On           string
 	SelectionSet *SelectionSet
+
+	// spreadSkipped marks fragments spread in a selection omitted by @skip or
+	// @include, which don't count as unused.
+	spreadSkipped bool
 }
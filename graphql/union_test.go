@@ -78,7 +78,7 @@ func TestUnionType(t *testing.T) {
 	}
 
 	if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`
-		{"vehicle": { "name": "a", "speed": 50 }, "asset": { "name": "b", "batteryLevel": 5, "__typename": "Gateway" }}`)); d != "" {
+		{"vehicle": { "name": "a", "speed": 50 }, "asset": { "name": "b", "batteryLevel": 5, "__typename": "Asset" }}`)); d != "" {
 		t.Errorf("expected did not match result: %s", d)
 	}
 }
@@ -255,6 +255,47 @@ func TestUnionList(t *testing.T) {
 	}
 }
 
+func TestUnionFragments(t *testing.T) {
+	type UnionType struct {
+		schemabuilder.Union
+
+		*UnionPart1
+		*UnionPart2
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("list", func() ([]*UnionType, error) {
+		return []*UnionType{
+			{UnionPart1: &UnionPart1{"a"}},
+			{UnionPart2: &UnionPart2{"b"}},
+		}, nil
+	})
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`
+		{ list { ...UnionFields ... on UnionPart2 { thing } } }
+		fragment UnionFields on UnionType { __typename ...PartFields }
+		fragment PartFields on UnionPart1 { otherThing }
+	`, nil)
+
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`
+		{ "list": [{"__typename": "UnionPart1", "otherThing": "a"}, {"__typename": "UnionPart2", "thing": "b"}] }`)); d != "" {
+		t.Errorf("expected did not match result: %s", d)
+	}
+}
+
 func TestUnionStruct(t *testing.T) {
 	type UnionType struct {
 		schemabuilder.Union
@@ -297,3 +338,59 @@ func TestUnionStruct(t *testing.T) {
 		t.Errorf("expected did not match result: %s", d)
 	}
 }
+
+func TestRegisterUnion(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Item struct {
+		Title string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{})
+	schema.Object("Item", Item{})
+	schema.RegisterUnion("SearchResult", nil, User{}, Item{})
+	schema.Query().FieldFunc("search", func() []interface{} {
+		return []interface{}{&User{Name: "alice"}, Item{Title: "book"}}
+	}, schemabuilder.ReturnsUnion("SearchResult"))
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ search { __typename ... on User { name } ... on Item { title } } }`, nil)
+	if err := graphql.PrepareQuery(builtSchema.Query, q.SelectionSet); err != nil {
+		t.Error(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Errorf("expected no error, received %s", err.Error())
+	}
+
+	if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`
+		{ "search": [
+			{ "__typename": "User", "name": "alice" },
+			{ "__typename": "Item", "title": "book" }
+		] }`)); d != "" {
+		t.Errorf("expected did not match result: %s", d)
+	}
+}
+
+func TestRegisterUnionUnregisteredMember(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Item struct {
+		Title string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{})
+	schema.RegisterUnion("SearchResult", nil, User{}, Item{})
+
+	_, err := schema.Build()
+	if err == nil || !strings.Contains(err.Error(), "should be a registered object") {
+		t.Errorf("expected registered object error, received %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Upload is a file sent with a multipart request, see WithHTTPUploads.
+// Resolvers take uploads as args of the Upload scalar, e.g.
+//    mutation.FieldFunc("setAvatar", func(args struct{ File graphql.Upload }) (bool, error) {
+//        data, err := ioutil.ReadAll(args.File.File)
+//        ...
+//    })
+// The file can only be read while the request is executed.
+type Upload struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	File        io.Reader
+}
+
+// maxUploadMemory is the number of bytes of uploaded files kept in memory;
+// the rest is stored in temporary files.
+const maxUploadMemory = 32 << 20
+
+// WithHTTPUploads also accepts multipart/form-data requests following the
+// GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec): the
+// operations field holds the usual JSON body, the map field maps the names of
+// the file parts to the paths of the variables they are passed as, e.g.
+//    {"0": ["variables.file"], "1": ["variables.files.0"]}
+// and the files are passed as Uploads. Multipart requests larger than
+// maxSize bytes are rejected.
+func WithHTTPUploads(maxSize int64) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.maxUploadSize = maxSize
+	}
+}
+
+// isMultipartRequest returns if r has a multipart/form-data body.
+func isMultipartRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// decodeMultipartBody decodes the operations of a multipart request of at most
+// maxSize bytes into params, and sets the variables mapped to files to their
+// Uploads. A file mapped to several variables is opened for each of them. The
+// returned closers close the opened files.
+func decodeMultipartBody(w http.ResponseWriter, r *http.Request, maxSize int64, params *httpPostBody) ([]io.Closer, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, NewClientError("bad multipart request: %s", err.Error())
+	}
+
+	operations := r.MultipartForm.Value["operations"]
+	if len(operations) != 1 {
+		return nil, NewClientError("multipart request must include operations")
+	}
+	if err := json.Unmarshal([]byte(operations[0]), params); err != nil {
+		return nil, NewClientError("bad operations: %s", err.Error())
+	}
+
+	var fileMap map[string][]string
+	if values := r.MultipartForm.Value["map"]; len(values) == 1 {
+		if err := json.Unmarshal([]byte(values[0]), &fileMap); err != nil {
+			return nil, NewClientError("bad map: %s", err.Error())
+		}
+	}
+	if params.Variables == nil {
+		params.Variables = make(map[string]interface{})
+	}
+
+	var closers []io.Closer
+	closeAll := func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}
+	for name, paths := range fileMap {
+		headers := r.MultipartForm.File[name]
+		if len(headers) != 1 {
+			closeAll()
+			return nil, NewClientError("missing file %s", name)
+		}
+		for _, path := range paths {
+			file, err := headers[0].Open()
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			closers = append(closers, file)
+
+			upload := &Upload{
+				Filename:    headers[0].Filename,
+				ContentType: headers[0].Header.Get("Content-Type"),
+				Size:        headers[0].Size,
+				File:        file,
+			}
+			if err := setUploadVariable(params.Variables, path, upload); err != nil {
+				closeAll()
+				return nil, err
+			}
+		}
+	}
+	return closers, nil
+}
+
+// setUploadVariable sets the variable at path, e.g. variables.files.0, to
+// upload.
+func setUploadVariable(variables map[string]interface{}, path string, upload *Upload) error {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 || parts[0] != "variables" {
+		return NewClientError("bad file path %s: should start with variables.", path)
+	}
+
+	var container interface{} = variables
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		switch value := container.(type) {
+		case map[string]interface{}:
+			if last {
+				value[part] = upload
+				return nil
+			}
+			container = value[part]
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(value) {
+				return NewClientError("bad file path %s: no index %s", path, part)
+			}
+			if last {
+				value[index] = upload
+				return nil
+			}
+			container = value[index]
+		default:
+			return NewClientError("bad file path %s: %s is not an object or list", path, part)
+		}
+	}
+	return nil
+}
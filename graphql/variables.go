@@ -0,0 +1,198 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// BindVariables returns the variables of query set from the fields of the
+// struct vars, e.g. for Go services sending queries to a GraphQL server:
+//    vars, err := graphql.BindVariables(`query($first: Int!) { users(first: $first) { name } }`, struct {
+//        First int64
+//    }{First: 10})
+// Fields are bound to the variable named in their graphql tag, or to their
+// name starting with a lowercase letter, and their values are converted as by
+// encoding/json. Nil fields are left out, so that their variables take their
+// default. BindVariables fails if a field has no variable, if a required
+// variable isn't set or if a value doesn't match its variable's type, instead
+// of the server rejecting the query.
+func BindVariables(query string, vars interface{}) (map[string]interface{}, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, err
+	}
+	var definitions []*ast.VariableDefinition
+	var operations int
+	for _, definition := range document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			definitions = operation.VariableDefinitions
+			operations++
+		}
+	}
+	if operations != 1 {
+		return nil, fmt.Errorf("query must have a single operation")
+	}
+	declared := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		declared[definition.Variable.Name.Value] = true
+	}
+
+	value := reflect.ValueOf(vars)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("variables should be a struct, not %T", vars)
+	}
+
+	bound := make(map[string]interface{})
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("graphql"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			first, size := utf8.DecodeRuneInString(field.Name)
+			name = string(unicode.ToLower(first)) + field.Name[size:]
+		}
+		if !declared[name] {
+			return nil, fmt.Errorf("field %s: query has no variable $%s", field.Name, name)
+		}
+
+		// Marshal the value as it would be sent, so that it is checked like
+		// the variables of a request.
+		marshaled, err := json.Marshal(value.Field(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", field.Name, err)
+		}
+		var unmarshaled interface{}
+		if err := json.Unmarshal(marshaled, &unmarshaled); err != nil {
+			return nil, fmt.Errorf("field %s: %s", field.Name, err)
+		}
+		if unmarshaled != nil {
+			bound[name] = unmarshaled
+		}
+	}
+
+	if _, err := coerceVariables(definitions, bound); err != nil {
+		return nil, err
+	}
+	return bound, nil
+}
+
+// coerceVariables checks the values of a query's variables against their
+// definitions, and returns the values of the declared variables. Variables
+// that are declared but not provided are bound to nil, so that using an
+// undeclared variable can be detected.
+func coerceVariables(definitions []*ast.VariableDefinition, vars map[string]interface{}) (map[string]interface{}, error) {
+	bound := make(map[string]interface{}, len(definitions))
+	for _, definition := range definitions {
+		name := definition.Variable.Name.Value
+		if _, ok := bound[name]; ok {
+			return nil, NewClientError("duplicate variable $%s", name)
+		}
+
+		value := vars[name]
+		if _, ok := definition.Type.(*ast.NonNull); ok && value == nil {
+			return nil, NewClientError("required variable $%s not provided", name)
+		}
+
+		coerced, err := coerceVariable(definition.Type, value)
+		if err != nil {
+			return nil, NewClientError("variable $%s: %s", name, err.Error())
+		}
+		bound[name] = coerced
+	}
+	return bound, nil
+}
+
+// coerceVariable checks that value matches typ, wrapping single values in a
+// list where typ is a list. Values of types other than the builtin scalars are
+// checked when the args using them are parsed.
+func coerceVariable(typ ast.Type, value interface{}) (interface{}, error) {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		if value == nil {
+			return nil, NewClientError("expected %s, got null", typeString(typ))
+		}
+		return coerceVariable(typ.Type, value)
+
+	case *ast.List:
+		if value == nil {
+			return nil, nil
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		coerced := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			item, err := coerceVariable(typ.Type, item)
+			if err != nil {
+				return nil, err
+			}
+			coerced = append(coerced, item)
+		}
+		return coerced, nil
+
+	case *ast.Named:
+		if value == nil {
+			return nil, nil
+		}
+
+		var ok bool
+		switch typ.Name.Value {
+		case "Int", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			var number float64
+			number, ok = value.(float64)
+			ok = ok && number == math.Trunc(number)
+		case "Float", "float32", "float64":
+			_, ok = value.(float64)
+		case "String", "string":
+			_, ok = value.(string)
+		case "ID":
+			// IDs are strings, but integers are accepted as input.
+			_, ok = value.(string)
+			if number, isNumber := value.(float64); isNumber {
+				ok = number == math.Trunc(number)
+			}
+		case "Boolean", "bool":
+			_, ok = value.(bool)
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, NewClientError("expected %s, got %v", typ.Name.Value, value)
+		}
+		return value, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// typeString formats typ as in the query, e.g. [int64!].
+func typeString(typ ast.Type) string {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		return typeString(typ.Type) + "!"
+	case *ast.List:
+		return "[" + typeString(typ.Type) + "]"
+	case *ast.Named:
+		return typ.Name.Value
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,53 @@
+package reactive
+
+import (
+	"context"
+	"sync"
+)
+
+// keyResources maps the keys passed to AddKeyDependency to the Resource
+// invalidated by Invalidate.
+var keyResources = struct {
+	mu        sync.Mutex
+	resources map[interface{}]*Resource
+}{resources: make(map[interface{}]*Resource)}
+
+// AddKeyDependency registers that the computation running in ctx depends on
+// key, so that it reruns when key is passed to Invalidate. It decouples
+// invalidation from holding a specific Resource, e.g. a resolver can depend on
+// "user:1" and a mutation of the user invalidates it. key must be comparable.
+func AddKeyDependency(ctx context.Context, key interface{}) {
+	keyResources.mu.Lock()
+	defer keyResources.mu.Unlock()
+
+	// Releasing a resource invalidates it, so a resource that is still in the
+	// map while it is being released is replaced instead of being depended on.
+	r, ok := keyResources.resources[key]
+	if !ok || r.node.Invalidated() {
+		r = NewResource()
+		r.Cleanup(func() {
+			keyResources.mu.Lock()
+			defer keyResources.mu.Unlock()
+
+			// The key may already map to a newer resource.
+			if keyResources.resources[key] == r {
+				delete(keyResources.resources, key)
+			}
+		})
+		keyResources.resources[key] = r
+	}
+	AddDependency(ctx, r, nil)
+}
+
+// Invalidate invalidates all computations that depend on key through
+// AddKeyDependency, so that their Rerunners run them again.
+func Invalidate(key interface{}) {
+	keyResources.mu.Lock()
+	r, ok := keyResources.resources[key]
+	delete(keyResources.resources, key)
+	keyResources.mu.Unlock()
+
+	if ok {
+		r.Invalidate()
+	}
+}
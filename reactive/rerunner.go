@@ -182,6 +182,14 @@ func WithDependencyCallback(ctx context.Context, f DependencyCallbackFunc) conte
 	return context.WithValue(ctx, dependencyCallbackKey{}, f)
 }
 
+// WithDependencySet returns a context that collects the dependencies added
+// with AddDependency in a new set, returned by Dependencies. It separates the
+// dependencies of parts of a computation, e.g. of several queries executed by
+// the same Rerunner.
+func WithDependencySet(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dependencySetKey{}, &dependencySet{})
+}
+
 func Dependencies(ctx context.Context) []Dependency {
 	depSet := ctx.Value(dependencySetKey{}).(*dependencySet)
 	if depSet == nil {
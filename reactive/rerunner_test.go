@@ -383,3 +383,51 @@ func TestRerunImmediately(t *testing.T) {
 	r.Invalidate()
 	run.Expect(t, "expected rerun")
 }
+
+// TestKeyDependencyRestart tests that a Rerunner depending on a key while the
+// resource of a stopped Rerunner on the same key is being released doesn't get
+// the released resource, which would invalidate it over and over.
+func TestKeyDependencyRestart(t *testing.T) {
+	const key = "restart"
+
+	dependOnKey := func(runs *int32) *Rerunner {
+		run := NewExpect()
+		var once sync.Once
+		runner := NewRerunner(context.Background(), func(ctx context.Context) (interface{}, error) {
+			AddKeyDependency(ctx, key)
+			atomic.AddInt32(runs, 1)
+			once.Do(run.Trigger)
+			return nil, nil
+		}, 0)
+		run.Expect(t, "expected run")
+		return runner
+	}
+
+	var runs int32
+	runner := dependOnKey(&runs)
+	for i := 0; i < 50; i++ {
+		// Hold the lock until the resource of the stopped Rerunner is
+		// invalidated, so that its cleanup races with the next Rerunner.
+		keyResources.mu.Lock()
+		r := keyResources.resources[key]
+		runner.Stop()
+		for !r.node.Invalidated() {
+			time.Sleep(time.Millisecond)
+		}
+		keyResources.mu.Unlock()
+
+		atomic.StoreInt32(&runs, 0)
+		runner = dependOnKey(&runs)
+		time.Sleep(10 * time.Millisecond)
+		if n := atomic.LoadInt32(&runs); n != 1 {
+			t.Fatalf("expected 1 run without Invalidate, got %d", n)
+		}
+	}
+
+	Invalidate(key)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("expected 2 runs after Invalidate, got %d", n)
+	}
+	runner.Stop()
+}